@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/cybozu-go/scim/resource"
@@ -108,9 +109,14 @@ func (call *GetGroupCall) Do(ctx context.Context) (*resource.Group, error) {
 	if len(m) > 0 {
 		vals = make(url.Values)
 		for key, value := range m {
+			if key == "schemas" {
+				continue
+			}
 			switch value := value.(type) {
 			case []string:
 				vals.Add(key, strings.Join(value, ","))
+			case int:
+				vals.Add(key, strconv.Itoa(value))
 			default:
 				vals.Add(key, fmt.Sprintf(`%s`, value))
 			}
@@ -657,9 +663,14 @@ func (call *DeleteGroupCall) Do(ctx context.Context) error {
 	if len(m) > 0 {
 		vals = make(url.Values)
 		for key, value := range m {
+			if key == "schemas" {
+				continue
+			}
 			switch value := value.(type) {
 			case []string:
 				vals.Add(key, strings.Join(value, ","))
+			case int:
+				vals.Add(key, strconv.Itoa(value))
 			default:
 				vals.Add(key, fmt.Sprintf(`%s`, value))
 			}
@@ -859,3 +870,176 @@ func (call *SearchGroupCall) Do(ctx context.Context) (*resource.ListResponse, er
 
 	return &respayload, nil
 }
+
+// ListGroupCall is an encapsulation of a SCIM operation.
+type ListGroupCall struct {
+	builder *resource.SearchRequestBuilder
+	object  *resource.SearchRequest
+	err     error
+	client  *Client
+	trace   io.Writer
+}
+
+func (call *ListGroupCall) payload() (*resource.SearchRequest, error) {
+	if object := call.object; object != nil {
+		return object, nil
+	}
+	return call.builder.Build()
+}
+
+func (call *ListGroupCall) FromJSON(data []byte) *ListGroupCall {
+	if call.err != nil {
+		return call
+	}
+	var in resource.SearchRequest
+	if err := json.Unmarshal(data, &in); err != nil {
+		call.err = fmt.Errorf("failed to decode data: %w", err)
+		return call
+	}
+	call.object = &in
+	return call
+}
+
+// List creates an instance of ListGroupCall that sends an HTTP GET request to
+// /Groups to search for groups using query parameters. Use Search
+// to send the search request as a POST request to /Groups/.search instead.
+func (svc *GroupService) List() *ListGroupCall {
+	return &ListGroupCall{
+		builder: resource.NewSearchRequestBuilder(),
+		client:  svc.client,
+	}
+}
+
+func (call *ListGroupCall) Attributes(in ...string) *ListGroupCall {
+	call.builder.Attributes(in...)
+	return call
+}
+
+func (call *ListGroupCall) Count(in int) *ListGroupCall {
+	call.builder.Count(in)
+	return call
+}
+
+func (call *ListGroupCall) ExcludedAttributes(in ...string) *ListGroupCall {
+	call.builder.ExcludedAttributes(in...)
+	return call
+}
+
+func (call *ListGroupCall) Filter(in string) *ListGroupCall {
+	call.builder.Filter(in)
+	return call
+}
+
+func (call *ListGroupCall) Schema(in string) *ListGroupCall {
+	call.builder.Schema(in)
+	return call
+}
+
+func (call *ListGroupCall) Schemas(in ...string) *ListGroupCall {
+	call.builder.Schemas(in...)
+	return call
+}
+
+func (call *ListGroupCall) SortBy(in string) *ListGroupCall {
+	call.builder.SortBy(in)
+	return call
+}
+
+func (call *ListGroupCall) SortOrder(in string) *ListGroupCall {
+	call.builder.SortOrder(in)
+	return call
+}
+
+func (call *ListGroupCall) StartIndex(in int) *ListGroupCall {
+	call.builder.StartIndex(in)
+	return call
+}
+
+func (call *ListGroupCall) Trace(w io.Writer) *ListGroupCall {
+	call.trace = w
+	return call
+}
+
+func (call *ListGroupCall) makeURL() string {
+	return call.client.baseURL + "/Groups"
+}
+
+func (call *ListGroupCall) Do(ctx context.Context) (*resource.ListResponse, error) {
+	if err := call.err; err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	payload, err := call.payload()
+	if err != nil {
+		return nil, fmt.Errorf(`failed to generate request payload for ListGroupCall: %w`, err)
+	}
+
+	trace := call.trace
+	if trace == nil {
+		trace = call.client.trace
+	}
+	u := call.makeURL()
+	if trace != nil {
+		fmt.Fprintf(trace, "trace: client sending call request to %q\n", u)
+	}
+
+	var vals url.Values
+	m := make(map[string]interface{})
+	if err := payload.AsMap(m); err != nil {
+		return nil, fmt.Errorf(`failed to convert resource into map: %w`, err)
+	}
+	if len(m) > 0 {
+		vals = make(url.Values)
+		for key, value := range m {
+			if key == "schemas" {
+				continue
+			}
+			switch value := value.(type) {
+			case []string:
+				vals.Add(key, strings.Join(value, ","))
+			case int:
+				vals.Add(key, strconv.Itoa(value))
+			default:
+				vals.Add(key, fmt.Sprintf(`%s`, value))
+			}
+		}
+	}
+	if enc := vals.Encode(); len(enc) > 0 {
+		u = u + "?" + vals.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to create new HTTP request: %w`, err)
+	}
+	req.Header.Set(`Accept`, `application/scim+json`)
+
+	if trace != nil {
+		buf, _ := httputil.DumpRequestOut(req, true)
+		fmt.Fprintf(trace, "%s\n", buf)
+	}
+
+	res, err := call.client.httpcl.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to send request to %q: %w`, u, err)
+	}
+	if trace != nil {
+		buf, _ := httputil.DumpResponse(res, true)
+		fmt.Fprintf(trace, "%s\n", buf)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		var serr resource.Error
+		var resBody bytes.Buffer
+		if err := json.NewDecoder(io.TeeReader(res.Body, &resBody)).Decode(&serr); err != nil {
+			return nil, fmt.Errorf("expected %d (got %d): %s", http.StatusOK, res.StatusCode, resBody.String())
+		}
+		return nil, &serr
+	}
+
+	var respayload resource.ListResponse
+	if err := json.NewDecoder(res.Body).Decode(&respayload); err != nil {
+		return nil, fmt.Errorf(`failed to decode call response: %w`, err)
+	}
+
+	return &respayload, nil
+}
@@ -0,0 +1,585 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/cybozu-go/scim/resource"
+)
+
+// MeService the logical grouping of SCIM API calls that operate on the
+// authenticated user, using the /Me alias
+type MeService struct {
+	client *Client
+}
+
+// Me creates a new Service object to perform an operation
+func (client *Client) Me() *MeService {
+	return &MeService{
+		client: client,
+	}
+}
+
+// GetMeCall is an encapsulation of a SCIM operation.
+type GetMeCall struct {
+	builder *resource.PartialResourceRepresentationRequestBuilder
+	object  *resource.PartialResourceRepresentationRequest
+	err     error
+	client  *Client
+	trace   io.Writer
+}
+
+func (call *GetMeCall) payload() (*resource.PartialResourceRepresentationRequest, error) {
+	if object := call.object; object != nil {
+		return object, nil
+	}
+	return call.builder.Build()
+}
+
+func (call *GetMeCall) FromJSON(data []byte) *GetMeCall {
+	if call.err != nil {
+		return call
+	}
+	var in resource.PartialResourceRepresentationRequest
+	if err := json.Unmarshal(data, &in); err != nil {
+		call.err = fmt.Errorf("failed to decode data: %w", err)
+		return call
+	}
+	call.object = &in
+	return call
+}
+
+// Get creates an instance of GetMeCall that sends an HTTP GET request to
+// /Me to retrieve the authenticated user.
+func (svc *MeService) Get() *GetMeCall {
+	return &GetMeCall{
+		builder: resource.NewPartialResourceRepresentationRequestBuilder(),
+		client:  svc.client,
+	}
+}
+
+func (call *GetMeCall) Attributes(in ...string) *GetMeCall {
+	call.builder.Attributes(in...)
+	return call
+}
+
+func (call *GetMeCall) ExcludedAttributes(in ...string) *GetMeCall {
+	call.builder.ExcludedAttributes(in...)
+	return call
+}
+
+func (call *GetMeCall) Trace(w io.Writer) *GetMeCall {
+	call.trace = w
+	return call
+}
+
+func (call *GetMeCall) makeURL() string {
+	return call.client.baseURL + "/Me"
+}
+
+func (call *GetMeCall) Do(ctx context.Context) (*resource.User, error) {
+	if err := call.err; err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	payload, err := call.payload()
+	if err != nil {
+		return nil, fmt.Errorf(`failed to generate request payload for GetMeCall: %w`, err)
+	}
+
+	trace := call.trace
+	if trace == nil {
+		trace = call.client.trace
+	}
+	u := call.makeURL()
+	if trace != nil {
+		fmt.Fprintf(trace, "trace: client sending call request to %q\n", u)
+	}
+
+	var vals url.Values
+	m := make(map[string]interface{})
+	if err := payload.AsMap(m); err != nil {
+		return nil, fmt.Errorf(`failed to convert resource into map: %w`, err)
+	}
+	if len(m) > 0 {
+		vals = make(url.Values)
+		for key, value := range m {
+			if key == "schemas" {
+				continue
+			}
+			switch value := value.(type) {
+			case []string:
+				vals.Add(key, strings.Join(value, ","))
+			case int:
+				vals.Add(key, strconv.Itoa(value))
+			default:
+				vals.Add(key, fmt.Sprintf(`%s`, value))
+			}
+		}
+	}
+	if enc := vals.Encode(); len(enc) > 0 {
+		u = u + "?" + vals.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to create new HTTP request: %w`, err)
+	}
+	req.Header.Set(`Accept`, `application/scim+json`)
+
+	if trace != nil {
+		buf, _ := httputil.DumpRequestOut(req, true)
+		fmt.Fprintf(trace, "%s\n", buf)
+	}
+
+	res, err := call.client.httpcl.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to send request to %q: %w`, u, err)
+	}
+	if trace != nil {
+		buf, _ := httputil.DumpResponse(res, true)
+		fmt.Fprintf(trace, "%s\n", buf)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		var serr resource.Error
+		var resBody bytes.Buffer
+		if err := json.NewDecoder(io.TeeReader(res.Body, &resBody)).Decode(&serr); err != nil {
+			return nil, fmt.Errorf("expected %d (got %d): %s", http.StatusOK, res.StatusCode, resBody.String())
+		}
+		return nil, &serr
+	}
+
+	var respayload resource.User
+	if err := json.NewDecoder(res.Body).Decode(&respayload); err != nil {
+		return nil, fmt.Errorf(`failed to decode call response: %w`, err)
+	}
+
+	return &respayload, nil
+}
+
+// ReplaceMeCall is an encapsulation of a SCIM operation.
+type ReplaceMeCall struct {
+	builder *resource.UserBuilder
+	object  *resource.User
+	err     error
+	client  *Client
+	trace   io.Writer
+}
+
+func (call *ReplaceMeCall) payload() (*resource.User, error) {
+	if object := call.object; object != nil {
+		return object, nil
+	}
+	return call.builder.Build()
+}
+
+func (call *ReplaceMeCall) FromJSON(data []byte) *ReplaceMeCall {
+	if call.err != nil {
+		return call
+	}
+	var in resource.User
+	if err := json.Unmarshal(data, &in); err != nil {
+		call.err = fmt.Errorf("failed to decode data: %w", err)
+		return call
+	}
+	call.object = &in
+	return call
+}
+
+// Replace creates an instance of ReplaceMeCall that sends an HTTP PUT request to
+// /Me to replace the authenticated user.
+func (svc *MeService) Replace() *ReplaceMeCall {
+	return &ReplaceMeCall{
+		builder: resource.NewUserBuilder(),
+		client:  svc.client,
+	}
+}
+
+func (call *ReplaceMeCall) Active(in bool) *ReplaceMeCall {
+	call.builder.Active(in)
+	return call
+}
+
+func (call *ReplaceMeCall) Addresses(in ...*resource.Address) *ReplaceMeCall {
+	call.builder.Addresses(in...)
+	return call
+}
+
+func (call *ReplaceMeCall) DisplayName(in string) *ReplaceMeCall {
+	call.builder.DisplayName(in)
+	return call
+}
+
+func (call *ReplaceMeCall) Emails(in ...*resource.Email) *ReplaceMeCall {
+	call.builder.Emails(in...)
+	return call
+}
+
+func (call *ReplaceMeCall) Entitlements(in ...*resource.Entitlement) *ReplaceMeCall {
+	call.builder.Entitlements(in...)
+	return call
+}
+
+func (call *ReplaceMeCall) ExternalID(in string) *ReplaceMeCall {
+	call.builder.ExternalID(in)
+	return call
+}
+
+func (call *ReplaceMeCall) IMS(in ...*resource.IMS) *ReplaceMeCall {
+	call.builder.IMS(in...)
+	return call
+}
+
+func (call *ReplaceMeCall) Locale(in string) *ReplaceMeCall {
+	call.builder.Locale(in)
+	return call
+}
+
+func (call *ReplaceMeCall) Name(in *resource.Names) *ReplaceMeCall {
+	call.builder.Name(in)
+	return call
+}
+
+func (call *ReplaceMeCall) NickName(in string) *ReplaceMeCall {
+	call.builder.NickName(in)
+	return call
+}
+
+func (call *ReplaceMeCall) Password(in string) *ReplaceMeCall {
+	call.builder.Password(in)
+	return call
+}
+
+func (call *ReplaceMeCall) PhoneNumbers(in ...*resource.PhoneNumber) *ReplaceMeCall {
+	call.builder.PhoneNumbers(in...)
+	return call
+}
+
+func (call *ReplaceMeCall) Photos(in ...*resource.Photo) *ReplaceMeCall {
+	call.builder.Photos(in...)
+	return call
+}
+
+func (call *ReplaceMeCall) PreferredLanguage(in string) *ReplaceMeCall {
+	call.builder.PreferredLanguage(in)
+	return call
+}
+
+func (call *ReplaceMeCall) ProfileURL(in string) *ReplaceMeCall {
+	call.builder.ProfileURL(in)
+	return call
+}
+
+func (call *ReplaceMeCall) Roles(in ...*resource.Role) *ReplaceMeCall {
+	call.builder.Roles(in...)
+	return call
+}
+
+func (call *ReplaceMeCall) Timezone(in string) *ReplaceMeCall {
+	call.builder.Timezone(in)
+	return call
+}
+
+func (call *ReplaceMeCall) Title(in string) *ReplaceMeCall {
+	call.builder.Title(in)
+	return call
+}
+
+func (call *ReplaceMeCall) UserName(in string) *ReplaceMeCall {
+	call.builder.UserName(in)
+	return call
+}
+
+func (call *ReplaceMeCall) UserType(in string) *ReplaceMeCall {
+	call.builder.UserType(in)
+	return call
+}
+
+func (call *ReplaceMeCall) X509Certificates(in ...*resource.X509Certificate) *ReplaceMeCall {
+	call.builder.X509Certificates(in...)
+	return call
+}
+
+// Extension allows users to register an extension using the fully qualified URI
+func (call *ReplaceMeCall) Extension(uri string, value interface{}) *ReplaceMeCall {
+	call.builder.Extension(uri, value)
+	return call
+}
+
+func (call *ReplaceMeCall) Trace(w io.Writer) *ReplaceMeCall {
+	call.trace = w
+	return call
+}
+
+func (call *ReplaceMeCall) makeURL() string {
+	return call.client.baseURL + "/Me"
+}
+
+func (call *ReplaceMeCall) Do(ctx context.Context) (*resource.User, error) {
+	if err := call.err; err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	payload, err := call.payload()
+	if err != nil {
+		return nil, fmt.Errorf(`failed to generate request payload for ReplaceMeCall: %w`, err)
+	}
+
+	trace := call.trace
+	if trace == nil {
+		trace = call.client.trace
+	}
+	u := call.makeURL()
+	if trace != nil {
+		fmt.Fprintf(trace, "trace: client sending call request to %q\n", u)
+	}
+
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(payload); err != nil {
+		return nil, fmt.Errorf(`failed to encode call request: %w`, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u, &body)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to create new HTTP request: %w`, err)
+	}
+
+	req.Header.Set(`Content-Type`, `application/scim+json`)
+	req.Header.Set(`Accept`, `application/scim+json`)
+
+	if trace != nil {
+		buf, _ := httputil.DumpRequestOut(req, true)
+		fmt.Fprintf(trace, "%s\n", buf)
+	}
+
+	res, err := call.client.httpcl.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to send request to %q: %w`, u, err)
+	}
+	if trace != nil {
+		buf, _ := httputil.DumpResponse(res, true)
+		fmt.Fprintf(trace, "%s\n", buf)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		var serr resource.Error
+		var resBody bytes.Buffer
+		if err := json.NewDecoder(io.TeeReader(res.Body, &resBody)).Decode(&serr); err != nil {
+			return nil, fmt.Errorf("expected %d (got %d): %s", http.StatusOK, res.StatusCode, resBody.String())
+		}
+		return nil, &serr
+	}
+
+	var respayload resource.User
+	if err := json.NewDecoder(res.Body).Decode(&respayload); err != nil {
+		return nil, fmt.Errorf(`failed to decode call response: %w`, err)
+	}
+
+	return &respayload, nil
+}
+
+// PatchMeCall is an encapsulation of a SCIM operation.
+type PatchMeCall struct {
+	builder *resource.PatchRequestBuilder
+	object  *resource.PatchRequest
+	err     error
+	client  *Client
+	trace   io.Writer
+}
+
+func (call *PatchMeCall) payload() (*resource.PatchRequest, error) {
+	if object := call.object; object != nil {
+		return object, nil
+	}
+	return call.builder.Build()
+}
+
+func (call *PatchMeCall) FromJSON(data []byte) *PatchMeCall {
+	if call.err != nil {
+		return call
+	}
+	var in resource.PatchRequest
+	if err := json.Unmarshal(data, &in); err != nil {
+		call.err = fmt.Errorf("failed to decode data: %w", err)
+		return call
+	}
+	call.object = &in
+	return call
+}
+
+// Patch allows the user to patch parts of the authenticated user object
+func (svc *MeService) Patch() *PatchMeCall {
+	return &PatchMeCall{
+		builder: resource.NewPatchRequestBuilder(),
+		client:  svc.client,
+	}
+}
+
+func (call *PatchMeCall) Operations(in ...*resource.PatchOperation) *PatchMeCall {
+	call.builder.Operations(in...)
+	return call
+}
+
+func (call *PatchMeCall) Schemas(in ...string) *PatchMeCall {
+	call.builder.Schemas(in...)
+	return call
+}
+
+// Extension allows users to register an extension using the fully qualified URI
+func (call *PatchMeCall) Extension(uri string, value interface{}) *PatchMeCall {
+	call.builder.Extension(uri, value)
+	return call
+}
+
+func (call *PatchMeCall) Trace(w io.Writer) *PatchMeCall {
+	call.trace = w
+	return call
+}
+
+func (call *PatchMeCall) makeURL() string {
+	return call.client.baseURL + "/Me"
+}
+
+func (call *PatchMeCall) Do(ctx context.Context) (*resource.User, error) {
+	if err := call.err; err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	payload, err := call.payload()
+	if err != nil {
+		return nil, fmt.Errorf(`failed to generate request payload for PatchMeCall: %w`, err)
+	}
+
+	trace := call.trace
+	if trace == nil {
+		trace = call.client.trace
+	}
+	u := call.makeURL()
+	if trace != nil {
+		fmt.Fprintf(trace, "trace: client sending call request to %q\n", u)
+	}
+
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(payload); err != nil {
+		return nil, fmt.Errorf(`failed to encode call request: %w`, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, u, &body)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to create new HTTP request: %w`, err)
+	}
+
+	req.Header.Set(`Content-Type`, `application/scim+json`)
+	req.Header.Set(`Accept`, `application/scim+json`)
+
+	if trace != nil {
+		buf, _ := httputil.DumpRequestOut(req, true)
+		fmt.Fprintf(trace, "%s\n", buf)
+	}
+
+	res, err := call.client.httpcl.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to send request to %q: %w`, u, err)
+	}
+	if trace != nil {
+		buf, _ := httputil.DumpResponse(res, true)
+		fmt.Fprintf(trace, "%s\n", buf)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNoContent {
+		//nolint:nilnil
+		return nil, nil
+	}
+
+	if res.StatusCode != http.StatusOK {
+		var serr resource.Error
+		var resBody bytes.Buffer
+		if err := json.NewDecoder(io.TeeReader(res.Body, &resBody)).Decode(&serr); err != nil {
+			return nil, fmt.Errorf("expected %d (got %d): %s", http.StatusOK, res.StatusCode, resBody.String())
+		}
+		return nil, &serr
+	}
+
+	var respayload resource.User
+	if err := json.NewDecoder(res.Body).Decode(&respayload); err != nil {
+		return nil, fmt.Errorf(`failed to decode call response: %w`, err)
+	}
+
+	return &respayload, nil
+}
+
+// DeleteMeCall is an encapsulation of a SCIM operation.
+type DeleteMeCall struct {
+	err    error
+	client *Client
+	trace  io.Writer
+}
+
+func (svc *MeService) Delete() *DeleteMeCall {
+	return &DeleteMeCall{
+		client: svc.client,
+	}
+}
+
+func (call *DeleteMeCall) Trace(w io.Writer) *DeleteMeCall {
+	call.trace = w
+	return call
+}
+
+func (call *DeleteMeCall) makeURL() string {
+	return call.client.baseURL + "/Me"
+}
+
+func (call *DeleteMeCall) Do(ctx context.Context) error {
+	if err := call.err; err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	trace := call.trace
+	if trace == nil {
+		trace = call.client.trace
+	}
+	u := call.makeURL()
+	if trace != nil {
+		fmt.Fprintf(trace, "trace: client sending call request to %q\n", u)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, u, nil)
+	if err != nil {
+		return fmt.Errorf(`failed to create new HTTP request: %w`, err)
+	}
+	req.Header.Set(`Accept`, `application/scim+json`)
+
+	if trace != nil {
+		buf, _ := httputil.DumpRequestOut(req, true)
+		fmt.Fprintf(trace, "%s\n", buf)
+	}
+
+	res, err := call.client.httpcl.Do(req)
+	if err != nil {
+		return fmt.Errorf(`failed to send request to %q: %w`, u, err)
+	}
+	if trace != nil {
+		buf, _ := httputil.DumpResponse(res, true)
+		fmt.Fprintf(trace, "%s\n", buf)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusNoContent {
+		var serr resource.Error
+		var resBody bytes.Buffer
+		if err := json.NewDecoder(io.TeeReader(res.Body, &resBody)).Decode(&serr); err != nil {
+			return fmt.Errorf("expected %d (got %d): %s", http.StatusNoContent, res.StatusCode, resBody.String())
+		}
+		return &serr
+	}
+
+	return nil
+}
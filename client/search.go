@@ -0,0 +1,39 @@
+package client
+
+import "github.com/cybozu-go/scim/filter"
+
+// FilterExpr sets the filter using an expression, such as one
+// built using `filter.Attr()`. It is equivalent to calling `Filter()`
+// with the result of `filter.Format()`
+func (call *SearchCall) FilterExpr(in filter.Expr) *SearchCall {
+	call.builder.Filter(filter.Format(in))
+	return call
+}
+
+// FilterExpr sets the filter using an expression.
+// See `(*SearchCall).FilterExpr()` for details
+func (call *SearchUserCall) FilterExpr(in filter.Expr) *SearchUserCall {
+	call.builder.Filter(filter.Format(in))
+	return call
+}
+
+// FilterExpr sets the filter using an expression.
+// See `(*SearchCall).FilterExpr()` for details
+func (call *SearchGroupCall) FilterExpr(in filter.Expr) *SearchGroupCall {
+	call.builder.Filter(filter.Format(in))
+	return call
+}
+
+// FilterExpr sets the filter using an expression.
+// See `(*SearchCall).FilterExpr()` for details
+func (call *ListUserCall) FilterExpr(in filter.Expr) *ListUserCall {
+	call.builder.Filter(filter.Format(in))
+	return call
+}
+
+// FilterExpr sets the filter using an expression.
+// See `(*SearchCall).FilterExpr()` for details
+func (call *ListGroupCall) FilterExpr(in filter.Expr) *ListGroupCall {
+	call.builder.Filter(filter.Format(in))
+	return call
+}
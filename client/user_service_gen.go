@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/cybozu-go/scim/resource"
@@ -111,9 +112,14 @@ func (call *GetUserCall) Do(ctx context.Context) (*resource.User, error) {
 	if len(m) > 0 {
 		vals = make(url.Values)
 		for key, value := range m {
+			if key == "schemas" {
+				continue
+			}
 			switch value := value.(type) {
 			case []string:
 				vals.Add(key, strings.Join(value, ","))
+			case int:
+				vals.Add(key, strconv.Itoa(value))
 			default:
 				vals.Add(key, fmt.Sprintf(`%s`, value))
 			}
@@ -964,3 +970,176 @@ func (call *SearchUserCall) Do(ctx context.Context) (*resource.ListResponse, err
 
 	return &respayload, nil
 }
+
+// ListUserCall is an encapsulation of a SCIM operation.
+type ListUserCall struct {
+	builder *resource.SearchRequestBuilder
+	object  *resource.SearchRequest
+	err     error
+	client  *Client
+	trace   io.Writer
+}
+
+func (call *ListUserCall) payload() (*resource.SearchRequest, error) {
+	if object := call.object; object != nil {
+		return object, nil
+	}
+	return call.builder.Build()
+}
+
+func (call *ListUserCall) FromJSON(data []byte) *ListUserCall {
+	if call.err != nil {
+		return call
+	}
+	var in resource.SearchRequest
+	if err := json.Unmarshal(data, &in); err != nil {
+		call.err = fmt.Errorf("failed to decode data: %w", err)
+		return call
+	}
+	call.object = &in
+	return call
+}
+
+// List creates an instance of ListUserCall that sends an HTTP GET request to
+// /Users to search for users using query parameters. Use Search
+// to send the search request as a POST request to /Users/.search instead.
+func (svc *UserService) List() *ListUserCall {
+	return &ListUserCall{
+		builder: resource.NewSearchRequestBuilder(),
+		client:  svc.client,
+	}
+}
+
+func (call *ListUserCall) Attributes(in ...string) *ListUserCall {
+	call.builder.Attributes(in...)
+	return call
+}
+
+func (call *ListUserCall) Count(in int) *ListUserCall {
+	call.builder.Count(in)
+	return call
+}
+
+func (call *ListUserCall) ExcludedAttributes(in ...string) *ListUserCall {
+	call.builder.ExcludedAttributes(in...)
+	return call
+}
+
+func (call *ListUserCall) Filter(in string) *ListUserCall {
+	call.builder.Filter(in)
+	return call
+}
+
+func (call *ListUserCall) Schema(in string) *ListUserCall {
+	call.builder.Schema(in)
+	return call
+}
+
+func (call *ListUserCall) Schemas(in ...string) *ListUserCall {
+	call.builder.Schemas(in...)
+	return call
+}
+
+func (call *ListUserCall) SortBy(in string) *ListUserCall {
+	call.builder.SortBy(in)
+	return call
+}
+
+func (call *ListUserCall) SortOrder(in string) *ListUserCall {
+	call.builder.SortOrder(in)
+	return call
+}
+
+func (call *ListUserCall) StartIndex(in int) *ListUserCall {
+	call.builder.StartIndex(in)
+	return call
+}
+
+func (call *ListUserCall) Trace(w io.Writer) *ListUserCall {
+	call.trace = w
+	return call
+}
+
+func (call *ListUserCall) makeURL() string {
+	return call.client.baseURL + "/Users"
+}
+
+func (call *ListUserCall) Do(ctx context.Context) (*resource.ListResponse, error) {
+	if err := call.err; err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	payload, err := call.payload()
+	if err != nil {
+		return nil, fmt.Errorf(`failed to generate request payload for ListUserCall: %w`, err)
+	}
+
+	trace := call.trace
+	if trace == nil {
+		trace = call.client.trace
+	}
+	u := call.makeURL()
+	if trace != nil {
+		fmt.Fprintf(trace, "trace: client sending call request to %q\n", u)
+	}
+
+	var vals url.Values
+	m := make(map[string]interface{})
+	if err := payload.AsMap(m); err != nil {
+		return nil, fmt.Errorf(`failed to convert resource into map: %w`, err)
+	}
+	if len(m) > 0 {
+		vals = make(url.Values)
+		for key, value := range m {
+			if key == "schemas" {
+				continue
+			}
+			switch value := value.(type) {
+			case []string:
+				vals.Add(key, strings.Join(value, ","))
+			case int:
+				vals.Add(key, strconv.Itoa(value))
+			default:
+				vals.Add(key, fmt.Sprintf(`%s`, value))
+			}
+		}
+	}
+	if enc := vals.Encode(); len(enc) > 0 {
+		u = u + "?" + vals.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to create new HTTP request: %w`, err)
+	}
+	req.Header.Set(`Accept`, `application/scim+json`)
+
+	if trace != nil {
+		buf, _ := httputil.DumpRequestOut(req, true)
+		fmt.Fprintf(trace, "%s\n", buf)
+	}
+
+	res, err := call.client.httpcl.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to send request to %q: %w`, u, err)
+	}
+	if trace != nil {
+		buf, _ := httputil.DumpResponse(res, true)
+		fmt.Fprintf(trace, "%s\n", buf)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		var serr resource.Error
+		var resBody bytes.Buffer
+		if err := json.NewDecoder(io.TeeReader(res.Body, &resBody)).Decode(&serr); err != nil {
+			return nil, fmt.Errorf("expected %d (got %d): %s", http.StatusOK, res.StatusCode, resBody.String())
+		}
+		return nil, &serr
+	}
+
+	var respayload resource.ListResponse
+	if err := json.NewDecoder(res.Body).Decode(&respayload); err != nil {
+		return nil, fmt.Errorf(`failed to decode call response: %w`, err)
+	}
+
+	return &respayload, nil
+}
@@ -4,19 +4,13 @@ go 1.18
 
 require (
 	github.com/cybozu-go/scim v0.0.0-20220520091855-11f56e98670d
-	github.com/doug-martin/goqu/v9 v9.18.0
 	github.com/google/uuid v1.3.0
-	github.com/stretchr/testify v1.8.0
 )
 
 require (
-	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/kr/pretty v0.3.0 // indirect
 	github.com/lestrrat-go/blackmagic v1.0.2-0.20220926062815-509018abad40 // indirect
 	github.com/lestrrat-go/mux v0.0.0-20220525044338-e2775b70cf3d // indirect
 	github.com/lestrrat-go/option v1.0.0 // indirect
-	github.com/pmezard/go-difflib v1.0.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
 replace github.com/cybozu-go/scim => ../
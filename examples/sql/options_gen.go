@@ -1,35 +0,0 @@
-// This file is auto-generated by tools/cmd/genoptions/main.go. DO NOT EDIT
-
-package sql
-
-type Option = option.Interface
-
-type Option interface {
-	Option
-	option()
-}
-
-type option struct {
-	Option
-}
-
-func (*option) option() {}
-
-type identColumnMapper struct{}
-type identDialect struct{}
-
-func (identColumnMapper) String() string {
-	return "WithColumnMapper"
-}
-
-func (identDialect) String() string {
-	return "WithDialect"
-}
-
-func WithColumnMapper(v ColumnMapper) Option {
-	return &option{option.New(identColumnMapper{}, v)}
-}
-
-func WithDialect(v string) Option {
-	return &option{option.New(identDialect{}, v)}
-}
@@ -0,0 +1,78 @@
+package filter_test
+
+import (
+	"testing"
+
+	"github.com/cybozu-go/scim/filter"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttrPath(t *testing.T) {
+	testcases := []struct {
+		Filter       string
+		Options      []filter.ParseOption
+		SchemaURI    string
+		Attribute    string
+		SubAttribute string
+	}{
+		{
+			Filter:    `userName eq "bjensen"`,
+			Attribute: `userName`,
+		},
+		{
+			Filter:       `name.familyName pr`,
+			Attribute:    `name`,
+			SubAttribute: `familyName`,
+		},
+		{
+			Filter:    `urn:ietf:params:scim:schemas:core:2.0:User:userName sw "bj"`,
+			SchemaURI: `urn:ietf:params:scim:schemas:core:2.0:User`,
+			Attribute: `userName`,
+		},
+		{
+			Filter:       `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.value eq "26118915-6090-4610-87e4-49d8ca9f808d"`,
+			SchemaURI:    `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User`,
+			Attribute:    `manager`,
+			SubAttribute: `value`,
+		},
+		{
+			Filter:       `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.value`,
+			Options:      []filter.ParseOption{filter.WithPatchExpression(true)},
+			SchemaURI:    `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User`,
+			Attribute:    `manager`,
+			SubAttribute: `value`,
+		},
+		{
+			Filter:    `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber`,
+			Options:   []filter.ParseOption{filter.WithPatchExpression(true)},
+			SchemaURI: `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User`,
+			Attribute: `employeeNumber`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Filter, func(t *testing.T) {
+			expr, err := filter.Parse(tc.Filter, tc.Options...)
+			require.NoError(t, err, `filter.Parse should succeed`)
+
+			var ident filter.IdentifierExpr
+			switch expr := expr.(type) {
+			case filter.PresenceExpr:
+				ident = expr.Attr().(filter.IdentifierExpr)
+			case filter.CompareExpr:
+				ident = expr.LHE().(filter.IdentifierExpr)
+			case filter.RegexExpr:
+				ident = expr.LHE().(filter.IdentifierExpr)
+			case filter.ValuePath:
+				ident = expr.ParentAttr().(filter.IdentifierExpr)
+			default:
+				t.Fatalf(`unexpected expression %T`, expr)
+			}
+
+			require.Equal(t, tc.SchemaURI, ident.SchemaURI(), `SchemaURI should match`)
+			require.Equal(t, tc.Attribute, ident.Attribute(), `Attribute should match`)
+			require.Equal(t, tc.SubAttribute, ident.SubAttribute(), `SubAttribute should match`)
+		})
+	}
+}
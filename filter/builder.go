@@ -0,0 +1,276 @@
+package filter
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/cybozu-go/scim/filter/internal/expr"
+)
+
+// AttrBuilder is used to build expressions against an attribute.
+// Use `filter.Attr()` to create one.
+type AttrBuilder struct {
+	name string
+	err  error
+}
+
+// ExprBuilder holds the expression built using `filter.Attr()`, and
+// allows it to be combined with other expressions.
+//
+// Errors that occur while building the expression (e.g. invalid
+// attribute paths, or values that cannot be represented in a filter)
+// are reported when `Build()` is called.
+type ExprBuilder struct {
+	expr Expr
+	err  error
+}
+
+// Attr creates a new builder for expressions against the attribute
+// specified by name. The name may contain a sub-attribute, and may be
+// prefixed by a schema URI (e.g. `name.familyName`, or
+// `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber`)
+//
+// Values given to the builder are properly escaped, so you do not need
+// to worry about quotes appearing in user supplied values:
+//
+//	filter.Attr(`emails`).Sub(filter.Attr(`value`).Eq(`x@example.com`)).
+//	  And(filter.Attr(`active`).Eq(true))
+func Attr(name string) *AttrBuilder {
+	return &AttrBuilder{
+		name: name,
+		err:  expr.ValidateAttrPath(name),
+	}
+}
+
+func (b *AttrBuilder) ident() Expr {
+	return NewIdentifierExpr(b.name)
+}
+
+func (b *AttrBuilder) compare(op string, v interface{}) *ExprBuilder {
+	if b.err != nil {
+		return &ExprBuilder{err: b.err}
+	}
+
+	value, err := valueExpr(v)
+	if err != nil {
+		return &ExprBuilder{err: fmt.Errorf(`invalid value for %q %s: %w`, b.name, op, err)}
+	}
+	return &ExprBuilder{expr: NewCompareExpr(b.ident(), op, value)}
+}
+
+func (b *AttrBuilder) regex(op string, v string) *ExprBuilder {
+	if b.err != nil {
+		return &ExprBuilder{err: b.err}
+	}
+	return &ExprBuilder{expr: NewRegexExpr(b.ident(), op, v)}
+}
+
+// Pr creates a `pr` (present) expression
+func (b *AttrBuilder) Pr() *ExprBuilder {
+	if b.err != nil {
+		return &ExprBuilder{err: b.err}
+	}
+	return &ExprBuilder{expr: NewPresenceExpr(b.ident(), PresenceOp)}
+}
+
+// Eq creates an `eq` (equal) expression. v may be a string, a boolean,
+// a number, a time.Time, or nil (which is represented as `null`)
+func (b *AttrBuilder) Eq(v interface{}) *ExprBuilder {
+	return b.compare(EqualOp, v)
+}
+
+// Ne creates a `ne` (not equal) expression. See Eq for the list of
+// types that v may take.
+func (b *AttrBuilder) Ne(v interface{}) *ExprBuilder {
+	return b.compare(NotEqualOp, v)
+}
+
+// Co creates a `co` (contains) expression
+func (b *AttrBuilder) Co(v string) *ExprBuilder {
+	return b.regex(ContainsOp, v)
+}
+
+// Sw creates a `sw` (starts with) expression
+func (b *AttrBuilder) Sw(v string) *ExprBuilder {
+	return b.regex(StartsWithOp, v)
+}
+
+// Ew creates a `ew` (ends with) expression
+func (b *AttrBuilder) Ew(v string) *ExprBuilder {
+	return b.regex(EndsWithOp, v)
+}
+
+// Gt creates a `gt` (greater than) expression. See Eq for the list of
+// types that v may take.
+func (b *AttrBuilder) Gt(v interface{}) *ExprBuilder {
+	return b.compare(GreaterThanOp, v)
+}
+
+// Ge creates a `ge` (greater than or equal to) expression. See Eq for
+// the list of types that v may take.
+func (b *AttrBuilder) Ge(v interface{}) *ExprBuilder {
+	return b.compare(GreaterThanOrEqualToOp, v)
+}
+
+// Lt creates a `lt` (less than) expression. See Eq for the list of
+// types that v may take.
+func (b *AttrBuilder) Lt(v interface{}) *ExprBuilder {
+	return b.compare(LessThanOp, v)
+}
+
+// Le creates a `le` (less than or equal to) expression. See Eq for
+// the list of types that v may take.
+func (b *AttrBuilder) Le(v interface{}) *ExprBuilder {
+	return b.compare(LessThanOrEqualToOp, v)
+}
+
+// Sub creates a value path expression (e.g. `emails[type eq "work"]`),
+// which applies the filter to each of the values of a complex attribute.
+func (b *AttrBuilder) Sub(filter *ExprBuilder) *ExprBuilder {
+	if b.err != nil {
+		return &ExprBuilder{err: b.err}
+	}
+	if filter.err != nil {
+		return &ExprBuilder{err: filter.err}
+	}
+	return &ExprBuilder{expr: NewValuePath(b.ident(), nil, filter.expr)}
+}
+
+// And combines the expression with the others using `and`
+func (b *ExprBuilder) And(others ...*ExprBuilder) *ExprBuilder {
+	return combine(AndOp, b, others)
+}
+
+// Or combines the expression with the others using `or`
+func (b *ExprBuilder) Or(others ...*ExprBuilder) *ExprBuilder {
+	return combine(OrOp, b, others)
+}
+
+// Not negates the expression
+func (b *ExprBuilder) Not() *ExprBuilder {
+	return Not(b)
+}
+
+// Build returns the expression that was built, or the first error
+// that occurred while building it.
+func (b *ExprBuilder) Build() (Expr, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	return b.expr, nil
+}
+
+// MustBuild is like Build, but panics if an error occurred
+func (b *ExprBuilder) MustBuild() Expr {
+	e, err := b.Build()
+	if err != nil {
+		panic(fmt.Errorf(`failed to build filter expression: %w`, err))
+	}
+	return e
+}
+
+// String returns the expression in SCIM filter syntax. If an error
+// occurred while building the expression, an empty string is returned.
+func (b *ExprBuilder) String() string {
+	if b.err != nil {
+		return ""
+	}
+	return Format(b.expr)
+}
+
+// And combines the expressions using `and`
+func And(list ...*ExprBuilder) *ExprBuilder {
+	if len(list) == 0 {
+		return &ExprBuilder{err: fmt.Errorf(`filter.And requires at least one expression`)}
+	}
+	return combine(AndOp, list[0], list[1:])
+}
+
+// Or combines the expressions using `or`
+func Or(list ...*ExprBuilder) *ExprBuilder {
+	if len(list) == 0 {
+		return &ExprBuilder{err: fmt.Errorf(`filter.Or requires at least one expression`)}
+	}
+	return combine(OrOp, list[0], list[1:])
+}
+
+// Not negates the expression
+func Not(b *ExprBuilder) *ExprBuilder {
+	if b.err != nil {
+		return b
+	}
+	return &ExprBuilder{expr: NewParenExpr(NotOp, b.expr)}
+}
+
+func combine(op string, first *ExprBuilder, others []*ExprBuilder) *ExprBuilder {
+	if first.err != nil {
+		return first
+	}
+
+	e := first.expr
+	for _, other := range others {
+		if other.err != nil {
+			return other
+		}
+		e = NewLogExpr(e, op, other.expr)
+	}
+	return &ExprBuilder{expr: e}
+}
+
+// valueExpr converts a Go value into a comparison value expression
+func valueExpr(v interface{}) (Expr, error) {
+	switch v := v.(type) {
+	case nil:
+		return NewIdentifierExpr(Null), nil
+	case string:
+		return NewAttrValueExpr(v), nil
+	case bool:
+		return NewBoolExpr(v), nil
+	case int:
+		return NewNumberExpr(v), nil
+	case int8:
+		return NewNumberExpr(int(v)), nil
+	case int16:
+		return NewNumberExpr(int(v)), nil
+	case int32:
+		return NewNumberExpr(int(v)), nil
+	case int64:
+		return NewNumberExpr(int(v)), nil
+	case uint8:
+		return NewNumberExpr(int(v)), nil
+	case uint16:
+		return NewNumberExpr(int(v)), nil
+	case uint:
+		return unsignedExpr(uint64(v))
+	case uint32:
+		return unsignedExpr(uint64(v))
+	case uint64:
+		return unsignedExpr(v)
+	case float32:
+		return decimalExpr(float64(v))
+	case float64:
+		return decimalExpr(v)
+	case time.Time:
+		return NewAttrValueExpr(v.Format(time.RFC3339Nano)), nil
+	default:
+		return nil, fmt.Errorf(`unsupported value type %T`, v)
+	}
+}
+
+// unsignedExpr converts unsigned integers that may not fit in an int
+func unsignedExpr(v uint64) (Expr, error) {
+	if v > math.MaxInt {
+		return nil, fmt.Errorf(`value %d is out of range`, v)
+	}
+	return NewNumberExpr(int(v)), nil
+}
+
+// decimalExpr converts floating point numbers, which must be finite
+// as NaN and infinities cannot be represented in filters
+func decimalExpr(v float64) (Expr, error) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil, fmt.Errorf(`value %v is not a finite number`, v)
+	}
+	return NewDecimalExpr(v), nil
+}
@@ -0,0 +1,147 @@
+package filter_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/cybozu-go/scim/filter"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuilder(t *testing.T) {
+	lastModified := time.Date(2011, 5, 13, 4, 42, 34, 0, time.UTC)
+
+	testcases := []struct {
+		Name     string
+		Builder  *filter.ExprBuilder
+		Expected string
+		Error    bool
+	}{
+		{
+			Name:     "simple comparison",
+			Builder:  filter.Attr(`userName`).Eq(`bjensen`),
+			Expected: `userName eq "bjensen"`,
+		},
+		{
+			Name:     "values are escaped",
+			Builder:  filter.Attr(`displayName`).Eq(`x" or userName pr or displayName eq "`),
+			Expected: `displayName eq "x\" or userName pr or displayName eq \""`,
+		},
+		{
+			Name:     "value path",
+			Builder:  filter.Attr(`emails`).Sub(filter.Attr(`value`).Eq(`x@y`)).And(filter.Attr(`active`).Eq(true)),
+			Expected: `emails[value eq "x@y"] and active eq true`,
+		},
+		{
+			Name:     "mixed logical operators",
+			Builder:  filter.Attr(`title`).Pr().And(filter.Attr(`userType`).Eq(`Employee`).Or(filter.Attr(`userType`).Eq(`Intern`))),
+			Expected: `title pr and (userType eq "Employee" or userType eq "Intern")`,
+		},
+		{
+			Name:     "variadic",
+			Builder:  filter.Or(filter.Attr(`name.familyName`).Co(`O'Malley`), filter.Attr(`userName`).Sw(`J`), filter.Attr(`userName`).Ew(`.com`)),
+			Expected: `name.familyName co "O'Malley" or userName sw "J" or userName ew ".com"`,
+		},
+		{
+			Name:     "not",
+			Builder:  filter.Attr(`nickName`).Ne(nil).Not(),
+			Expected: `not (nickName ne null)`,
+		},
+		{
+			Name:     "numbers and dates",
+			Builder:  filter.And(filter.Attr(`age`).Ge(20), filter.Attr(`score`).Lt(1.5), filter.Attr(`meta.lastModified`).Gt(lastModified)),
+			Expected: `age ge 20 and score lt 1.5 and meta.lastModified gt "2011-05-13T04:42:34Z"`,
+		},
+		{
+			Name:     "unsigned integers",
+			Builder:  filter.And(filter.Attr(`a`).Eq(uint(1)), filter.Attr(`b`).Eq(uint8(2)), filter.Attr(`c`).Eq(uint32(3)), filter.Attr(`d`).Eq(uint64(4))),
+			Expected: `a eq 1 and b eq 2 and c eq 3 and d eq 4`,
+		},
+		{
+			Name:    "unsigned integer out of range",
+			Builder: filter.Attr(`a`).Eq(uint64(math.MaxUint64)),
+			Error:   true,
+		},
+		{
+			Name:    "NaN",
+			Builder: filter.Attr(`a`).Gt(math.NaN()),
+			Error:   true,
+		},
+		{
+			Name:    "positive infinity",
+			Builder: filter.Attr(`a`).Gt(math.Inf(1)),
+			Error:   true,
+		},
+		{
+			Name:    "negative infinity",
+			Builder: filter.Attr(`a`).Lt(float32(math.Inf(-1))),
+			Error:   true,
+		},
+		{
+			Name:     "URN attribute path",
+			Builder:  filter.Attr(`urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber`).Le(`701984`),
+			Expected: `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber le "701984"`,
+		},
+		{
+			Name:    "invalid attribute path",
+			Builder: filter.Attr(`foo:bar`).Eq(`x`).And(filter.Attr(`title`).Pr()),
+			Error:   true,
+		},
+		{
+			Name:    "attribute name containing an expression",
+			Builder: filter.Attr(`userName pr or userName`).Eq(`x`),
+			Error:   true,
+		},
+		{
+			Name:    "attribute name containing quotes",
+			Builder: filter.Attr(`userName eq "x"`).Pr(),
+			Error:   true,
+		},
+		{
+			Name:    "attribute name containing brackets",
+			Builder: filter.Attr(`emails[type eq "work"]`).Pr(),
+			Error:   true,
+		},
+		{
+			Name:    "sub-attribute containing parentheses",
+			Builder: filter.Attr(`name.familyName) or (title`).Pr(),
+			Error:   true,
+		},
+		{
+			Name:    "schema URI containing spaces",
+			Builder: filter.Attr(`urn:foo or title pr:userName`).Pr(),
+			Error:   true,
+		},
+		{
+			Name:    "unsupported value",
+			Builder: filter.Attr(`title`).Pr().And(filter.Attr(`userName`).Eq([]string{`x`})),
+			Error:   true,
+		},
+		{
+			Name:    "error in value path",
+			Builder: filter.Attr(`emails`).Sub(filter.Attr(`value`).Eq(struct{}{})),
+			Error:   true,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			expr, err := tc.Builder.Build()
+			if tc.Error {
+				require.Error(t, err, `Build should fail`)
+				require.Panics(t, func() { tc.Builder.MustBuild() }, `MustBuild should panic`)
+				return
+			}
+			require.NoError(t, err, `Build should succeed`)
+			require.Equal(t, tc.Expected, filter.Format(expr), `filter should match`)
+			require.Equal(t, tc.Expected, tc.Builder.String(), `String() should match`)
+
+			// the result should be parsable
+			parsed, err := filter.Parse(tc.Expected)
+			require.NoError(t, err, `filter.Parse should succeed`)
+			require.Equal(t, tc.Expected, filter.Format(parsed), `filter.Format should match`)
+		})
+	}
+}
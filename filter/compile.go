@@ -0,0 +1,362 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cybozu-go/scim/resource"
+	"github.com/cybozu-go/scim/schema"
+)
+
+// Predicate reports whether the resource satisfies the filter
+// that it was compiled from.
+type Predicate func(interface{}) bool
+
+// Compile converts the filter expression into a Predicate, which can
+// be used to evaluate many resources without re-examining the
+// expression each time.
+//
+// The semantics are the same as `filter.Match()`, but all of the
+// work that does not depend on the resource is done ahead of time:
+// attribute paths are resolved against the schema, comparison values
+// (including dateTime values) are parsed, and case-folding is decided
+// using the `caseExact` characteristic of each attribute.
+//
+// Problems that can be detected from the expression alone, such as
+// malformed dateTime values or `gt` against boolean values, are
+// reported by Compile. Values in the resource that cannot be compared
+// against the filter cause the comparison to evaluate to false.
+//
+// s may be nil, in which case all attributes are treated as if they
+// were not case-exact.
+func Compile(expr Expr, s *resource.Schema) (Predicate, error) {
+	c := &compiler{}
+	if s != nil {
+		c.uri = s.ID()
+		c.attrs = s.Attributes()
+	}
+	return c.compile(expr)
+}
+
+type compiler struct {
+	uri   string
+	attrs []*resource.SchemaAttribute
+}
+
+// accessor returns the values found at an attribute path
+type accessor func(interface{}) []interface{}
+
+func (c *compiler) compile(v Expr) (Predicate, error) {
+	switch v := v.(type) {
+	case PresenceExpr:
+		return c.compilePresenceExpr(v)
+	case CompareExpr:
+		return c.compileComparison(v.LHE(), v.Operator(), v.RHE())
+	case RegexExpr:
+		return c.compileComparison(v.LHE(), v.Operator(), v.Value())
+	case LogExpr:
+		return c.compileLogExpr(v)
+	case ParenExpr:
+		return c.compileParenExpr(v)
+	case ValuePath:
+		return c.compileValuePath(v)
+	default:
+		return nil, fmt.Errorf(`unhandled expression type: %T`, v)
+	}
+}
+
+// path resolves the attribute path, and returns a function to fetch
+// the values from a resource, as well as the schema attribute that
+// describes the values, if available
+func (c *compiler) path(v Expr) (accessor, *resource.SchemaAttribute, error) {
+	name, err := attrName(v)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	path := parseAttrPath(name)
+	if path.uri == "" || strings.EqualFold(path.uri, c.uri) {
+		get := func(root interface{}) []interface{} {
+			return resolve([]interface{}{root}, path.names)
+		}
+		return get, findAttributePath(c.attrs, path.names), nil
+	}
+
+	var attrs []*resource.SchemaAttribute
+	if s, ok := schema.Get(path.uri); ok {
+		attrs = s.Attributes()
+	}
+	get := func(root interface{}) []interface{} {
+		ext, ok := lookupKey(root, path.uri)
+		if !ok {
+			return nil
+		}
+		return resolve([]interface{}{ext}, path.names)
+	}
+	return get, findAttributePath(attrs, path.names), nil
+}
+
+func (c *compiler) compilePresenceExpr(v PresenceExpr) (Predicate, error) {
+	get, _, err := c.path(v.Attr())
+	if err != nil {
+		return nil, fmt.Errorf(`left hand side of %q is not valid: %w`, v.Operator(), err)
+	}
+	return func(root interface{}) bool {
+		return isPresent(get(root))
+	}, nil
+}
+
+func (c *compiler) compileComparison(lhe Expr, op string, rhe interface{}) (Predicate, error) {
+	get, attr, err := c.path(lhe)
+	if err != nil {
+		return nil, fmt.Errorf(`left hand side of %q is not valid: %w`, op, err)
+	}
+
+	rhs, err := literal(rhe)
+	if err != nil {
+		return nil, fmt.Errorf(`right hand side of %q is not valid: %w`, op, err)
+	}
+
+	if rhs == nil {
+		switch op {
+		case EqualOp:
+			return func(root interface{}) bool {
+				return !isPresent(get(root))
+			}, nil
+		case NotEqualOp:
+			return func(root interface{}) bool {
+				return isPresent(get(root))
+			}, nil
+		default:
+			return nil, fmt.Errorf(`operator %q cannot be used against null`, op)
+		}
+	}
+
+	if attr != nil && attr.Type() == resource.Complex {
+		return nil, fmt.Errorf(`operator %q cannot be used against complex attribute %q`, op, attr.Name())
+	}
+
+	match, err := compileCompare(op, attr, rhs)
+	if err != nil {
+		return nil, err
+	}
+	return func(root interface{}) bool {
+		for _, value := range get(root) {
+			if match(value) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// compileCompare prepares the comparison of a single value from the
+// resource against the literal value in the filter
+func compileCompare(op string, attr *resource.SchemaAttribute, rhs interface{}) (func(interface{}) bool, error) {
+	var cmp func(interface{}) bool
+	switch rhs := rhs.(type) {
+	case string:
+		f, err := compileStringCompare(op, attr, rhs)
+		if err != nil {
+			return nil, err
+		}
+		cmp = f
+	case bool:
+		switch op {
+		case EqualOp, NotEqualOp:
+		default:
+			return nil, fmt.Errorf(`operator %q cannot be used against boolean values`, op)
+		}
+		cmp = func(lhs interface{}) bool {
+			b, ok := lhs.(bool)
+			return ok && (b == rhs) == (op == EqualOp)
+		}
+	case float64:
+		if _, err := compareOrdered(op, 0); err != nil {
+			return nil, err
+		}
+		cmp = func(lhs interface{}) bool {
+			l, ok := toFloat(lhs)
+			if !ok {
+				return false
+			}
+			var c int
+			switch {
+			case l < rhs:
+				c = -1
+			case l > rhs:
+				c = 1
+			}
+			ok, _ = compareOrdered(op, c)
+			return ok
+		}
+	default:
+		return nil, fmt.Errorf(`unhandled comparison value type: %T`, rhs)
+	}
+
+	// Complex values can only be compared through their sub-attributes.
+	// Without a schema this is only known when the resource is evaluated,
+	// so such comparisons evaluate to false
+	return func(lhs interface{}) bool {
+		return !isComplex(lhs) && cmp(lhs)
+	}, nil
+}
+
+func compileStringCompare(op string, attr *resource.SchemaAttribute, rhs string) (func(interface{}) bool, error) {
+	var strOp func(string, string) bool
+	switch op {
+	case ContainsOp:
+		strOp = strings.Contains
+	case StartsWithOp:
+		strOp = strings.HasPrefix
+	case EndsWithOp:
+		strOp = strings.HasSuffix
+	default:
+		if _, err := compareOrdered(op, 0); err != nil {
+			return nil, err
+		}
+		strOp = func(l, r string) bool {
+			ok, _ := compareOrdered(op, strings.Compare(l, r))
+			return ok
+		}
+	}
+
+	// The value is folded once, and the case-folding of the value
+	// in the resource is decided once for the attribute
+	folded := strings.ToLower(rhs)
+	caseExact := attr != nil && (attr.CaseExact() || attr.Type() == resource.Binary)
+
+	if attr != nil && attr.Type() == resource.Binary {
+		switch op {
+		case EqualOp, NotEqualOp:
+		default:
+			return nil, fmt.Errorf(`operator %q cannot be used against binary values`, op)
+		}
+	}
+
+	// dateTime values must be compared as points in time. If the
+	// schema does not tell us that the attribute is a dateTime, the
+	// literal may not be a dateTime at all, so a parse error only causes
+	// comparisons against time.Time values to evaluate to false
+	var rhsTime time.Time
+	var rhsTimeErr error
+	isDateTime := attr != nil && attr.Type() == resource.DateTime
+	if isDateTime {
+		t, err := resource.ParseDateTime(rhs)
+		if err != nil {
+			return nil, fmt.Errorf(`failed to parse %q as dateTime: %w`, rhs, err)
+		}
+		rhsTime = t
+	} else {
+		rhsTime, rhsTimeErr = resource.ParseDateTime(rhs)
+	}
+
+	compareTime := func(lhs time.Time) bool {
+		if rhsTimeErr != nil {
+			return false
+		}
+		var c int
+		switch {
+		case lhs.Before(rhsTime):
+			c = -1
+		case lhs.After(rhsTime):
+			c = 1
+		}
+		ok, _ := compareOrdered(op, c)
+		return ok
+	}
+
+	return func(lhs interface{}) bool {
+		switch lhs := lhs.(type) {
+		case string:
+			if isDateTime {
+				t, err := resource.ParseDateTime(lhs)
+				if err != nil {
+					return false
+				}
+				return compareTime(t)
+			}
+			if caseExact {
+				return strOp(lhs, rhs)
+			}
+			return strOp(strings.ToLower(lhs), folded)
+		case time.Time:
+			return compareTime(lhs)
+		default:
+			return false
+		}
+	}, nil
+}
+
+func (c *compiler) compileLogExpr(v LogExpr) (Predicate, error) {
+	lhs, err := c.compile(v.LHE())
+	if err != nil {
+		return nil, fmt.Errorf(`failed to compile left hand side of %q: %w`, v.Operator(), err)
+	}
+	rhs, err := c.compile(v.RHS())
+	if err != nil {
+		return nil, fmt.Errorf(`failed to compile right hand side of %q: %w`, v.Operator(), err)
+	}
+
+	switch v.Operator() {
+	case AndOp:
+		return func(root interface{}) bool {
+			return lhs(root) && rhs(root)
+		}, nil
+	case OrOp:
+		return func(root interface{}) bool {
+			return lhs(root) || rhs(root)
+		}, nil
+	default:
+		return nil, fmt.Errorf(`unhandled logical operator %q`, v.Operator())
+	}
+}
+
+func (c *compiler) compileParenExpr(v ParenExpr) (Predicate, error) {
+	sub, err := c.compile(v.SubExpr())
+	if err != nil {
+		return nil, err
+	}
+
+	switch v.Operator() {
+	case "":
+		return sub, nil
+	case NotOp:
+		return func(root interface{}) bool {
+			return !sub(root)
+		}, nil
+	default:
+		return nil, fmt.Errorf(`unhandled grouping operator %q`, v.Operator())
+	}
+}
+
+func (c *compiler) compileValuePath(v ValuePath) (Predicate, error) {
+	get, attr, err := c.path(v.ParentAttr())
+	if err != nil {
+		return nil, fmt.Errorf(`parent attribute of value path is not valid: %w`, err)
+	}
+
+	if v.SubExpr() == nil {
+		return func(root interface{}) bool {
+			return isPresent(get(root))
+		}, nil
+	}
+
+	sub := &compiler{}
+	if attr != nil {
+		sub.attrs = attr.SubAttributes()
+	}
+	match, err := sub.compile(v.SubExpr())
+	if err != nil {
+		return nil, fmt.Errorf(`failed to compile filter for value path: %w`, err)
+	}
+	return func(root interface{}) bool {
+		for _, value := range get(root) {
+			if match(value) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
@@ -0,0 +1,160 @@
+package filter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cybozu-go/scim/filter"
+	"github.com/cybozu-go/scim/resource"
+	"github.com/cybozu-go/scim/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestUser(t testing.TB) *resource.User {
+	t.Helper()
+
+	var b resource.Builder
+	lastModified, err := time.Parse(time.RFC3339, "2011-05-13T04:42:34Z")
+	require.NoError(t, err, `time.Parse should succeed`)
+
+	return b.User().
+		ID("2819c223-7f76-453a-919d-413861904646").
+		ExternalID("bjensen").
+		UserName("bjensen@example.com").
+		Title("Tour Guide").
+		Active(true).
+		Name(b.Names().
+			FamilyName("O'Malley").
+			GivenName("Barbara").
+			MustBuild()).
+		Emails(
+			b.Email().
+				Value("bjensen@example.com").
+				Type("work").
+				Primary(true).
+				MustBuild(),
+			b.Email().
+				Value("babs@jensen.org").
+				Type("home").
+				MustBuild(),
+		).
+		Extension(resource.EnterpriseUserSchemaURI, b.EnterpriseUser().
+			EmployeeNumber("701984").
+			MustBuild()).
+		Meta(b.Meta().
+			ResourceType("User").
+			LastModified(lastModified).
+			MustBuild()).
+		MustBuild()
+}
+
+func TestCompile(t *testing.T) {
+	userSchema, ok := schema.Get(resource.UserSchemaURI)
+	require.True(t, ok, `schema.Get should succeed`)
+
+	user := newTestUser(t)
+	resources := []interface{}{
+		user,
+		map[string]interface{}{
+			"schemas":  []interface{}{resource.UserSchemaURI},
+			"userName": "BJensen@example.com",
+			"meta":     map[string]interface{}{"lastModified": "2011-05-13T10:42:34+09:00"},
+		},
+	}
+
+	filters := []string{
+		`userName eq "bjensen@example.com"`,
+		`USERNAME eq "BJENSEN@EXAMPLE.COM"`,
+		`externalId eq "bjensen"`,
+		`externalId eq "BJENSEN"`,
+		`name.familyName co "o'malley"`,
+		`userName sw "J"`,
+		`userName ew "example.COM"`,
+		`userName gt "a"`,
+		`title pr`,
+		`nickName eq null`,
+		`title ne null`,
+		`active eq true`,
+		`active ne true`,
+		`meta.lastModified gt "2011-05-13T04:42:34Z"`,
+		`meta.lastModified ge "2011-05-13T04:42:34Z"`,
+		`meta.lastModified lt "2011-05-13T04:00:00Z"`,
+		`emails.type eq "home"`,
+		`emails.value co "jensen.org"`,
+		`emails[type eq "work" and value co "@example.com"]`,
+		`emails[type eq "home" and value co "@example.com"]`,
+		`emails[primary eq true]`,
+		`title pr and userType eq "Employee"`,
+		`title pr or userType eq "Intern"`,
+		`not (title pr)`,
+		`urn:ietf:params:scim:schemas:core:2.0:User:userName sw "bj"`,
+		`urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber eq "701984"`,
+		`urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber gt "7"`,
+	}
+
+	for _, src := range filters {
+		src := src
+		t.Run(src, func(t *testing.T) {
+			expr, err := filter.Parse(src)
+			require.NoError(t, err, `filter.Parse should succeed`)
+
+			pred, err := filter.Compile(expr, userSchema)
+			require.NoError(t, err, `filter.Compile should succeed`)
+
+			for _, r := range resources {
+				expected, err := filter.Match(expr, r, filter.WithSchema(userSchema))
+				require.NoError(t, err, `filter.Match should succeed`)
+				require.Equal(t, expected, pred(r), `compiled predicate should agree with filter.Match (%T)`, r)
+			}
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		for _, src := range []string{
+			`active gt true`,
+			`meta.lastModified lt "yesterday"`,
+			`userName gt null`,
+			`x509Certificates.value sw "TUlJRFF6"`,
+			`emails co "jensen.org"`,
+		} {
+			expr, err := filter.Parse(src)
+			require.NoError(t, err, `filter.Parse should succeed`)
+			_, err = filter.Compile(expr, userSchema)
+			require.Error(t, err, `filter.Compile should fail for %q`, src)
+		}
+	})
+}
+
+const benchmarkFilter = `userName sw "bj" and (emails[type eq "work" and value co "@example.com"] or title pr) and meta.lastModified gt "2011-01-01T00:00:00Z"`
+
+func BenchmarkMatch(b *testing.B) {
+	user := newTestUser(b)
+	expr, err := filter.Parse(benchmarkFilter)
+	require.NoError(b, err, `filter.Parse should succeed`)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if ok, err := filter.Match(expr, user); err != nil || !ok {
+			b.Fatalf(`filter.Match should succeed (ok = %t, err = %s)`, ok, err)
+		}
+	}
+}
+
+func BenchmarkCompile(b *testing.B) {
+	userSchema, ok := schema.Get(resource.UserSchemaURI)
+	require.True(b, ok, `schema.Get should succeed`)
+
+	user := newTestUser(b)
+	expr, err := filter.Parse(benchmarkFilter)
+	require.NoError(b, err, `filter.Parse should succeed`)
+
+	pred, err := filter.Compile(expr, userSchema)
+	require.NoError(b, err, `filter.Compile should succeed`)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !pred(user) {
+			b.Fatal(`predicate should match`)
+		}
+	}
+}
@@ -71,6 +71,12 @@ func NewNumberExpr(lit int) NumberExpr {
 	return expr.NewNumberExpr(lit)
 }
 
+type DecimalExpr = expr.DecimalExpr
+
+func NewDecimalExpr(lit float64) DecimalExpr {
+	return expr.NewDecimalExpr(lit)
+}
+
 type IdentifierExpr = expr.IdentifierExpr
 
 func NewIdentifierExpr(lit string) IdentifierExpr {
@@ -13,19 +13,55 @@ import (
 
 type Expr = expr.Interface
 
+// ParseError is the error returned by `filter.Parse()` when the source
+// is not a valid filter. It contains the location of the problem, the
+// offending token, and the list of tokens that were expected instead.
+type ParseError = expr.ParseError
+
+// LimitError is the error reported when a filter exceeds the limits
+// specified by `filter.WithMaxLength()`, `filter.WithMaxDepth()` or
+// `filter.WithMaxNodes()`. It is wrapped in a *filter.ParseError, and
+// can be extracted using `errors.As()`.
+type LimitError = expr.LimitError
+
+// Kinds of limits reported in LimitError.Limit
+const (
+	LimitLength = expr.LimitLength
+	LimitDepth  = expr.LimitDepth
+	LimitNodes  = expr.LimitNodes
+)
+
 // Parse takes a string input and converts it into an expression.
 // The `options` parameter can be specified to toggle specific behavior.
 //
 // By default the the parser expects an expression that is used for SCIM filters.
 // But by specifying filter.WithPatchExpression(true), it adds support for
 // allowing a single "valuePath" element to be present.
+//
+// Filters sent by clients can be limited in size using
+// filter.WithMaxLength(), filter.WithMaxDepth() and filter.WithMaxNodes(),
+// so that overly complex filters are rejected before they reach the
+// backend.
+//
+// If the source cannot be parsed, a *filter.ParseError is returned.
+// If the source exceeds one of the limits, the *filter.ParseError wraps
+// a *filter.LimitError.
 func Parse(src string, options ...ParseOption) (Expr, error) {
 	parseFn := fparser.Parse
+	var limits expr.Limits
+
+	//nolint:forcetypeassert
 	for _, option := range options {
 		switch option.Ident() {
 		case identPatchExpression{}:
 			parseFn = pparser.Parse
+		case identMaxLength{}:
+			limits.MaxLength = option.Value().(int)
+		case identMaxDepth{}:
+			limits.MaxDepth = option.Value().(int)
+		case identMaxNodes{}:
+			limits.MaxNodes = option.Value().(int)
 		}
 	}
-	return parseFn(src)
+	return parseFn(src, limits)
 }
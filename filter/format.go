@@ -0,0 +1,15 @@
+package filter
+
+import "github.com/cybozu-go/scim/filter/internal/expr"
+
+// Format converts the expression back into the SCIM filter syntax.
+// It is equivalent to calling the `String()` method on the expression.
+//
+// The output is canonical, and can be fed back to `filter.Parse()`:
+// operators are written in lower case, tokens are separated by a single
+// space, string values are quoted and escaped as JSON strings, and
+// nested logical expressions are enclosed in parentheses where the
+// structure of the expression requires them.
+func Format(e Expr) string {
+	return expr.Format(e)
+}
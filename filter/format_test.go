@@ -0,0 +1,94 @@
+package filter_test
+
+import (
+	"testing"
+
+	"github.com/cybozu-go/scim/filter"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	testcases := []struct {
+		Name     string
+		Filter   string
+		Expr     filter.Expr
+		Expected string
+		Options  []filter.ParseOption
+	}{
+		{Filter: `userName Eq "bjensen"`, Expected: `userName eq "bjensen"`},
+		{Filter: `title  PR`, Expected: `title pr`},
+		{Filter: `name.familyName co "O'Malley"`, Expected: `name.familyName co "O'Malley"`},
+		{Filter: `userName sw "J"`, Expected: `userName sw "J"`},
+		{Filter: `displayName eq "say \"hi\" \\ あ"`, Expected: `displayName eq "say \"hi\" \\ あ"`},
+		{Filter: `displayName eq ""`, Expected: `displayName eq ""`},
+		{Filter: `nickName eq null`, Expected: `nickName eq null`},
+		{Filter: `active eq TRUE`, Expected: `active eq true`},
+		{Filter: `age gt 30`, Expected: `age gt 30`},
+		{Filter: `age gt -30`, Expected: `age gt -30`},
+		{Filter: `score gt 1.50`, Expected: `score gt 1.5`},
+		{Filter: `score gt 2.0`, Expected: `score gt 2.0`},
+		{Filter: `score gt 1E21`, Expected: `score gt 1e+21`},
+		{Filter: `score lt 2.5e-3`, Expected: `score lt 0.0025`},
+		{Filter: `title pr AND userType eq "Employee"`, Expected: `title pr and userType eq "Employee"`},
+		{Filter: `a pr and b pr and c pr`, Expected: `a pr and b pr and c pr`},
+		{Filter: `a pr and (b pr or c pr)`, Expected: `a pr and (b pr or c pr)`},
+		{Filter: `NOT (title pr)`, Expected: `not (title pr)`},
+		{Filter: `emails[type eq "work" and value co "@example.com"]`, Expected: `emails[type eq "work" and value co "@example.com"]`},
+		{
+			Filter:   `members[value eq "2819c223-7f76-453a-919d-413861904646"].displayName`,
+			Expected: `members[value eq "2819c223-7f76-453a-919d-413861904646"].displayName`,
+			Options:  []filter.ParseOption{filter.WithPatchExpression(true)},
+		},
+		{
+			Name: "nested logical expression on the right hand side",
+			Expr: filter.NewLogExpr(
+				filter.NewPresenceExpr(filter.NewIdentifierExpr(`a`), filter.PresenceOp),
+				filter.AndOp,
+				filter.NewLogExpr(
+					filter.NewPresenceExpr(filter.NewIdentifierExpr(`b`), filter.PresenceOp),
+					filter.AndOp,
+					filter.NewPresenceExpr(filter.NewIdentifierExpr(`c`), filter.PresenceOp),
+				),
+			),
+			Expected: `a pr and (b pr and c pr)`,
+		},
+		{
+			Name: "mixed logical operators",
+			Expr: filter.NewLogExpr(
+				filter.NewLogExpr(
+					filter.NewPresenceExpr(filter.NewIdentifierExpr(`a`), filter.PresenceOp),
+					filter.OrOp,
+					filter.NewPresenceExpr(filter.NewIdentifierExpr(`b`), filter.PresenceOp),
+				),
+				filter.AndOp,
+				filter.NewPresenceExpr(filter.NewIdentifierExpr(`c`), filter.PresenceOp),
+			),
+			Expected: `(a pr or b pr) and c pr`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		name := tc.Name
+		if name == "" {
+			name = tc.Filter
+		}
+		t.Run(name, func(t *testing.T) {
+			expr := tc.Expr
+			if expr == nil {
+				var err error
+				expr, err = filter.Parse(tc.Filter, tc.Options...)
+				require.NoError(t, err, `filter.Parse should succeed`)
+			}
+
+			s := filter.Format(expr)
+			require.Equal(t, tc.Expected, s, `filter.Format should produce the expected output`)
+			require.Equal(t, s, expr.String(), `String() should be the same as filter.Format`)
+
+			// the output must be parsable, and should result in the same output
+			reparsed, err := filter.Parse(s, tc.Options...)
+			require.NoError(t, err, `filter.Parse should succeed on the formatted output`)
+			require.Equal(t, s, filter.Format(reparsed), `formatting should be stable`)
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseError is returned when the filter could not be parsed
+type ParseError struct {
+	// Line and Column point to the location of the offending token.
+	// Both are 1-based, and Column is counted in runes
+	Line   int
+	Column int
+
+	// Token is the offending token as it appeared in the source. It is
+	// empty if the source ended unexpectedly.
+	Token string
+
+	// Expected is the list of tokens that would have been accepted
+	// in place of the offending token, described in a human readable form
+	// (e.g. `attribute path`, `"eq"`, `end of input`)
+	Expected []string
+
+	// Err is the underlying error, if the problem was not a simple
+	// unexpected token (e.g. unterminated strings, malformed attribute paths)
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	var where string
+	if e.Line > 1 {
+		where = fmt.Sprintf(`line %d, column %d`, e.Line, e.Column)
+	} else {
+		where = fmt.Sprintf(`column %d`, e.Column)
+	}
+
+	if e.Err != nil {
+		return fmt.Sprintf(`%s at %s`, e.Err, where)
+	}
+
+	var sb strings.Builder
+	if e.Token == "" {
+		sb.WriteString(`unexpected end of input`)
+	} else {
+		fmt.Fprintf(&sb, `unexpected %q`, e.Token)
+	}
+	sb.WriteString(` at `)
+	sb.WriteString(where)
+
+	if l := len(e.Expected); l > 0 {
+		sb.WriteString(`, expected `)
+		for i, s := range e.Expected {
+			switch {
+			case i == 0:
+			case i == l-1 && l == 2:
+				sb.WriteString(` or `)
+			case i == l-1:
+				sb.WriteString(`, or `)
+			default:
+				sb.WriteString(`, `)
+			}
+			sb.WriteString(s)
+		}
+	}
+	return sb.String()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
@@ -8,4 +8,7 @@ type Position struct {
 // Expr is an interface to group AST nodes that are expressions
 type Interface interface {
 	expression()
+
+	// String returns the expression in SCIM filter syntax. See Format
+	String() string
 }
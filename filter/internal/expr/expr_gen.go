@@ -2,6 +2,7 @@ package expr
 
 type PresenceExpr interface {
 	Interface
+	isPresenceExpr()
 	Attr() Interface
 	Operator() string
 }
@@ -13,6 +14,12 @@ type presenceExpr struct {
 
 func (*presenceExpr) expression() {}
 
+func (*presenceExpr) isPresenceExpr() {}
+
+func (e *presenceExpr) String() string {
+	return Format(e)
+}
+
 func NewPresenceExpr(attr Interface, operator string) PresenceExpr {
 	return &presenceExpr{
 		attr:     attr,
@@ -30,6 +37,7 @@ func (e *presenceExpr) Operator() string {
 
 type CompareExpr interface {
 	Interface
+	isCompareExpr()
 	LHE() Interface
 	Operator() string
 	RHE() Interface
@@ -43,6 +51,12 @@ type compareExpr struct {
 
 func (*compareExpr) expression() {}
 
+func (*compareExpr) isCompareExpr() {}
+
+func (e *compareExpr) String() string {
+	return Format(e)
+}
+
 func NewCompareExpr(lhe Interface, operator string, rhe Interface) CompareExpr {
 	return &compareExpr{
 		lhe:      lhe,
@@ -65,6 +79,7 @@ func (e *compareExpr) RHE() Interface {
 
 type RegexExpr interface {
 	Interface
+	isRegexExpr()
 	LHE() Interface
 	Operator() string
 	Value() interface{}
@@ -78,6 +93,12 @@ type regexExpr struct {
 
 func (*regexExpr) expression() {}
 
+func (*regexExpr) isRegexExpr() {}
+
+func (e *regexExpr) String() string {
+	return Format(e)
+}
+
 func NewRegexExpr(lhe Interface, operator string, value interface{}) RegexExpr {
 	return &regexExpr{
 		lhe:      lhe,
@@ -100,6 +121,7 @@ func (e *regexExpr) Value() interface{} {
 
 type ParenExpr interface {
 	Interface
+	isParenExpr()
 	Operator() string
 	SubExpr() Interface
 }
@@ -111,6 +133,12 @@ type parenExpr struct {
 
 func (*parenExpr) expression() {}
 
+func (*parenExpr) isParenExpr() {}
+
+func (e *parenExpr) String() string {
+	return Format(e)
+}
+
 func NewParenExpr(operator string, subExpr Interface) ParenExpr {
 	return &parenExpr{
 		operator: operator,
@@ -128,6 +156,7 @@ func (e *parenExpr) SubExpr() Interface {
 
 type LogExpr interface {
 	Interface
+	isLogExpr()
 	LHE() Interface
 	Operator() string
 	RHS() Interface
@@ -141,6 +170,12 @@ type logExpr struct {
 
 func (*logExpr) expression() {}
 
+func (*logExpr) isLogExpr() {}
+
+func (e *logExpr) String() string {
+	return Format(e)
+}
+
 func NewLogExpr(lhe Interface, operator string, rhS Interface) LogExpr {
 	return &logExpr{
 		lhe:      lhe,
@@ -163,6 +198,7 @@ func (e *logExpr) RHS() Interface {
 
 type ValuePath interface {
 	Interface
+	isValuePath()
 	ParentAttr() Interface
 	SubAttr() Interface
 	SubExpr() Interface
@@ -176,6 +212,12 @@ type valuePath struct {
 
 func (*valuePath) expression() {}
 
+func (*valuePath) isValuePath() {}
+
+func (e *valuePath) String() string {
+	return Format(e)
+}
+
 func NewValuePath(parentAttr Interface, subAttr Interface, subExpr Interface) ValuePath {
 	return &valuePath{
 		parentAttr: parentAttr,
@@ -198,6 +240,7 @@ func (e *valuePath) SubExpr() Interface {
 
 type NumberExpr interface {
 	Interface
+	isNumberExpr()
 	Lit() int
 }
 
@@ -207,6 +250,12 @@ type numberExpr struct {
 
 func (*numberExpr) expression() {}
 
+func (*numberExpr) isNumberExpr() {}
+
+func (e *numberExpr) String() string {
+	return Format(e)
+}
+
 func NewNumberExpr(lit int) NumberExpr {
 	return &numberExpr{
 		lit: lit,
@@ -217,21 +266,56 @@ func (e *numberExpr) Lit() int {
 	return e.lit
 }
 
+type DecimalExpr interface {
+	Interface
+	isDecimalExpr()
+	Lit() float64
+}
+
+type decimalExpr struct {
+	lit float64
+}
+
+func (*decimalExpr) expression() {}
+
+func (*decimalExpr) isDecimalExpr() {}
+
+func (e *decimalExpr) String() string {
+	return Format(e)
+}
+
+func NewDecimalExpr(lit float64) DecimalExpr {
+	return &decimalExpr{
+		lit: lit,
+	}
+}
+
+func (e *decimalExpr) Lit() float64 {
+	return e.lit
+}
+
 type IdentifierExpr interface {
 	Interface
+	isIdentifierExpr()
 	Lit() string
+	SchemaURI() string
+	Attribute() string
+	SubAttribute() string
 }
 
 type identifierExpr struct {
-	lit string
+	lit          string
+	schemaURI    string
+	attribute    string
+	subAttribute string
 }
 
 func (*identifierExpr) expression() {}
 
-func NewIdentifierExpr(lit string) IdentifierExpr {
-	return &identifierExpr{
-		lit: lit,
-	}
+func (*identifierExpr) isIdentifierExpr() {}
+
+func (e *identifierExpr) String() string {
+	return Format(e)
 }
 
 func (e *identifierExpr) Lit() string {
@@ -240,6 +324,7 @@ func (e *identifierExpr) Lit() string {
 
 type AttrValueExpr interface {
 	Interface
+	isAttrValueExpr()
 	Lit() string
 }
 
@@ -249,6 +334,12 @@ type attrValueExpr struct {
 
 func (*attrValueExpr) expression() {}
 
+func (*attrValueExpr) isAttrValueExpr() {}
+
+func (e *attrValueExpr) String() string {
+	return Format(e)
+}
+
 func NewAttrValueExpr(lit string) AttrValueExpr {
 	return &attrValueExpr{
 		lit: lit,
@@ -261,6 +352,7 @@ func (e *attrValueExpr) Lit() string {
 
 type BoolExpr interface {
 	Interface
+	isBoolExpr()
 	Lit() bool
 }
 
@@ -270,6 +362,12 @@ type boolExpr struct {
 
 func (*boolExpr) expression() {}
 
+func (*boolExpr) isBoolExpr() {}
+
+func (e *boolExpr) String() string {
+	return Format(e)
+}
+
 func NewBoolExpr(lit bool) BoolExpr {
 	return &boolExpr{
 		lit: lit,
@@ -0,0 +1,116 @@
+package expr
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Format converts the expression back into the SCIM filter syntax.
+//
+// The output is canonical: operators are written in lower case,
+// tokens are separated by a single space, string literals are always
+// quoted and escaped as JSON strings, and parentheses are inserted
+// where the structure of the expression requires them.
+func Format(e Interface) string {
+	var buf bytes.Buffer
+	format(&buf, e)
+	return buf.String()
+}
+
+func format(buf *bytes.Buffer, e interface{}) {
+	switch e := e.(type) {
+	case nil:
+	case string:
+		formatString(buf, e)
+	case PresenceExpr:
+		format(buf, e.Attr())
+		buf.WriteByte(' ')
+		buf.WriteString(strings.ToLower(e.Operator()))
+	case CompareExpr:
+		format(buf, e.LHE())
+		buf.WriteByte(' ')
+		buf.WriteString(strings.ToLower(e.Operator()))
+		buf.WriteByte(' ')
+		format(buf, e.RHE())
+	case RegexExpr:
+		format(buf, e.LHE())
+		buf.WriteByte(' ')
+		buf.WriteString(strings.ToLower(e.Operator()))
+		buf.WriteByte(' ')
+		format(buf, e.Value())
+	case LogExpr:
+		op := strings.ToLower(e.Operator())
+		formatOperand(buf, op, e.LHE(), false)
+		buf.WriteByte(' ')
+		buf.WriteString(op)
+		buf.WriteByte(' ')
+		formatOperand(buf, op, e.RHS(), true)
+	case ParenExpr:
+		if op := e.Operator(); op != "" {
+			buf.WriteString(strings.ToLower(op))
+			buf.WriteByte(' ')
+		}
+		buf.WriteByte('(')
+		format(buf, e.SubExpr())
+		buf.WriteByte(')')
+	case ValuePath:
+		format(buf, e.ParentAttr())
+		if sub := e.SubExpr(); sub != nil {
+			buf.WriteByte('[')
+			format(buf, sub)
+			buf.WriteByte(']')
+		}
+		if attr := e.SubAttr(); attr != nil {
+			buf.WriteByte('.')
+			format(buf, attr)
+		}
+	case AttrValueExpr:
+		formatString(buf, e.Lit())
+	case IdentifierExpr:
+		buf.WriteString(e.Lit())
+	case NumberExpr:
+		buf.WriteString(strconv.Itoa(e.Lit()))
+	case DecimalExpr:
+		formatDecimal(buf, e.Lit())
+	case BoolExpr:
+		buf.WriteString(strconv.FormatBool(e.Lit()))
+	default:
+		fmt.Fprintf(buf, `%v`, e)
+	}
+}
+
+// formatOperand formats an operand of a logical expression. Nested
+// logical expressions are enclosed in parentheses unless the result
+// is unambiguous without them, that is, when the operand is the left
+// hand side of an expression with the same operator
+func formatOperand(buf *bytes.Buffer, op string, e Interface, rhs bool) {
+	if l, ok := e.(LogExpr); ok && (rhs || strings.ToLower(l.Operator()) != op) {
+		buf.WriteByte('(')
+		format(buf, e)
+		buf.WriteByte(')')
+		return
+	}
+	format(buf, e)
+}
+
+// formatDecimal formats the number so that it is always parsed back
+// as a decimal, even if it does not have a fraction
+func formatDecimal(buf *bytes.Buffer, f float64) {
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, `.e`) {
+		s += `.0`
+	}
+	buf.WriteString(s)
+}
+
+func formatString(buf *bytes.Buffer, s string) {
+	var tmp bytes.Buffer
+	enc := json.NewEncoder(&tmp)
+	enc.SetEscapeHTML(false)
+	// encoding a string never fails
+	_ = enc.Encode(s)
+	buf.Write(bytes.TrimSuffix(tmp.Bytes(), []byte{'\n'}))
+}
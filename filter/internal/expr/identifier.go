@@ -0,0 +1,129 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NewIdentifierExpr creates an identifier expression for the attribute
+// path lit. The schema URI, the attribute name, and the sub-attribute
+// are split from lit once, when the expression is created.
+func NewIdentifierExpr(lit string) IdentifierExpr {
+	uri, attr, sub := SplitAttrPath(lit)
+	return &identifierExpr{
+		lit:          lit,
+		schemaURI:    uri,
+		attribute:    attr,
+		subAttribute: sub,
+	}
+}
+
+// SchemaURI returns the schema URI portion of a fully qualified
+// attribute path such as `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.value`.
+// If the attribute path is not qualified, an empty string is returned.
+func (e *identifierExpr) SchemaURI() string {
+	return e.schemaURI
+}
+
+// Attribute returns the attribute name portion of the attribute path,
+// without the schema URI and the sub-attribute.
+func (e *identifierExpr) Attribute() string {
+	return e.attribute
+}
+
+// SubAttribute returns the sub-attribute portion of the attribute path.
+// If the attribute path does not contain a sub-attribute, an empty
+// string is returned.
+func (e *identifierExpr) SubAttribute() string {
+	return e.subAttribute
+}
+
+// SplitAttrPath splits an attribute path (RFC7644 Section 3.10) into
+// the schema URI, the attribute name, and the sub-attribute.
+//
+//	attrPath = [URI ":"] ATTRNAME *1subAttr
+func SplitAttrPath(s string) (string, string, string) {
+	var uri string
+	if len(s) > 4 && strings.EqualFold(s[:4], `urn:`) {
+		// the attribute name comes after the last colon. ATTRNAME and
+		// subAttr may not contain colons, but the URI may contain dots
+		if i := strings.LastIndexByte(s, ':'); i > 0 {
+			uri = s[:i]
+			s = s[i+1:]
+		}
+	}
+
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		return uri, s[:i], s[i+1:]
+	}
+	return uri, s, ""
+}
+
+// ValidateAttrPath checks that s is a well-formed attribute path.
+// Colons may only appear as part of a schema URI, which must be
+// followed by an attribute name. The attribute name and the
+// sub-attribute must conform to ATTRNAME in RFC7643 Section 2.1.
+//
+//	ATTRNAME = ALPHA *(nameChar)
+//	nameChar = "-" / "_" / DIGIT / ALPHA
+func ValidateAttrPath(s string) error {
+	uri, attr, sub := SplitAttrPath(s)
+	switch {
+	case attr == "":
+		if uri != "" {
+			return fmt.Errorf(`attribute path %q is missing an attribute name after the schema URI`, s)
+		}
+		return fmt.Errorf(`attribute path %q is missing an attribute name`, s)
+	case strings.ContainsRune(attr, ':') || strings.ContainsRune(sub, ':'):
+		return fmt.Errorf(`attribute path %q contains a colon, but is not prefixed by a schema URI`, s)
+	case strings.HasSuffix(s, "."):
+		return fmt.Errorf(`attribute path %q is missing a sub-attribute name`, s)
+	}
+
+	for _, r := range uri {
+		if !isURIChar(r) {
+			return fmt.Errorf(`schema URI in %q contains an invalid character %q`, s, r)
+		}
+	}
+
+	names := []string{attr}
+	if sub != "" {
+		names = append(names, strings.Split(sub, ".")...)
+	}
+	for _, name := range names {
+		if !isAttrName(name) {
+			return fmt.Errorf(`attribute name %q in %q must start with a letter, followed by letters, digits, "-" or "_"`, name, s)
+		}
+	}
+	return nil
+}
+
+// isAttrName reports whether s matches ATTRNAME
+func isAttrName(s string) bool {
+	if s == "" || !isAlpha(rune(s[0])) {
+		return false
+	}
+	for _, r := range s[1:] {
+		if !isAlpha(r) && !isDigit(r) && r != '-' && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// isURIChar reports whether r may appear in the schema URI of an
+// attribute path. This is the set of characters that the scanner
+// accepts in attribute paths, so that paths containing other
+// characters cannot be mistaken for something else when they are
+// formatted and parsed again
+func isURIChar(r rune) bool {
+	return isAlpha(r) || isDigit(r) || strings.ContainsRune(`-_.:/`, r)
+}
+
+func isAlpha(r rune) bool {
+	return ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z')
+}
+
+func isDigit(r rune) bool {
+	return '0' <= r && r <= '9'
+}
@@ -0,0 +1,88 @@
+package expr
+
+import "fmt"
+
+// Kinds of limits that can be imposed on the parser
+const (
+	LimitLength = `length`
+	LimitDepth  = `depth`
+	LimitNodes  = `nodes`
+)
+
+// Limits describes the limits imposed on the filters that the parser
+// accepts. Zero values mean that there is no limit.
+type Limits struct {
+	// MaxLength is the maximum length of the filter in bytes
+	MaxLength int
+	// MaxDepth is the maximum nesting depth of parentheses and brackets
+	MaxDepth int
+	// MaxNodes is the maximum number of expressions: comparisons,
+	// logical operators, groupings and value paths
+	MaxNodes int
+}
+
+// LimitError is reported when the filter exceeds one of the limits
+// imposed on the parser. It is returned wrapped in a *ParseError, which
+// points to the location where the limit was exceeded.
+type LimitError struct {
+	// Limit is the kind of limit that was exceeded (e.g. `depth`)
+	Limit string
+	// Max is the value of the limit
+	Max int
+}
+
+func (e *LimitError) Error() string {
+	switch e.Limit {
+	case LimitLength:
+		return fmt.Sprintf(`filter is longer than the maximum of %d bytes`, e.Max)
+	case LimitDepth:
+		return fmt.Sprintf(`filter is nested deeper than the maximum depth of %d`, e.Max)
+	case LimitNodes:
+		return fmt.Sprintf(`filter contains more than the maximum of %d expressions`, e.Max)
+	default:
+		return fmt.Sprintf(`filter exceeds the maximum %s of %d`, e.Limit, e.Max)
+	}
+}
+
+// Counter keeps track of the tokens produced by the scanner, and
+// reports when the limits have been exceeded
+type Counter struct {
+	limits Limits
+	depth  int
+	nodes  int
+}
+
+func NewCounter(limits Limits) *Counter {
+	return &Counter{limits: limits}
+}
+
+// CheckLength checks the length of the source before it is scanned
+func (c *Counter) CheckLength(src string) error {
+	if max := c.limits.MaxLength; max > 0 && len(src) > max {
+		return &LimitError{Limit: LimitLength, Max: max}
+	}
+	return nil
+}
+
+// Open records an opening parenthesis or bracket
+func (c *Counter) Open() error {
+	c.depth++
+	if max := c.limits.MaxDepth; max > 0 && c.depth > max {
+		return &LimitError{Limit: LimitDepth, Max: max}
+	}
+	return nil
+}
+
+// Close records a closing parenthesis or bracket
+func (c *Counter) Close() {
+	c.depth--
+}
+
+// Node records a token that results in an expression
+func (c *Counter) Node() error {
+	c.nodes++
+	if max := c.limits.MaxNodes; max > 0 && c.nodes > max {
+		return &LimitError{Limit: LimitNodes, Max: max}
+	}
+	return nil
+}
@@ -34,9 +34,10 @@ var keywords = map[string]int{
 }
 
 var families = map[string]int{
-	token.Value:  tVALUE,
-	token.Number: tNUMBER,
-	token.Ident:  tIDENT,
+	token.Value:   tVALUE,
+	token.Number:  tNUMBER,
+	token.Decimal: tDECIMAL,
+	token.Ident:   tIDENT,
 }
 
 var miscellaneous = map[string]int{
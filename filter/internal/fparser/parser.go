@@ -5,6 +5,9 @@ import __yyfmt__ "fmt"
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/cybozu-go/scim/filter/internal/expr"
 	"github.com/cybozu-go/scim/filter/internal/scanner"
@@ -29,25 +32,26 @@ const tFALSE = 57348
 const tNULL = 57349
 const tVALUE = 57350
 const tNUMBER = 57351
-const tPR = 57352
-const tEQ = 57353
-const tNE = 57354
-const tCO = 57355
-const tSW = 57356
-const tEW = 57357
-const tGT = 57358
-const tGE = 57359
-const tLT = 57360
-const tLE = 57361
-const tAND = 57362
-const tOR = 57363
-const tNOT = 57364
-const tLPAREN = 57365
-const tRPAREN = 57366
-const tLBOXP = 57367
-const tRBOXP = 57368
-const tSP = 57369
-const tDOT = 57370
+const tDECIMAL = 57352
+const tPR = 57353
+const tEQ = 57354
+const tNE = 57355
+const tCO = 57356
+const tSW = 57357
+const tEW = 57358
+const tGT = 57359
+const tGE = 57360
+const tLT = 57361
+const tLE = 57362
+const tAND = 57363
+const tOR = 57364
+const tNOT = 57365
+const tLPAREN = 57366
+const tRPAREN = 57367
+const tLBOXP = 57368
+const tRBOXP = 57369
+const tSP = 57370
+const tDOT = 57371
 
 var yyToknames = [...]string{
 	"$end",
@@ -59,6 +63,7 @@ var yyToknames = [...]string{
 	"tNULL",
 	"tVALUE",
 	"tNUMBER",
+	"tDECIMAL",
 	"tPR",
 	"tEQ",
 	"tNE",
@@ -87,40 +92,72 @@ const yyErrCode = 2
 const yyInitialStackSize = 16
 
 type lexer struct {
-	s         scanner.Scanner
-	recentLit interface{}
-	recentPos expr.Position
-	expr      expr.Interface
-	err       chan error
+	s       scanner.Scanner
+	tokens  []xtoken
+	expr    expr.Interface
+	err     chan error
+	counter *expr.Counter
 }
 
 func (l *lexer) Lex(lval *yySymType) int {
 	tok, lit, pos, err := l.s.Scan()
 	if err != nil {
-		l.emitError(err)
+		l.emitError(&expr.ParseError{Line: pos.Line, Column: pos.Column, Err: err})
 		return -1
 	}
 	if tok == tEOF {
-		return 0
+		tok = 0
 	}
 	lval.tok = xtoken{tok: tok, lit: lit, pos: pos}
-	l.recentLit = lit
-	l.recentPos = pos
+	l.tokens = append(l.tokens, lval.tok)
+
+	if err := l.count(tok); err != nil {
+		l.fail(lval.tok, err)
+		return -1
+	}
 	return tok
 }
 
-func (l *lexer) makeError(e interface{}) error {
-	switch e := e.(type) {
-	case error:
-		return fmt.Errorf(`parse error: line %d, column %d: %q: %w`, l.recentPos.Line, l.recentPos.Column, l.recentLit, e)
-	default:
-		return fmt.Errorf(`parse error: line %d, column %d: %q: %s`, l.recentPos.Line, l.recentPos.Column, l.recentLit, e)
+// count checks the token against the limits imposed on the parser,
+// so that the parser stops before building an expression that is too
+// large
+func (l *lexer) count(tok int) error {
+	switch tok {
+	case tLPAREN, tLBOXP:
+		if err := l.counter.Open(); err != nil {
+			return err
+		}
+		// groupings and value paths
+		return l.counter.Node()
+	case tRPAREN, tRBOXP:
+		l.counter.Close()
+	case tPR, tEQ, tNE, tCO, tSW, tEW, tGT, tGE, tLT, tLE, tAND, tOR:
+		return l.counter.Node()
 	}
+	return nil
 }
 
 // implements yylexer, so it must stay
-func (l *lexer) Error(e string) {
-	l.emitError(l.makeError(e))
+func (l *lexer) Error(string) {
+	if len(l.err) > 0 {
+		// the scanner has already reported a more specific error
+		return
+	}
+
+	perr := &expr.ParseError{}
+	if n := len(l.tokens); n > 0 {
+		last := l.tokens[n-1]
+		perr.Line = last.pos.Line
+		perr.Column = last.pos.Column
+		perr.Token = last.text()
+		perr.Expected = expectedTokens(l.tokens[:n-1])
+	}
+	l.emitError(perr)
+}
+
+// fail reports an error that was detected while processing the token t
+func (l *lexer) fail(t xtoken, err error) {
+	l.emitError(&expr.ParseError{Line: t.pos.Line, Column: t.pos.Column, Token: t.text(), Err: err})
 }
 
 func (l *lexer) emitError(err error) {
@@ -130,9 +167,122 @@ func (l *lexer) emitError(err error) {
 	}
 }
 
-func Parse(src string) (expr.Interface, error) {
+// text returns the textual representation of the token, to be
+// used in error messages
+func (t xtoken) text() string {
+	switch {
+	case t.tok == 0:
+		return ""
+	case t.tok == tVALUE:
+		return strconv.Quote(t.lit.(string))
+	default:
+		return fmt.Sprint(t.lit)
+	}
+}
+
+// tokenDescriptions lists the tokens along with their human readable
+// descriptions, which are used in error messages
+var tokenDescriptions = []struct {
+	tok  int
+	desc string
+}{
+	{tIDENT, `attribute path`},
+	{tVALUE, `string`},
+	{tNUMBER, `number`},
+	{tDECIMAL, `number`},
+	{tTRUE, `"true"`},
+	{tFALSE, `"false"`},
+	{tNULL, `"null"`},
+	{tPR, `"pr"`},
+	{tEQ, `"eq"`},
+	{tNE, `"ne"`},
+	{tCO, `"co"`},
+	{tSW, `"sw"`},
+	{tEW, `"ew"`},
+	{tGT, `"gt"`},
+	{tGE, `"ge"`},
+	{tLT, `"lt"`},
+	{tLE, `"le"`},
+	{tAND, `"and"`},
+	{tOR, `"or"`},
+	{tNOT, `"not"`},
+	{tLPAREN, `"("`},
+	{tRPAREN, `")"`},
+	{tLBOXP, `"["`},
+	{tRBOXP, `"]"`},
+	{tDOT, `"."`},
+	{0, `end of input`},
+}
+
+// replayLexer feeds a fixed list of tokens to the parser, followed
+// by the end of input
+type replayLexer struct {
+	tokens []xtoken
+	count  int
+}
+
+func (l *replayLexer) Lex(lval *yySymType) int {
+	l.count++
+	if l.count > len(l.tokens) {
+		return 0
+	}
+	lval.tok = l.tokens[l.count-1]
+	return lval.tok.tok
+}
+
+func (l *replayLexer) Error(string) {}
+
+// expectedTokens returns the descriptions of the tokens that may
+// follow the given list of tokens. Each candidate token is appended
+// to the list and fed to the parser: the candidate is accepted if
+// the parser proceeds past it without an error.
+func expectedTokens(prefix []xtoken) []string {
+	var list []string
+	seen := make(map[string]struct{})
+	for _, candidate := range tokenDescriptions {
+		if _, ok := seen[candidate.desc]; ok {
+			continue
+		}
+
+		// the parser actions expect literals of the correct type
+		var lit interface{}
+		switch candidate.tok {
+		case tNUMBER:
+			lit = 0
+		case tDECIMAL:
+			lit = float64(0)
+		case tIDENT, tVALUE:
+			lit = `x`
+		default:
+			lit = candidate.desc
+		}
+
+		tokens := make([]xtoken, len(prefix), len(prefix)+1)
+		copy(tokens, prefix)
+		tokens = append(tokens, xtoken{tok: candidate.tok, lit: lit})
+
+		rl := &replayLexer{tokens: tokens}
+		if yyParse(rl) != 0 && rl.count <= len(tokens) {
+			continue
+		}
+		seen[candidate.desc] = struct{}{}
+		list = append(list, candidate.desc)
+	}
+	return list
+}
+
+func Parse(src string, limits expr.Limits) (expr.Interface, error) {
+	counter := expr.NewCounter(limits)
+	if err := counter.CheckLength(src); err != nil {
+		// point to the first character beyond the limit
+		prefix := src[:limits.MaxLength]
+		line := strings.Count(prefix, "\n") + 1
+		column := utf8.RuneCountInString(prefix[strings.LastIndexByte(prefix, '\n')+1:]) + 1
+		return nil, &expr.ParseError{Line: line, Column: column, Err: err}
+	}
+
 	s := scanner.New(src, Dialect{})
-	l := lexer{s: s, err: make(chan error, 1)}
+	l := lexer{s: s, err: make(chan error, 1), counter: counter}
 	if yyParse(&l) != 0 {
 		return nil, <-l.err
 	}
@@ -147,55 +297,55 @@ var yyExca = [...]int8{
 
 const yyPrivate = 57344
 
-const yyLast = 54
+const yyLast = 58
 
 var yyAct = [...]int8{
 	3, 9, 10, 11, 12, 13, 14, 15, 16, 17,
-	18, 43, 7, 8, 6, 6, 19, 21, 41, 7,
-	8, 7, 8, 42, 24, 39, 2, 7, 8, 8,
-	33, 20, 5, 4, 22, 23, 30, 32, 31, 1,
-	34, 35, 36, 37, 44, 0, 38, 0, 40, 26,
-	27, 28, 29, 25,
+	18, 44, 7, 8, 6, 6, 19, 21, 42, 7,
+	8, 7, 8, 43, 8, 40, 34, 2, 7, 8,
+	33, 32, 20, 5, 4, 22, 23, 1, 24, 27,
+	28, 29, 30, 25, 26, 45, 0, 39, 0, 41,
+	31, 0, 0, 0, 35, 36, 37, 38,
 }
 
 var yyPact = [...]int16{
-	10, -1000, 7, -9, 10, -6, -1000, 10, 10, -1000,
-	44, 44, 30, 29, 22, 44, 44, 44, 44, 10,
-	1, 10, 8, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
-	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -8, -1000,
-	-1, -17, -1000, 11, -1000,
+	10, -1000, 7, -10, 10, -7, -1000, 10, 10, -1000,
+	34, 34, 23, 22, 18, 34, 34, 34, 34, 10,
+	0, 10, 2, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
+	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -9,
+	-1000, -2, -18, -1000, 11, -1000,
 }
 
 var yyPgo = [...]int8{
-	0, 39, 26, 0, 24,
+	0, 37, 27, 0, 38,
 }
 
 var yyR1 = [...]int8{
 	0, 1, 2, 2, 2, 2, 2, 2, 2, 2,
 	2, 2, 2, 2, 2, 2, 2, 2, 3, 4,
-	4, 4, 4, 4,
+	4, 4, 4, 4, 4,
 }
 
 var yyR2 = [...]int8{
 	0, 1, 2, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 4, 6, 4, 1, 1,
-	1, 1, 1, 1,
+	1, 1, 1, 1, 1,
 }
 
 var yyChk = [...]int16{
-	-1000, -1, -2, -3, 23, 22, 4, 20, 21, 10,
-	11, 12, 13, 14, 15, 16, 17, 18, 19, 25,
-	-2, 23, -2, -2, -4, 9, 5, 6, 7, 8,
-	-4, 8, 8, 8, -4, -4, -4, -4, -2, 24,
-	-2, 26, 24, 28, -3,
+	-1000, -1, -2, -3, 24, 23, 4, 21, 22, 11,
+	12, 13, 14, 15, 16, 17, 18, 19, 20, 26,
+	-2, 24, -2, -2, -4, 9, 10, 5, 6, 7,
+	8, -4, 8, 8, 8, -4, -4, -4, -4, -2,
+	25, -2, 27, 25, 29, -3,
 }
 
 var yyDef = [...]int8{
 	0, -2, 1, 0, 0, 0, 18, 0, 0, 2,
 	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
 	0, 0, 12, 13, 3, 19, 20, 21, 22, 23,
-	4, 5, 6, 7, 8, 9, 10, 11, 0, 14,
-	0, 17, 15, 0, 16,
+	24, 4, 5, 6, 7, 8, 9, 10, 11, 0,
+	14, 0, 17, 15, 0, 16,
 }
 
 var yyTok1 = [...]int8{
@@ -205,7 +355,7 @@ var yyTok1 = [...]int8{
 var yyTok2 = [...]int8{
 	2, 3, 4, 5, 6, 7, 8, 9, 10, 11,
 	12, 13, 14, 15, 16, 17, 18, 19, 20, 21,
-	22, 23, 24, 25, 26, 27, 28,
+	22, 23, 24, 25, 26, 27, 28, 29,
 }
 
 var yyTok3 = [...]int8{
@@ -638,6 +788,12 @@ yydefault:
 	case 18:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
+			if err := expr.ValidateAttrPath(yyDollar[1].tok.lit.(string)); err != nil {
+				if l, ok := yylex.(*lexer); ok {
+					l.fail(yyDollar[1].tok, err)
+				}
+				return 1
+			}
 			yyVAL.expr = expr.NewIdentifierExpr(yyDollar[1].tok.lit.(string))
 		}
 	case 19:
@@ -648,19 +804,24 @@ yydefault:
 	case 20:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
-			yyVAL.expr = expr.NewBoolExpr(true)
+			yyVAL.expr = expr.NewDecimalExpr(yyDollar[1].tok.lit.(float64))
 		}
 	case 21:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
-			yyVAL.expr = expr.NewBoolExpr(false)
+			yyVAL.expr = expr.NewBoolExpr(true)
 		}
 	case 22:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
-			yyVAL.expr = expr.NewIdentifierExpr(yyDollar[1].tok.lit.(string))
+			yyVAL.expr = expr.NewBoolExpr(false)
 		}
 	case 23:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.expr = expr.NewIdentifierExpr(yyDollar[1].tok.lit.(string))
+		}
+	case 24:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
 			yyVAL.expr = expr.NewAttrValueExpr(yyDollar[1].tok.lit.(string))
@@ -5,6 +5,9 @@ import __yyfmt__ "fmt"
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/cybozu-go/scim/filter/internal/expr"
 	"github.com/cybozu-go/scim/filter/internal/scanner"
@@ -29,25 +32,26 @@ const tFALSE = 57348
 const tNULL = 57349
 const tVALUE = 57350
 const tNUMBER = 57351
-const tPR = 57352
-const tEQ = 57353
-const tNE = 57354
-const tCO = 57355
-const tSW = 57356
-const tEW = 57357
-const tGT = 57358
-const tGE = 57359
-const tLT = 57360
-const tLE = 57361
-const tAND = 57362
-const tOR = 57363
-const tNOT = 57364
-const tLPAREN = 57365
-const tRPAREN = 57366
-const tLBOXP = 57367
-const tRBOXP = 57368
-const tSP = 57369
-const tDOT = 57370
+const tDECIMAL = 57352
+const tPR = 57353
+const tEQ = 57354
+const tNE = 57355
+const tCO = 57356
+const tSW = 57357
+const tEW = 57358
+const tGT = 57359
+const tGE = 57360
+const tLT = 57361
+const tLE = 57362
+const tAND = 57363
+const tOR = 57364
+const tNOT = 57365
+const tLPAREN = 57366
+const tRPAREN = 57367
+const tLBOXP = 57368
+const tRBOXP = 57369
+const tSP = 57370
+const tDOT = 57371
 
 var yyToknames = [...]string{
 	"$end",
@@ -59,6 +63,7 @@ var yyToknames = [...]string{
 	"tNULL",
 	"tVALUE",
 	"tNUMBER",
+	"tDECIMAL",
 	"tPR",
 	"tEQ",
 	"tNE",
@@ -87,40 +92,72 @@ const yyErrCode = 2
 const yyInitialStackSize = 16
 
 type lexer struct {
-	s         scanner.Scanner
-	recentLit interface{}
-	recentPos expr.Position
-	expr      expr.Interface
-	err       chan error
+	s       scanner.Scanner
+	tokens  []xtoken
+	expr    expr.Interface
+	err     chan error
+	counter *expr.Counter
 }
 
 func (l *lexer) Lex(lval *yySymType) int {
 	tok, lit, pos, err := l.s.Scan()
 	if err != nil {
-		l.emitError(err)
+		l.emitError(&expr.ParseError{Line: pos.Line, Column: pos.Column, Err: err})
 		return -1
 	}
 	if tok == tEOF {
-		return 0
+		tok = 0
 	}
 	lval.tok = xtoken{tok: tok, lit: lit, pos: pos}
-	l.recentLit = lit
-	l.recentPos = pos
+	l.tokens = append(l.tokens, lval.tok)
+
+	if err := l.count(tok); err != nil {
+		l.fail(lval.tok, err)
+		return -1
+	}
 	return tok
 }
 
-func (l *lexer) makeError(e interface{}) error {
-	switch e := e.(type) {
-	case error:
-		return fmt.Errorf(`parse error: line %d, column %d: %q: %w`, l.recentPos.Line, l.recentPos.Column, l.recentLit, e)
-	default:
-		return fmt.Errorf(`parse error: line %d, column %d: %q: %s`, l.recentPos.Line, l.recentPos.Column, l.recentLit, e)
+// count checks the token against the limits imposed on the parser,
+// so that the parser stops before building an expression that is too
+// large
+func (l *lexer) count(tok int) error {
+	switch tok {
+	case tLPAREN, tLBOXP:
+		if err := l.counter.Open(); err != nil {
+			return err
+		}
+		// groupings and value paths
+		return l.counter.Node()
+	case tRPAREN, tRBOXP:
+		l.counter.Close()
+	case tPR, tEQ, tNE, tCO, tSW, tEW, tGT, tGE, tLT, tLE, tAND, tOR:
+		return l.counter.Node()
 	}
+	return nil
 }
 
 // implements yylexer, so it must stay
-func (l *lexer) Error(e string) {
-	l.emitError(l.makeError(e))
+func (l *lexer) Error(string) {
+	if len(l.err) > 0 {
+		// the scanner has already reported a more specific error
+		return
+	}
+
+	perr := &expr.ParseError{}
+	if n := len(l.tokens); n > 0 {
+		last := l.tokens[n-1]
+		perr.Line = last.pos.Line
+		perr.Column = last.pos.Column
+		perr.Token = last.text()
+		perr.Expected = expectedTokens(l.tokens[:n-1])
+	}
+	l.emitError(perr)
+}
+
+// fail reports an error that was detected while processing the token t
+func (l *lexer) fail(t xtoken, err error) {
+	l.emitError(&expr.ParseError{Line: t.pos.Line, Column: t.pos.Column, Token: t.text(), Err: err})
 }
 
 func (l *lexer) emitError(err error) {
@@ -130,9 +167,122 @@ func (l *lexer) emitError(err error) {
 	}
 }
 
-func Parse(src string) (expr.Interface, error) {
+// text returns the textual representation of the token, to be
+// used in error messages
+func (t xtoken) text() string {
+	switch {
+	case t.tok == 0:
+		return ""
+	case t.tok == tVALUE:
+		return strconv.Quote(t.lit.(string))
+	default:
+		return fmt.Sprint(t.lit)
+	}
+}
+
+// tokenDescriptions lists the tokens along with their human readable
+// descriptions, which are used in error messages
+var tokenDescriptions = []struct {
+	tok  int
+	desc string
+}{
+	{tIDENT, `attribute path`},
+	{tVALUE, `string`},
+	{tNUMBER, `number`},
+	{tDECIMAL, `number`},
+	{tTRUE, `"true"`},
+	{tFALSE, `"false"`},
+	{tNULL, `"null"`},
+	{tPR, `"pr"`},
+	{tEQ, `"eq"`},
+	{tNE, `"ne"`},
+	{tCO, `"co"`},
+	{tSW, `"sw"`},
+	{tEW, `"ew"`},
+	{tGT, `"gt"`},
+	{tGE, `"ge"`},
+	{tLT, `"lt"`},
+	{tLE, `"le"`},
+	{tAND, `"and"`},
+	{tOR, `"or"`},
+	{tNOT, `"not"`},
+	{tLPAREN, `"("`},
+	{tRPAREN, `")"`},
+	{tLBOXP, `"["`},
+	{tRBOXP, `"]"`},
+	{tDOT, `"."`},
+	{0, `end of input`},
+}
+
+// replayLexer feeds a fixed list of tokens to the parser, followed
+// by the end of input
+type replayLexer struct {
+	tokens []xtoken
+	count  int
+}
+
+func (l *replayLexer) Lex(lval *yySymType) int {
+	l.count++
+	if l.count > len(l.tokens) {
+		return 0
+	}
+	lval.tok = l.tokens[l.count-1]
+	return lval.tok.tok
+}
+
+func (l *replayLexer) Error(string) {}
+
+// expectedTokens returns the descriptions of the tokens that may
+// follow the given list of tokens. Each candidate token is appended
+// to the list and fed to the parser: the candidate is accepted if
+// the parser proceeds past it without an error.
+func expectedTokens(prefix []xtoken) []string {
+	var list []string
+	seen := make(map[string]struct{})
+	for _, candidate := range tokenDescriptions {
+		if _, ok := seen[candidate.desc]; ok {
+			continue
+		}
+
+		// the parser actions expect literals of the correct type
+		var lit interface{}
+		switch candidate.tok {
+		case tNUMBER:
+			lit = 0
+		case tDECIMAL:
+			lit = float64(0)
+		case tIDENT, tVALUE:
+			lit = `x`
+		default:
+			lit = candidate.desc
+		}
+
+		tokens := make([]xtoken, len(prefix), len(prefix)+1)
+		copy(tokens, prefix)
+		tokens = append(tokens, xtoken{tok: candidate.tok, lit: lit})
+
+		rl := &replayLexer{tokens: tokens}
+		if yyParse(rl) != 0 && rl.count <= len(tokens) {
+			continue
+		}
+		seen[candidate.desc] = struct{}{}
+		list = append(list, candidate.desc)
+	}
+	return list
+}
+
+func Parse(src string, limits expr.Limits) (expr.Interface, error) {
+	counter := expr.NewCounter(limits)
+	if err := counter.CheckLength(src); err != nil {
+		// point to the first character beyond the limit
+		prefix := src[:limits.MaxLength]
+		line := strings.Count(prefix, "\n") + 1
+		column := utf8.RuneCountInString(prefix[strings.LastIndexByte(prefix, '\n')+1:]) + 1
+		return nil, &expr.ParseError{Line: line, Column: column, Err: err}
+	}
+
 	s := scanner.New(src, Dialect{})
-	l := lexer{s: s, err: make(chan error, 1)}
+	l := lexer{s: s, err: make(chan error, 1), counter: counter}
 	if yyParse(&l) != 0 {
 		return nil, <-l.err
 	}
@@ -147,55 +297,56 @@ var yyExca = [...]int8{
 
 const yyPrivate = 57344
 
-const yyLast = 53
+const yyLast = 64
 
 var yyAct = [...]int8{
-	8, 3, 7, 5, 27, 26, 6, 11, 30, 13,
-	14, 14, 25, 39, 38, 12, 28, 29, 13, 14,
-	13, 14, 47, 37, 44, 4, 36, 4, 46, 45,
-	40, 41, 42, 43, 15, 16, 17, 18, 19, 20,
-	21, 22, 23, 24, 2, 10, 9, 32, 33, 34,
-	35, 31, 1,
+	5, 8, 3, 6, 7, 27, 13, 14, 11, 13,
+	14, 26, 12, 48, 25, 14, 13, 14, 28, 29,
+	45, 40, 39, 38, 30, 2, 4, 1, 0, 47,
+	0, 46, 15, 16, 17, 18, 19, 20, 21, 22,
+	23, 24, 37, 4, 0, 0, 41, 42, 43, 44,
+	33, 34, 35, 36, 31, 32, 0, 0, 0, 0,
+	0, 0, 10, 9,
 }
 
 var yyPact = [...]int16{
-	21, -1000, -1000, -22, -1000, 23, 21, -11, 24, 23,
-	-18, -1000, -24, 23, 23, -1000, 42, 42, 15, 6,
-	5, 42, 42, 42, 42, 0, 23, 21, -10, -1000,
+	22, -1000, -1000, -26, -1000, 39, 22, -15, 21, 39,
+	-13, -1000, -24, 39, 39, -1000, 45, 45, 15, 14,
+	13, 45, 45, 45, 45, -5, 39, 22, -7, -1000,
 	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
-	-1000, -1000, -1000, -1000, -1000, -2, -1000, -1000,
+	-1000, -1000, -1000, -1000, -1000, -1000, -12, -1000, -1000,
 }
 
 var yyPgo = [...]int8{
-	0, 52, 44, 2, 0, 8,
+	0, 27, 25, 4, 1, 24,
 }
 
 var yyR1 = [...]int8{
 	0, 1, 2, 2, 2, 2, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	4, 5, 5, 5, 5, 5,
+	4, 5, 5, 5, 5, 5, 5,
 }
 
 var yyR2 = [...]int8{
 	0, 1, 6, 4, 3, 1, 2, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 4,
-	1, 1, 1, 1, 1, 1,
+	1, 1, 1, 1, 1, 1, 1,
 }
 
 var yyChk = [...]int16{
-	-1000, -1, -2, -4, 4, 25, 28, -3, -4, 23,
-	22, -4, 26, 20, 21, 10, 11, 12, 13, 14,
-	15, 16, 17, 18, 19, -3, 23, 28, -3, -3,
-	-5, 9, 5, 6, 7, 8, -5, 8, 8, 8,
-	-5, -5, -5, -5, 24, -3, -4, 24,
+	-1000, -1, -2, -4, 4, 26, 29, -3, -4, 24,
+	23, -4, 27, 21, 22, 11, 12, 13, 14, 15,
+	16, 17, 18, 19, 20, -3, 24, 29, -3, -3,
+	-5, 9, 10, 5, 6, 7, 8, -5, 8, 8,
+	8, -5, -5, -5, -5, 25, -3, -4, 25,
 }
 
 var yyDef = [...]int8{
 	0, -2, 1, 5, 20, 0, 0, 0, 0, 0,
 	0, 4, 3, 0, 0, 6, 0, 0, 0, 0,
 	0, 0, 0, 0, 0, 0, 0, 0, 16, 17,
-	7, 21, 22, 23, 24, 25, 8, 9, 10, 11,
-	12, 13, 14, 15, 18, 0, 2, 19,
+	7, 21, 22, 23, 24, 25, 26, 8, 9, 10,
+	11, 12, 13, 14, 15, 18, 0, 2, 19,
 }
 
 var yyTok1 = [...]int8{
@@ -205,7 +356,7 @@ var yyTok1 = [...]int8{
 var yyTok2 = [...]int8{
 	2, 3, 4, 5, 6, 7, 8, 9, 10, 11,
 	12, 13, 14, 15, 16, 17, 18, 19, 20, 21,
-	22, 23, 24, 25, 26, 27, 28,
+	22, 23, 24, 25, 26, 27, 28, 29,
 }
 
 var yyTok3 = [...]int8{
@@ -648,6 +799,12 @@ yydefault:
 	case 20:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
+			if err := expr.ValidateAttrPath(yyDollar[1].tok.lit.(string)); err != nil {
+				if l, ok := yylex.(*lexer); ok {
+					l.fail(yyDollar[1].tok, err)
+				}
+				return 1
+			}
 			yyVAL.expr = expr.NewIdentifierExpr(yyDollar[1].tok.lit.(string))
 		}
 	case 21:
@@ -658,19 +815,24 @@ yydefault:
 	case 22:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
-			yyVAL.expr = expr.NewBoolExpr(true)
+			yyVAL.expr = expr.NewDecimalExpr(yyDollar[1].tok.lit.(float64))
 		}
 	case 23:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
-			yyVAL.expr = expr.NewBoolExpr(false)
+			yyVAL.expr = expr.NewBoolExpr(true)
 		}
 	case 24:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
-			yyVAL.expr = expr.NewIdentifierExpr(yyDollar[1].tok.lit.(string))
+			yyVAL.expr = expr.NewBoolExpr(false)
 		}
 	case 25:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.expr = expr.NewIdentifierExpr(yyDollar[1].tok.lit.(string))
+		}
+	case 26:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
 			yyVAL.expr = expr.NewAttrValueExpr(yyDollar[1].tok.lit.(string))
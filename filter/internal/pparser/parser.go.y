@@ -28,6 +28,9 @@ package pparser
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/cybozu-go/scim/filter/internal/expr"
 	"github.com/cybozu-go/scim/filter/internal/scanner"
@@ -53,7 +56,7 @@ type xtoken struct {
 %type<expr> attrName
 %type<expr> attrValue
 
-%token<tok> tIDENT tTRUE tFALSE tNULL tVALUE tNUMBER tPR tEQ tNE tCO tSW tEW tGT tGE tLT tLE tAND tOR tNOT tLPAREN tRPAREN tLBOXP tRBOXP tSP tDOT
+%token<tok> tIDENT tTRUE tFALSE tNULL tVALUE tNUMBER tDECIMAL tPR tEQ tNE tCO tSW tEW tGT tGE tLT tLE tAND tOR tNOT tLPAREN tRPAREN tLBOXP tRBOXP tSP tDOT
 
 %left  tAND
 %left  tOR
@@ -150,6 +153,12 @@ expr //TODO: gt,ge,lt,le operators should take string and boolean
 attrName
         : tIDENT 
 	{
+		if err := expr.ValidateAttrPath($1.lit.(string)); err != nil {
+			if l, ok := yylex.(*lexer); ok {
+				l.fail($1, err)
+			}
+			return 1
+		}
 		$$ = expr.NewIdentifierExpr($1.lit.(string))
         }
 
@@ -158,6 +167,10 @@ attrValue
 	{
 		$$ = expr.NewNumberExpr($1.lit.(int))
 	}
+        | tDECIMAL
+	{
+		$$ = expr.NewDecimalExpr($1.lit.(float64))
+	}
         | tTRUE
         {
        		$$ = expr.NewBoolExpr(true)
@@ -178,40 +191,72 @@ attrValue
 %%
 
 type lexer struct {
-	s         scanner.Scanner
-	recentLit interface{}
-	recentPos expr.Position
-	expr      expr.Interface
-	err       chan error
+	s       scanner.Scanner
+	tokens  []xtoken
+	expr    expr.Interface
+	err     chan error
+	counter *expr.Counter
 }
 
 func (l *lexer) Lex(lval *yySymType) int {
 	tok, lit, pos, err := l.s.Scan()
 	if err != nil {
-		l.emitError(err)
+		l.emitError(&expr.ParseError{Line: pos.Line, Column: pos.Column, Err: err})
 		return -1
 	}
 	if tok == tEOF {
-		return 0
+		tok = 0
 	}
 	lval.tok = xtoken{tok: tok, lit: lit, pos: pos}
-	l.recentLit = lit
-	l.recentPos = pos
+	l.tokens = append(l.tokens, lval.tok)
+
+	if err := l.count(tok); err != nil {
+		l.fail(lval.tok, err)
+		return -1
+	}
 	return tok
 }
 
-func (l *lexer) makeError(e interface{}) error {
-	switch e := e.(type) {
-	case error:
-		return fmt.Errorf(`parse error: line %d, column %d: %q: %w`, l.recentPos.Line, l.recentPos.Column, l.recentLit, e)
-	default:
-		return fmt.Errorf(`parse error: line %d, column %d: %q: %s`, l.recentPos.Line, l.recentPos.Column, l.recentLit, e)
+// count checks the token against the limits imposed on the parser,
+// so that the parser stops before building an expression that is too
+// large
+func (l *lexer) count(tok int) error {
+	switch tok {
+	case tLPAREN, tLBOXP:
+		if err := l.counter.Open(); err != nil {
+			return err
+		}
+		// groupings and value paths
+		return l.counter.Node()
+	case tRPAREN, tRBOXP:
+		l.counter.Close()
+	case tPR, tEQ, tNE, tCO, tSW, tEW, tGT, tGE, tLT, tLE, tAND, tOR:
+		return l.counter.Node()
 	}
+	return nil
 }
 
 // implements yylexer, so it must stay
-func (l *lexer) Error(e string) {
-	l.emitError(l.makeError(e))
+func (l *lexer) Error(string) {
+	if len(l.err) > 0 {
+		// the scanner has already reported a more specific error
+		return
+	}
+
+	perr := &expr.ParseError{}
+	if n := len(l.tokens); n > 0 {
+		last := l.tokens[n-1]
+		perr.Line = last.pos.Line
+		perr.Column = last.pos.Column
+		perr.Token = last.text()
+		perr.Expected = expectedTokens(l.tokens[:n-1])
+	}
+	l.emitError(perr)
+}
+
+// fail reports an error that was detected while processing the token t
+func (l *lexer) fail(t xtoken, err error) {
+	l.emitError(&expr.ParseError{Line: t.pos.Line, Column: t.pos.Column, Token: t.text(), Err: err})
 }
 
 func (l *lexer) emitError(err error) {
@@ -221,13 +266,124 @@ func (l *lexer) emitError(err error) {
 	}
 }
 
-func Parse(src string) (expr.Interface, error) {
+// text returns the textual representation of the token, to be
+// used in error messages
+func (t xtoken) text() string {
+	switch {
+	case t.tok == 0:
+		return ""
+	case t.tok == tVALUE:
+		return strconv.Quote(t.lit.(string))
+	default:
+		return fmt.Sprint(t.lit)
+	}
+}
+
+// tokenDescriptions lists the tokens along with their human readable
+// descriptions, which are used in error messages
+var tokenDescriptions = []struct {
+	tok  int
+	desc string
+}{
+	{tIDENT, `attribute path`},
+	{tVALUE, `string`},
+	{tNUMBER, `number`},
+	{tDECIMAL, `number`},
+	{tTRUE, `"true"`},
+	{tFALSE, `"false"`},
+	{tNULL, `"null"`},
+	{tPR, `"pr"`},
+	{tEQ, `"eq"`},
+	{tNE, `"ne"`},
+	{tCO, `"co"`},
+	{tSW, `"sw"`},
+	{tEW, `"ew"`},
+	{tGT, `"gt"`},
+	{tGE, `"ge"`},
+	{tLT, `"lt"`},
+	{tLE, `"le"`},
+	{tAND, `"and"`},
+	{tOR, `"or"`},
+	{tNOT, `"not"`},
+	{tLPAREN, `"("`},
+	{tRPAREN, `")"`},
+	{tLBOXP, `"["`},
+	{tRBOXP, `"]"`},
+	{tDOT, `"."`},
+	{0, `end of input`},
+}
+
+// replayLexer feeds a fixed list of tokens to the parser, followed
+// by the end of input
+type replayLexer struct {
+	tokens []xtoken
+	count  int
+}
+
+func (l *replayLexer) Lex(lval *yySymType) int {
+	l.count++
+	if l.count > len(l.tokens) {
+		return 0
+	}
+	lval.tok = l.tokens[l.count-1]
+	return lval.tok.tok
+}
+
+func (l *replayLexer) Error(string) {}
+
+// expectedTokens returns the descriptions of the tokens that may
+// follow the given list of tokens. Each candidate token is appended
+// to the list and fed to the parser: the candidate is accepted if
+// the parser proceeds past it without an error.
+func expectedTokens(prefix []xtoken) []string {
+	var list []string
+	seen := make(map[string]struct{})
+	for _, candidate := range tokenDescriptions {
+		if _, ok := seen[candidate.desc]; ok {
+			continue
+		}
+
+		// the parser actions expect literals of the correct type
+		var lit interface{}
+		switch candidate.tok {
+		case tNUMBER:
+			lit = 0
+		case tDECIMAL:
+			lit = float64(0)
+		case tIDENT, tVALUE:
+			lit = `x`
+		default:
+			lit = candidate.desc
+		}
+
+		tokens := make([]xtoken, len(prefix), len(prefix)+1)
+		copy(tokens, prefix)
+		tokens = append(tokens, xtoken{tok: candidate.tok, lit: lit})
+
+		rl := &replayLexer{tokens: tokens}
+		if yyParse(rl) != 0 && rl.count <= len(tokens) {
+			continue
+		}
+		seen[candidate.desc] = struct{}{}
+		list = append(list, candidate.desc)
+	}
+	return list
+}
+
+func Parse(src string, limits expr.Limits) (expr.Interface, error) {
+	counter := expr.NewCounter(limits)
+	if err := counter.CheckLength(src); err != nil {
+		// point to the first character beyond the limit
+		prefix := src[:limits.MaxLength]
+		line := strings.Count(prefix, "\n") + 1
+		column := utf8.RuneCountInString(prefix[strings.LastIndexByte(prefix, '\n')+1:]) + 1
+		return nil, &expr.ParseError{Line: line, Column: column, Err: err}
+	}
+
 	s := scanner.New(src, Dialect{})
-	l := lexer{s: s, err: make(chan error, 1)}
+	l := lexer{s: s, err: make(chan error, 1), counter: counter}
 	if yyParse(&l) != 0 {
 		return nil, <-l.err
 	}
 	return l.expr, nil
 }
-
-
@@ -26,6 +26,7 @@
 package scanner
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -45,6 +46,9 @@ func init() {
 }
 
 type Scanner interface {
+	// Scan returns the next token. The position of the token is
+	// returned even when an error occurs, so that the caller can
+	// report where the problem is.
 	Scan() (int, interface{}, expr.Position, error)
 }
 
@@ -83,7 +87,12 @@ func (s *scanner) Scan() (tok int, lit interface{}, pos expr.Position, err error
 	switch ch := s.peek(); {
 	case unicode.IsLetter(ch) || ch == '"':
 		if ch == '"' {
-			tok, lit = s.dialect.TokenType(token.Value), s.scanAttrValue()
+			v, verr := s.scanAttrValue()
+			if verr != nil {
+				err = verr
+				return
+			}
+			tok, lit = s.dialect.TokenType(token.Value), v
 		} else {
 			ident := s.scanIdentifier()
 			normalized := s.dialect.Normalize(ident)
@@ -95,10 +104,24 @@ func (s *scanner) Scan() (tok int, lit interface{}, pos expr.Position, err error
 				lit = ident
 			}
 		}
-	case isDigit(ch):
-		i, _ := strconv.Atoi(s.scanNumber())
-		tok = s.dialect.TokenType(token.Number)
-		lit = i
+	case isDigit(ch) || (ch == '-' && isDigit(s.peekAt(1))):
+		num, decimal := s.scanNumber()
+		if !decimal {
+			if i, ierr := strconv.Atoi(num); ierr == nil {
+				tok = s.dialect.TokenType(token.Number)
+				lit = i
+				return
+			}
+		}
+
+		// decimals, as well as integers that are too large to fit in an int
+		f, ferr := strconv.ParseFloat(num, 64)
+		if ferr != nil {
+			err = fmt.Errorf(`invalid number %q: %w`, num, ferr)
+			return
+		}
+		tok = s.dialect.TokenType(token.Decimal)
+		lit = f
 	case ch == '(':
 		tok = s.dialect.TokenType(token.LParen)
 		lit = "("
@@ -123,15 +146,11 @@ func (s *scanner) Scan() (tok int, lit interface{}, pos expr.Position, err error
 		tok = s.dialect.TokenType(token.EOF)
 		s.next()
 	default:
-		err = fmt.Errorf(`scan error: line %d, column %d: %q`, pos.Line, pos.Column, ch)
+		err = fmt.Errorf(`invalid character %q`, ch)
 	}
 	return
 }
 
-func isLetter(ch rune) bool {
-	return unicode.IsLetter(ch) || unicode.IsPunct(ch) || unicode.IsSymbol(ch)
-}
-
 func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
@@ -147,6 +166,14 @@ func (s *scanner) peek() rune {
 	return -1
 }
 
+// peekAt returns the rune that is n runes ahead of the current position
+func (s *scanner) peekAt(n int) rune {
+	if i := s.offset + n; i < len(s.src) {
+		return s.src[i]
+	}
+	return -1
+}
+
 func (s *scanner) next() {
 	if !s.reachEOF() {
 		if s.peek() == '\n' {
@@ -171,18 +198,33 @@ func (s *scanner) skipWhiteSpace() {
 	}
 }
 
-func (s *scanner) scanAttrValue() string {
-	var ret []rune
+// scanAttrValue scans a JSON string, including the surrounding
+// double quotes, and returns the unescaped value
+func (s *scanner) scanAttrValue() (string, error) {
+	ret := []rune{s.peek()}
 	s.next()
-	for isLetter(s.peek()) || isDigit(s.peek()) || s.peek() == ' ' {
-		ret = append(ret, s.peek())
+	for {
+		ch := s.peek()
+		if ch == -1 {
+			return "", fmt.Errorf(`unterminated string`)
+		}
+		ret = append(ret, ch)
 		s.next()
-		if ret[len(ret)-1] != '\\' && s.peek() == '"' {
-			s.next()
-			break
+
+		switch ch {
+		case '\\':
+			if s.peek() != -1 {
+				ret = append(ret, s.peek())
+				s.next()
+			}
+		case '"':
+			var v string
+			if err := json.Unmarshal([]byte(string(ret)), &v); err != nil {
+				return "", fmt.Errorf(`invalid string %s: %w`, string(ret), err)
+			}
+			return v, nil
 		}
 	}
-	return string(ret)
 }
 
 func (s *scanner) scanIdentifier() string {
@@ -199,11 +241,45 @@ func (s *scanner) scanIdentifier() string {
 	return string(ret)
 }
 
-func (s *scanner) scanNumber() string {
+// scanNumber scans a JSON number, and reports whether the number
+// contains a fraction or an exponent part
+func (s *scanner) scanNumber() (string, bool) {
 	var ret []rune
+	var decimal bool
+	if s.peek() == '-' {
+		ret = append(ret, s.peek())
+		s.next()
+	}
+	ret = s.scanDigits(ret)
+
+	if s.peek() == '.' && isDigit(s.peekAt(1)) {
+		decimal = true
+		ret = append(ret, s.peek())
+		s.next()
+		ret = s.scanDigits(ret)
+	}
+
+	if ch := s.peek(); ch == 'e' || ch == 'E' {
+		n := 1
+		if sign := s.peekAt(1); sign == '+' || sign == '-' {
+			n++
+		}
+		if isDigit(s.peekAt(n)) {
+			decimal = true
+			for i := 0; i < n; i++ {
+				ret = append(ret, s.peek())
+				s.next()
+			}
+			ret = s.scanDigits(ret)
+		}
+	}
+	return string(ret), decimal
+}
+
+func (s *scanner) scanDigits(ret []rune) []rune {
 	for isDigit(s.peek()) {
 		ret = append(ret, s.peek())
 		s.next()
 	}
-	return string(ret)
+	return ret
 }
@@ -29,6 +29,11 @@ func runSingleTest(t *testing.T, src string, expectTok int, dialect Dialect) {
 		if lit != s {
 			t.Errorf("Expect Scanner{%q}.Scan() = _, %#v want %#v", src, lit, src)
 		}
+	case float64:
+		f, _ := strconv.ParseFloat(src, 64)
+		if lit != f {
+			t.Errorf("Expect Scanner{%q}.Scan() = _, %#v want %#v", src, lit, src)
+		}
 	case bool:
 		s, _ := strconv.ParseBool(src)
 		if lit != s {
@@ -65,6 +70,11 @@ func TestScanner(t *testing.T, dialect Dialect) {
 		t.Errorf("could not find token type for %s", token.Number)
 	}
 
+	decimalTok := dialect.TokenType(token.Decimal)
+	if decimalTok == -1 {
+		t.Errorf("could not find token type for %s", token.Decimal)
+	}
+
 	trueTok := dialect.TokenType(token.True)
 	if trueTok == -1 {
 		t.Errorf("could not find token type for %s", token.True)
@@ -143,10 +153,34 @@ func TestScanner(t *testing.T, dialect Dialect) {
 			Input: `"ham (spam)"`,
 			Token: valueTok,
 		},
+		{
+			Input: `""`,
+			Token: valueTok,
+		},
 		{
 			Input: `123`,
 			Token: numberTok,
 		},
+		{
+			Input: `-123`,
+			Token: numberTok,
+		},
+		{
+			Input: `1.5`,
+			Token: decimalTok,
+		},
+		{
+			Input: `-0.25`,
+			Token: decimalTok,
+		},
+		{
+			Input: `1e3`,
+			Token: decimalTok,
+		},
+		{
+			Input: `6.02E+23`,
+			Token: decimalTok,
+		},
 		{
 			Input: `true`,
 			Token: trueTok,
@@ -2,10 +2,11 @@ package token
 
 const (
 	// token types
-	Value  = `!!!value`
-	Ident  = `!!!ident`
-	Number = `!!!number`
-	EOF    = `!!!eof`
+	Value   = `!!!value`
+	Ident   = `!!!ident`
+	Number  = `!!!number`
+	Decimal = `!!!decimal`
+	EOF     = `!!!eof`
 
 	// keywords
 	NotOp                  = `not`
@@ -0,0 +1,416 @@
+// Package ldapfilter translates SCIM filters into LDAP search filters,
+// as described in RFC 4515.
+package ldapfilter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cybozu-go/scim/filter"
+	"github.com/cybozu-go/scim/resource"
+	"github.com/cybozu-go/scim/schema"
+)
+
+// DefaultAttributes maps the attributes of the SCIM core User and Group
+// schemas to the attributes of the `inetOrgPerson` and `groupOfNames`
+// object classes.
+var DefaultAttributes = map[string]string{
+	`id`:                 `entryUUID`,
+	`externalId`:         `employeeNumber`,
+	`userName`:           `uid`,
+	`name.formatted`:     `cn`,
+	`name.familyName`:    `sn`,
+	`name.givenName`:     `givenName`,
+	`displayName`:        `displayName`,
+	`title`:              `title`,
+	`preferredLanguage`:  `preferredLanguage`,
+	`emails`:             `mail`,
+	`emails.value`:       `mail`,
+	`phoneNumbers`:       `telephoneNumber`,
+	`phoneNumbers.value`: `telephoneNumber`,
+	`members`:            `member`,
+	`members.value`:      `member`,
+	`meta.created`:       `createTimestamp`,
+	`meta.lastModified`:  `modifyTimestamp`,
+}
+
+// commonDateTimes lists the dateTime attributes that are common to all
+// resources, which are known without a schema
+var commonDateTimes = map[string]struct{}{
+	`meta.created`:      {},
+	`meta.lastmodified`: {},
+}
+
+// Translator translates SCIM filters into LDAP search filters. Use
+// `ldapfilter.New()` to create one.
+type Translator struct {
+	attributes map[string]string
+	schema     *resource.Schema
+}
+
+// New creates a new Translator.
+func New(options ...NewOption) *Translator {
+	attributes := DefaultAttributes
+	var s *resource.Schema
+
+	//nolint:forcetypeassert
+	for _, option := range options {
+		switch option.Ident() {
+		case identAttributes{}:
+			attributes = option.Value().(map[string]string)
+		case identSchema{}:
+			s = option.Value().(*resource.Schema)
+		}
+	}
+
+	// attribute paths are case-insensitive
+	t := &Translator{attributes: make(map[string]string, len(attributes)), schema: s}
+	for path, attr := range attributes {
+		t.attributes[strings.ToLower(path)] = attr
+	}
+	return t
+}
+
+// Translate translates the filter into an LDAP search filter.
+//
+// Attributes that are not listed in the attribute map result in an
+// error. Value paths (e.g. `emails[type eq "work"]`) are translated
+// by prefixing the attributes inside the brackets with the parent
+// attribute (e.g. `emails.type`). As LDAP attributes have no
+// sub-attributes, the conditions inside a value path may be satisfied
+// by different values of a multi-valued attribute.
+//
+// `ne`, `gt` and `lt` are not available in LDAP, and are expressed
+// using `!`. For example, `title ne "Tour Guide"` is translated into
+// `(&(title=*)(!(title=Tour Guide)))`, as `ne` does not match resources
+// without a value.
+//
+// Complex attributes (e.g. `emails`) can be tested for presence, but
+// comparisons must name a sub-attribute (e.g. `emails.value`), as
+// in `filter.Validate()`.
+//
+// Values of dateTime attributes (e.g. `"2011-05-13T04:42:34Z"`) are
+// converted into the GeneralizedTime syntax in UTC (e.g.
+// `20110513044234Z`), so that they are ordered correctly by LDAP
+// servers.
+func (t *Translator) Translate(expr filter.Expr) (string, error) {
+	if t.schema != nil {
+		if err := filter.Validate(expr, t.schema); err != nil {
+			return "", err
+		}
+	}
+
+	var sb strings.Builder
+	if err := t.translate(&sb, "", expr); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func (t *Translator) translate(sb *strings.Builder, prefix string, v filter.Expr) error {
+	switch v := v.(type) {
+	case filter.PresenceExpr:
+		attr, err := t.attribute(prefix, v.Attr())
+		if err != nil {
+			return fmt.Errorf(`left hand side of %q is not valid: %w`, v.Operator(), err)
+		}
+		fmt.Fprintf(sb, `(%s=*)`, attr)
+		return nil
+	case filter.CompareExpr:
+		return t.translateComparison(sb, prefix, v.LHE(), v.Operator(), v.RHE())
+	case filter.RegexExpr:
+		return t.translateComparison(sb, prefix, v.LHE(), v.Operator(), v.Value())
+	case filter.LogExpr:
+		return t.translateLogExpr(sb, prefix, v)
+	case filter.ParenExpr:
+		switch v.Operator() {
+		case "":
+			return t.translate(sb, prefix, v.SubExpr())
+		case filter.NotOp:
+			sb.WriteString(`(!`)
+			if err := t.translate(sb, prefix, v.SubExpr()); err != nil {
+				return err
+			}
+			sb.WriteString(`)`)
+			return nil
+		default:
+			return fmt.Errorf(`unhandled grouping operator %q`, v.Operator())
+		}
+	case filter.ValuePath:
+		return t.translateValuePath(sb, prefix, v)
+	default:
+		return fmt.Errorf(`unhandled expression type: %T`, v)
+	}
+}
+
+// attribute returns the LDAP attribute for the attribute path
+func (t *Translator) attribute(prefix string, v filter.Expr) (string, error) {
+	ident, ok := v.(filter.IdentifierExpr)
+	if !ok {
+		return "", fmt.Errorf(`expected identifier, got %T`, v)
+	}
+
+	path := ident.Lit()
+	if prefix != "" {
+		path = prefix + `.` + path
+	}
+	attr, ok := t.attributes[strings.ToLower(path)]
+	if !ok {
+		return "", fmt.Errorf(`attribute %q cannot be mapped to an LDAP attribute`, path)
+	}
+	return attr, nil
+}
+
+func (t *Translator) translateComparison(sb *strings.Builder, prefix string, lhe filter.Expr, op string, rhe interface{}) error {
+	attr, err := t.attribute(prefix, lhe)
+	if err != nil {
+		return fmt.Errorf(`left hand side of %q is not valid: %w`, op, err)
+	}
+
+	value, null, err := literal(rhe)
+	if err != nil {
+		return fmt.Errorf(`right hand side of %q is not valid: %w`, op, err)
+	}
+
+	if !null && t.isComplex(prefix, lhe) {
+		return fmt.Errorf(`operator %q cannot be used against complex attribute %q`, op, lhe)
+	}
+
+	if isString(rhe) && t.isDateTime(prefix, lhe) {
+		tm, err := resource.ParseDateTime(value)
+		if err != nil {
+			return fmt.Errorf(`failed to parse %q as dateTime: %w`, value, err)
+		}
+		value = generalizedTime(tm)
+	}
+
+	if null {
+		switch op {
+		case filter.EqualOp:
+			fmt.Fprintf(sb, `(!(%s=*))`, attr)
+		case filter.NotEqualOp:
+			fmt.Fprintf(sb, `(%s=*)`, attr)
+		default:
+			return fmt.Errorf(`operator %q cannot be used against null`, op)
+		}
+		return nil
+	}
+
+	// every value contains the empty string, which cannot be expressed
+	// as a substring assertion (e.g. `(attr=**)` is not a valid filter)
+	if value == "" {
+		switch op {
+		case filter.ContainsOp, filter.StartsWithOp, filter.EndsWithOp:
+			fmt.Fprintf(sb, `(%s=*)`, attr)
+			return nil
+		}
+	}
+
+	value = Escape(value)
+	switch op {
+	case filter.EqualOp:
+		fmt.Fprintf(sb, `(%s=%s)`, attr, value)
+	case filter.NotEqualOp:
+		fmt.Fprintf(sb, `(&(%s=*)(!(%s=%s)))`, attr, attr, value)
+	case filter.ContainsOp:
+		fmt.Fprintf(sb, `(%s=*%s*)`, attr, value)
+	case filter.StartsWithOp:
+		fmt.Fprintf(sb, `(%s=%s*)`, attr, value)
+	case filter.EndsWithOp:
+		fmt.Fprintf(sb, `(%s=*%s)`, attr, value)
+	case filter.GreaterThanOp:
+		fmt.Fprintf(sb, `(&(%s>=%s)(!(%s=%s)))`, attr, value, attr, value)
+	case filter.GreaterThanOrEqualToOp:
+		fmt.Fprintf(sb, `(%s>=%s)`, attr, value)
+	case filter.LessThanOp:
+		fmt.Fprintf(sb, `(&(%s<=%s)(!(%s=%s)))`, attr, value, attr, value)
+	case filter.LessThanOrEqualToOp:
+		fmt.Fprintf(sb, `(%s<=%s)`, attr, value)
+	default:
+		return fmt.Errorf(`unhandled comparison operator %q`, op)
+	}
+	return nil
+}
+
+// isString reports whether the comparison value is a string
+func isString(v interface{}) bool {
+	switch v.(type) {
+	case string, filter.AttrValueExpr:
+		return true
+	default:
+		return false
+	}
+}
+
+// isComplex reports whether the attribute path refers to a complex
+// attribute, which can only be compared through its sub-attributes.
+// Without a schema, attributes whose `value` sub-attribute is listed
+// in the attribute map (e.g. `emails`) are considered complex. With a
+// schema, such comparisons have already been rejected by
+// `filter.Validate()`
+func (t *Translator) isComplex(prefix string, v filter.Expr) bool {
+	ident, ok := v.(filter.IdentifierExpr)
+	if !ok || t.schema != nil {
+		return false
+	}
+
+	path := ident.Lit()
+	if prefix != "" {
+		path = prefix + `.` + path
+	}
+	_, ok = t.attributes[strings.ToLower(path)+`.value`]
+	return ok
+}
+
+// isDateTime reports whether the attribute path refers to a dateTime
+// attribute
+func (t *Translator) isDateTime(prefix string, v filter.Expr) bool {
+	ident, ok := v.(filter.IdentifierExpr)
+	if !ok {
+		return false
+	}
+
+	if t.schema == nil {
+		path := ident.Lit()
+		if prefix != "" {
+			path = prefix + `.` + path
+		}
+		_, ok := commonDateTimes[strings.ToLower(path)]
+		return ok
+	}
+
+	attrs := t.schema.Attributes()
+	if uri := ident.SchemaURI(); uri != "" && !strings.EqualFold(uri, t.schema.ID()) {
+		ext, ok := schema.Get(uri)
+		if !ok {
+			return false
+		}
+		attrs = ext.Attributes()
+	}
+
+	var names []string
+	if prefix != "" {
+		names = append(names, prefix)
+	}
+	names = append(names, ident.Attribute())
+	if sub := ident.SubAttribute(); sub != "" {
+		names = append(names, sub)
+	}
+
+	var attr *resource.SchemaAttribute
+	for _, name := range names {
+		if attr = schema.FindAttribute(attrs, name); attr == nil {
+			return false
+		}
+		attrs = attr.SubAttributes()
+	}
+	return attr.Type() == resource.DateTime
+}
+
+// generalizedTime formats the time in the GeneralizedTime syntax
+// (RFC 4517 Section 3.3.13) in UTC
+func generalizedTime(tm time.Time) string {
+	return tm.UTC().Format(`20060102150405.999999999`) + `Z`
+}
+
+// literal converts the comparison value of a filter into its LDAP string
+// representation. null is reported separately
+func literal(v interface{}) (string, bool, error) {
+	switch v := v.(type) {
+	case string:
+		return v, false, nil
+	case filter.AttrValueExpr:
+		return v.Lit(), false, nil
+	case filter.NumberExpr:
+		return strconv.Itoa(v.Lit()), false, nil
+	case filter.DecimalExpr:
+		return strconv.FormatFloat(v.Lit(), 'f', -1, 64), false, nil
+	case filter.BoolExpr:
+		// RFC 4517 Section 3.3.3
+		if v.Lit() {
+			return `TRUE`, false, nil
+		}
+		return `FALSE`, false, nil
+	case filter.IdentifierExpr:
+		if v.Lit() == filter.Null {
+			return "", true, nil
+		}
+		return "", false, fmt.Errorf(`unexpected identifier %q`, v.Lit())
+	default:
+		return "", false, fmt.Errorf(`unhandled value type: %T`, v)
+	}
+}
+
+func (t *Translator) translateLogExpr(sb *strings.Builder, prefix string, v filter.LogExpr) error {
+	switch v.Operator() {
+	case filter.AndOp:
+		sb.WriteString(`(&`)
+	case filter.OrOp:
+		sb.WriteString(`(|`)
+	default:
+		return fmt.Errorf(`unhandled logical operator %q`, v.Operator())
+	}
+
+	// chains of the same operator are written as a single filter
+	// (e.g. `(&(a=*)(b=*)(c=*))`)
+	var operands func(filter.Expr) error
+	operands = func(e filter.Expr) error {
+		if l, ok := e.(filter.LogExpr); ok && l.Operator() == v.Operator() {
+			if err := operands(l.LHE()); err != nil {
+				return err
+			}
+			return operands(l.RHS())
+		}
+		if err := t.translate(sb, prefix, e); err != nil {
+			return fmt.Errorf(`failed to translate operand of %q: %w`, v.Operator(), err)
+		}
+		return nil
+	}
+	if err := operands(v); err != nil {
+		return err
+	}
+	sb.WriteString(`)`)
+	return nil
+}
+
+func (t *Translator) translateValuePath(sb *strings.Builder, prefix string, v filter.ValuePath) error {
+	if prefix != "" {
+		return fmt.Errorf(`value paths cannot be nested`)
+	}
+
+	ident, ok := v.ParentAttr().(filter.IdentifierExpr)
+	if !ok || ident.SubAttribute() != "" {
+		return fmt.Errorf(`parent attribute of value path is not valid: %v`, v.ParentAttr())
+	}
+
+	if v.SubExpr() == nil {
+		attr, err := t.attribute("", ident)
+		if err != nil {
+			return fmt.Errorf(`parent attribute of value path is not valid: %w`, err)
+		}
+		fmt.Fprintf(sb, `(%s=*)`, attr)
+		return nil
+	}
+
+	if err := t.translate(sb, ident.Lit(), v.SubExpr()); err != nil {
+		return fmt.Errorf(`failed to translate filter for value path: %w`, err)
+	}
+	return nil
+}
+
+// Escape escapes the characters that have special meanings in the
+// assertion values of LDAP search filters (RFC 4515 Section 3)
+func Escape(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '*', '(', ')', '\\', 0:
+			fmt.Fprintf(&sb, `\%02x`, c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
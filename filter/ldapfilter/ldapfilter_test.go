@@ -0,0 +1,100 @@
+package ldapfilter_test
+
+import (
+	"testing"
+
+	"github.com/cybozu-go/scim/filter"
+	"github.com/cybozu-go/scim/filter/ldapfilter"
+	"github.com/cybozu-go/scim/resource"
+	"github.com/cybozu-go/scim/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranslate(t *testing.T) {
+	userSchema, ok := schema.Get(resource.UserSchemaURI)
+	require.True(t, ok, `schema.Get should succeed`)
+
+	testcases := []struct {
+		Filter   string
+		Options  []ldapfilter.NewOption
+		Expected string
+		Error    bool
+	}{
+		{Filter: `userName eq "bjensen"`, Expected: `(uid=bjensen)`},
+		{Filter: `USERNAME eq "bjensen"`, Expected: `(uid=bjensen)`},
+		{Filter: `userName sw "J"`, Expected: `(uid=J*)`},
+		{Filter: `userName ew "sen"`, Expected: `(uid=*sen)`},
+		{Filter: `name.familyName co "O'Malley"`, Expected: `(sn=*O'Malley*)`},
+		{Filter: `title pr`, Expected: `(title=*)`},
+		{Filter: `title ne "Tour Guide"`, Expected: `(&(title=*)(!(title=Tour Guide)))`},
+		{Filter: `title eq null`, Expected: `(!(title=*))`},
+		{Filter: `title ne null`, Expected: `(title=*)`},
+		{Filter: `displayName eq "a*b (c) \\ d"`, Expected: `(displayName=a\2ab \28c\29 \5c d)`},
+		{Filter: `displayName co "*"`, Expected: `(displayName=*\2a*)`},
+		{Filter: `displayName co ""`, Expected: `(displayName=*)`},
+		{Filter: `displayName sw ""`, Expected: `(displayName=*)`},
+		{Filter: `displayName ew ""`, Expected: `(displayName=*)`},
+		{Filter: `meta.lastModified gt "2011-05-13T04:42:34Z"`, Expected: `(&(modifyTimestamp>=20110513044234Z)(!(modifyTimestamp=20110513044234Z)))`},
+		{Filter: `meta.lastModified ge "2011-05-13T04:42:34Z"`, Expected: `(modifyTimestamp>=20110513044234Z)`},
+		{Filter: `meta.lastModified lt "2011-05-13T04:42:34Z"`, Expected: `(&(modifyTimestamp<=20110513044234Z)(!(modifyTimestamp=20110513044234Z)))`},
+		{Filter: `meta.lastModified le "2011-05-13T04:42:34Z"`, Expected: `(modifyTimestamp<=20110513044234Z)`},
+		{Filter: `meta.created ge "2011-05-13T13:42:34.5+09:00"`, Expected: `(createTimestamp>=20110513044234.5Z)`},
+		{Filter: `meta.lastModified gt "yesterday"`, Error: true},
+		{
+			Filter:   `meta.lastModified ge "2011-05-13T13:42:34+09:00" and userName sw "b"`,
+			Options:  []ldapfilter.NewOption{ldapfilter.WithSchema(userSchema)},
+			Expected: `(&(modifyTimestamp>=20110513044234Z)(uid=b*))`,
+		},
+		{
+			Filter:  `meta.lastModified gt true`,
+			Options: []ldapfilter.NewOption{ldapfilter.WithSchema(userSchema)},
+			Error:   true,
+		},
+		{Filter: `title pr and userName sw "b" and emails.value co "@example.com"`, Expected: `(&(title=*)(uid=b*)(mail=*@example.com*))`},
+		{Filter: `emails co "@example.com"`, Error: true},
+		{Filter: `emails pr`, Expected: `(mail=*)`},
+		{Filter: `title pr and (userName eq "a" or userName eq "b")`, Expected: `(&(title=*)(|(uid=a)(uid=b)))`},
+		{Filter: `not (userName eq "bjensen")`, Expected: `(!(uid=bjensen))`},
+		{Filter: `emails[value ew "@example.com"]`, Expected: `(mail=*@example.com)`},
+		{Filter: `emails[type eq "work"]`, Error: true},
+		{Filter: `nickName eq "Babs"`, Error: true},
+		{
+			Filter: `active eq true and employeeNumber eq 701984`,
+			Options: []ldapfilter.NewOption{
+				ldapfilter.WithAttributes(map[string]string{
+					`active`:         `accountActive`,
+					`employeeNumber`: `employeeNumber`,
+				}),
+			},
+			Expected: `(&(accountActive=TRUE)(employeeNumber=701984))`,
+		},
+		{
+			Filter: `userName eq "bjensen"`,
+			Options: []ldapfilter.NewOption{
+				ldapfilter.WithAttributes(map[string]string{`userName`: `sAMAccountName`}),
+			},
+			Expected: `(sAMAccountName=bjensen)`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Filter, func(t *testing.T) {
+			expr, err := filter.Parse(tc.Filter)
+			require.NoError(t, err, `filter.Parse should succeed`)
+
+			s, err := ldapfilter.New(tc.Options...).Translate(expr)
+			if tc.Error {
+				require.Error(t, err, `Translate should fail`)
+				return
+			}
+			require.NoError(t, err, `Translate should succeed`)
+			require.Equal(t, tc.Expected, s, `LDAP filter should match`)
+		})
+	}
+}
+
+func TestEscape(t *testing.T) {
+	require.Equal(t, `\28uid=\2a\29\5c\00`, ldapfilter.Escape("(uid=*)\\\x00"))
+	require.Equal(t, `José`, ldapfilter.Escape(`José`))
+}
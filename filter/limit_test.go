@@ -0,0 +1,97 @@
+package filter_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cybozu-go/scim/filter"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimits(t *testing.T) {
+	testcases := []struct {
+		Name    string
+		Filter  string
+		Options []filter.ParseOption
+		Limit   string
+		Column  int
+		Message string
+	}{
+		{
+			Name:    `within all limits`,
+			Filter:  `(userName eq "bjensen") and emails[type eq "work"]`,
+			Options: []filter.ParseOption{filter.WithMaxLength(50), filter.WithMaxDepth(1), filter.WithMaxNodes(6)},
+		},
+		{
+			Name:    `zero means no limit`,
+			Filter:  `((((userName pr))))`,
+			Options: []filter.ParseOption{filter.WithMaxLength(0), filter.WithMaxDepth(0), filter.WithMaxNodes(0)},
+		},
+		{
+			Name:    `too long`,
+			Filter:  `userName eq "bjensen"`,
+			Options: []filter.ParseOption{filter.WithMaxLength(10)},
+			Limit:   filter.LimitLength,
+			Column:  11,
+			Message: `filter is longer than the maximum of 10 bytes at column 11`,
+		},
+		{
+			Name:    `nested too deeply`,
+			Filter:  `((userName pr))`,
+			Options: []filter.ParseOption{filter.WithMaxDepth(1)},
+			Limit:   filter.LimitDepth,
+			Column:  2,
+			Message: `filter is nested deeper than the maximum depth of 1 at column 2`,
+		},
+		{
+			Name:    `value path counts towards depth`,
+			Filter:  `(emails[type eq "work"])`,
+			Options: []filter.ParseOption{filter.WithMaxDepth(1)},
+			Limit:   filter.LimitDepth,
+			Column:  8,
+			Message: `filter is nested deeper than the maximum depth of 1 at column 8`,
+		},
+		{
+			Name:    `sibling groups do not add up`,
+			Filter:  `(userName pr) or (title pr)`,
+			Options: []filter.ParseOption{filter.WithMaxDepth(1)},
+		},
+		{
+			Name:    `too many expressions`,
+			Filter:  `userName pr and title pr and nickName pr`,
+			Options: []filter.ParseOption{filter.WithMaxNodes(4)},
+			Limit:   filter.LimitNodes,
+			Column:  39,
+			Message: `filter contains more than the maximum of 4 expressions at column 39`,
+		},
+		{
+			Name:    `patch expression`,
+			Filter:  `members[value eq "2819c223"]`,
+			Options: []filter.ParseOption{filter.WithPatchExpression(true), filter.WithMaxNodes(1)},
+			Limit:   filter.LimitNodes,
+			Column:  15,
+			Message: `filter contains more than the maximum of 1 expressions at column 15`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			_, err := filter.Parse(tc.Filter, tc.Options...)
+			if tc.Limit == "" {
+				require.NoError(t, err, `filter.Parse should succeed`)
+				return
+			}
+			require.Error(t, err, `filter.Parse should fail`)
+
+			var perr *filter.ParseError
+			require.True(t, errors.As(err, &perr), `error should be a *filter.ParseError`)
+			require.Equal(t, tc.Column, perr.Column, `column should match`)
+			require.Equal(t, tc.Message, perr.Error(), `message should match`)
+
+			var lerr *filter.LimitError
+			require.True(t, errors.As(err, &lerr), `error should wrap a *filter.LimitError`)
+			require.Equal(t, tc.Limit, lerr.Limit, `limit should match`)
+		})
+	}
+}
@@ -0,0 +1,423 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cybozu-go/scim/resource"
+	"github.com/cybozu-go/scim/schema"
+)
+
+// Match evaluates the filter expression against v, and reports
+// whether v satisfies the filter.
+//
+// v is usually a *resource.User or a *resource.Group, but any object that
+// implements `Keys()` and `Get()` methods (such as extension objects),
+// as well as map[string]interface{} values can be evaluated.
+//
+// The evaluation follows RFC7644 Section 3.4.2.2: attribute names are
+// case-insensitive, and string values are compared case-insensitively
+// unless the attribute is defined as caseExact in the schema. When the
+// attribute is multi-valued, the expression matches if any one of the
+// values satisfies the condition.
+//
+// The schema used to determine the attribute characteristics is
+// deduced from the resource type. You may explicitly specify it by
+// using the `filter.WithSchema()` option.
+func Match(expr Expr, v interface{}, options ...MatchOption) (bool, error) {
+	var s *resource.Schema
+	//nolint:forcetypeassert
+	for _, option := range options {
+		switch option.Ident() {
+		case identSchema{}:
+			s = option.Value().(*resource.Schema)
+		}
+	}
+
+	if s == nil {
+		s, _ = schema.ForResource(v)
+	}
+	return newEvaluator(v, s).eval(expr)
+}
+
+type evaluator struct {
+	root  interface{}
+	uri   string
+	attrs []*resource.SchemaAttribute
+}
+
+func newEvaluator(root interface{}, s *resource.Schema) *evaluator {
+	e := &evaluator{root: root}
+	if s != nil {
+		e.uri = s.ID()
+		e.attrs = s.Attributes()
+	}
+	return e
+}
+
+func (e *evaluator) eval(v Expr) (bool, error) {
+	switch v := v.(type) {
+	case PresenceExpr:
+		return e.evalPresenceExpr(v)
+	case CompareExpr:
+		return e.evalCompareExpr(v)
+	case RegexExpr:
+		return e.evalRegexExpr(v)
+	case LogExpr:
+		return e.evalLogExpr(v)
+	case ParenExpr:
+		return e.evalParenExpr(v)
+	case ValuePath:
+		return e.evalValuePath(v)
+	default:
+		return false, fmt.Errorf(`unhandled expression type: %T`, v)
+	}
+}
+
+// lookup resolves the attribute path, and returns the list of values
+// found in the resource, as well as the schema attribute that describes
+// the values, if available
+func (e *evaluator) lookup(name string) ([]interface{}, *resource.SchemaAttribute) {
+	path := parseAttrPath(name)
+	root := e.root
+	attrs := e.attrs
+	if path.uri != "" && !strings.EqualFold(path.uri, e.uri) {
+		attrs = nil
+		if s, ok := schema.Get(path.uri); ok {
+			attrs = s.Attributes()
+		}
+
+		ext, ok := lookupKey(e.root, path.uri)
+		if !ok {
+			return nil, findAttributePath(attrs, path.names)
+		}
+		root = ext
+	}
+	return resolve([]interface{}{root}, path.names), findAttributePath(attrs, path.names)
+}
+
+func attrName(v Expr) (string, error) {
+	ident, ok := v.(IdentifierExpr)
+	if !ok {
+		return "", fmt.Errorf(`expected attribute path, got %T`, v)
+	}
+	return ident.Lit(), nil
+}
+
+// literal converts the value expression into a Go value. null is
+// represented as a nil value
+func literal(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case AttrValueExpr:
+		return v.Lit(), nil
+	case NumberExpr:
+		return float64(v.Lit()), nil
+	case DecimalExpr:
+		return v.Lit(), nil
+	case BoolExpr:
+		return v.Lit(), nil
+	case IdentifierExpr:
+		if v.Lit() == Null {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(`unexpected identifier %q in comparison value`, v.Lit())
+	default:
+		return nil, fmt.Errorf(`unhandled comparison value type: %T`, v)
+	}
+}
+
+func (e *evaluator) evalPresenceExpr(v PresenceExpr) (bool, error) {
+	name, err := attrName(v.Attr())
+	if err != nil {
+		return false, fmt.Errorf(`left hand side of %q is not valid: %w`, v.Operator(), err)
+	}
+	values, _ := e.lookup(name)
+	return isPresent(values), nil
+}
+
+func (e *evaluator) evalCompareExpr(v CompareExpr) (bool, error) {
+	return e.evalComparison(v.LHE(), v.Operator(), v.RHE())
+}
+
+func (e *evaluator) evalRegexExpr(v RegexExpr) (bool, error) {
+	return e.evalComparison(v.LHE(), v.Operator(), v.Value())
+}
+
+func (e *evaluator) evalComparison(lhe Expr, op string, rhe interface{}) (bool, error) {
+	name, err := attrName(lhe)
+	if err != nil {
+		return false, fmt.Errorf(`left hand side of %q is not valid: %w`, op, err)
+	}
+
+	rhs, err := literal(rhe)
+	if err != nil {
+		return false, fmt.Errorf(`right hand side of %q is not valid: %w`, op, err)
+	}
+
+	values, attr := e.lookup(name)
+	if rhs != nil && attr != nil && attr.Type() == resource.Complex {
+		return false, fmt.Errorf(`operator %q cannot be used against complex attribute %q`, op, name)
+	}
+	if rhs == nil {
+		switch op {
+		case EqualOp:
+			return !isPresent(values), nil
+		case NotEqualOp:
+			return isPresent(values), nil
+		default:
+			return false, fmt.Errorf(`operator %q cannot be used against null`, op)
+		}
+	}
+
+	for _, value := range values {
+		ok, err := compare(op, attr, value, rhs)
+		if err != nil {
+			return false, fmt.Errorf(`failed to evaluate %q: %w`, name, err)
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (e *evaluator) evalLogExpr(v LogExpr) (bool, error) {
+	lhs, err := e.eval(v.LHE())
+	if err != nil {
+		return false, fmt.Errorf(`failed to evaluate left hand side of %q: %w`, v.Operator(), err)
+	}
+
+	switch v.Operator() {
+	case AndOp:
+		if !lhs {
+			return false, nil
+		}
+	case OrOp:
+		if lhs {
+			return true, nil
+		}
+	default:
+		return false, fmt.Errorf(`unhandled logical operator %q`, v.Operator())
+	}
+
+	rhs, err := e.eval(v.RHS())
+	if err != nil {
+		return false, fmt.Errorf(`failed to evaluate right hand side of %q: %w`, v.Operator(), err)
+	}
+	return rhs, nil
+}
+
+func (e *evaluator) evalParenExpr(v ParenExpr) (bool, error) {
+	ok, err := e.eval(v.SubExpr())
+	if err != nil {
+		return false, err
+	}
+
+	switch v.Operator() {
+	case "":
+		return ok, nil
+	case NotOp:
+		return !ok, nil
+	default:
+		return false, fmt.Errorf(`unhandled grouping operator %q`, v.Operator())
+	}
+}
+
+func (e *evaluator) evalValuePath(v ValuePath) (bool, error) {
+	name, err := attrName(v.ParentAttr())
+	if err != nil {
+		return false, fmt.Errorf(`parent attribute of value path is not valid: %w`, err)
+	}
+
+	values, attr := e.lookup(name)
+	if v.SubExpr() == nil {
+		return isPresent(values), nil
+	}
+
+	var attrs []*resource.SchemaAttribute
+	if attr != nil {
+		attrs = attr.SubAttributes()
+	}
+	for _, value := range values {
+		sub := &evaluator{root: value, attrs: attrs}
+		ok, err := sub.eval(v.SubExpr())
+		if err != nil {
+			return false, fmt.Errorf(`failed to evaluate filter for %q: %w`, name, err)
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isPresent returns true if any of the values are non-empty
+func isPresent(values []interface{}) bool {
+	for _, v := range values {
+		switch v := v.(type) {
+		case nil:
+		case string:
+			if v != "" {
+				return true
+			}
+		case map[string]interface{}:
+			if len(v) > 0 {
+				return true
+			}
+		case keyer:
+			if len(v.Keys()) > 0 {
+				return true
+			}
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// compare compares a single value from the resource against the
+// literal value in the filter.
+func compare(op string, attr *resource.SchemaAttribute, lhs, rhs interface{}) (bool, error) {
+	// Complex values can only be compared through their sub-attributes
+	// (e.g. `emails.value co "example.com"`), as in `filter.Validate()`
+	if isComplex(lhs) {
+		return false, fmt.Errorf(`operator %q cannot be used against complex values`, op)
+	}
+
+	if attr != nil {
+		switch attr.Type() {
+		case resource.DateTime:
+			// dateTime values may be stored as strings (e.g. in a
+			// map[string]interface{}), but they must be compared as
+			// points in time, not lexicographically
+			if s, ok := lhs.(string); ok {
+				t, err := resource.ParseDateTime(s)
+				if err != nil {
+					return false, nil
+				}
+				lhs = t
+			}
+		case resource.Binary:
+			switch op {
+			case EqualOp, NotEqualOp:
+			default:
+				return false, fmt.Errorf(`operator %q cannot be used against binary values`, op)
+			}
+		}
+	}
+
+	switch lhs := lhs.(type) {
+	case string:
+		rhs, ok := rhs.(string)
+		if !ok {
+			return false, nil
+		}
+		return compareStrings(op, lhs, rhs, attr != nil && (attr.CaseExact() || attr.Type() == resource.Binary))
+	case bool:
+		rhs, ok := rhs.(bool)
+		if !ok {
+			return false, nil
+		}
+		switch op {
+		case EqualOp:
+			return lhs == rhs, nil
+		case NotEqualOp:
+			return lhs != rhs, nil
+		default:
+			return false, fmt.Errorf(`operator %q cannot be used against boolean values`, op)
+		}
+	case time.Time:
+		s, ok := rhs.(string)
+		if !ok {
+			return false, nil
+		}
+		t, err := resource.ParseDateTime(s)
+		if err != nil {
+			return false, fmt.Errorf(`failed to parse %q as dateTime: %w`, s, err)
+		}
+		var cmp int
+		switch {
+		case lhs.Before(t):
+			cmp = -1
+		case lhs.After(t):
+			cmp = 1
+		}
+		return compareOrdered(op, cmp)
+	default:
+		l, ok := toFloat(lhs)
+		if !ok {
+			return false, nil
+		}
+		r, ok := rhs.(float64)
+		if !ok {
+			return false, nil
+		}
+		var cmp int
+		switch {
+		case l < r:
+			cmp = -1
+		case l > r:
+			cmp = 1
+		}
+		return compareOrdered(op, cmp)
+	}
+}
+
+func compareStrings(op string, lhs, rhs string, caseExact bool) (bool, error) {
+	if !caseExact {
+		lhs = strings.ToLower(lhs)
+		rhs = strings.ToLower(rhs)
+	}
+
+	switch op {
+	case ContainsOp:
+		return strings.Contains(lhs, rhs), nil
+	case StartsWithOp:
+		return strings.HasPrefix(lhs, rhs), nil
+	case EndsWithOp:
+		return strings.HasSuffix(lhs, rhs), nil
+	default:
+		return compareOrdered(op, strings.Compare(lhs, rhs))
+	}
+}
+
+// compareOrdered converts the result of a three-way comparison into
+// the result of the operator
+func compareOrdered(op string, cmp int) (bool, error) {
+	switch op {
+	case EqualOp:
+		return cmp == 0, nil
+	case NotEqualOp:
+		return cmp != 0, nil
+	case GreaterThanOp:
+		return cmp > 0, nil
+	case GreaterThanOrEqualToOp:
+		return cmp >= 0, nil
+	case LessThanOp:
+		return cmp < 0, nil
+	case LessThanOrEqualToOp:
+		return cmp <= 0, nil
+	default:
+		return false, fmt.Errorf(`operator %q can only be used against string values`, op)
+	}
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,162 @@
+package filter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cybozu-go/scim/filter"
+	"github.com/cybozu-go/scim/resource"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatch(t *testing.T) {
+	var b resource.Builder
+
+	lastModified, _ := time.Parse(time.RFC3339, "2011-05-13T04:42:34Z")
+	user := b.User().
+		ID("2819c223-7f76-453a-919d-413861904646").
+		ExternalID("bjensen").
+		UserName("bjensen@example.com").
+		Title("Tour Guide").
+		Active(true).
+		Name(b.Names().
+			FamilyName("O'Malley").
+			GivenName("Barbara").
+			MustBuild()).
+		Emails(
+			b.Email().
+				Value("bjensen@example.com").
+				Type("work").
+				Primary(true).
+				MustBuild(),
+			b.Email().
+				Value("babs@jensen.org").
+				Type("home").
+				MustBuild(),
+		).
+		Extension(resource.EnterpriseUserSchemaURI, b.EnterpriseUser().
+			EmployeeNumber("701984").
+			Manager(b.EnterpriseManager().
+				DisplayName("John Smith").
+				MustBuild()).
+			MustBuild()).
+		Meta(b.Meta().
+			ResourceType("User").
+			LastModified(lastModified).
+			MustBuild()).
+		MustBuild()
+
+	group := b.Group().
+		DisplayName("Tour Guides").
+		Members(
+			b.GroupMember().
+				Value("2819c223-7f76-453a-919d-413861904646").
+				MustBuild(),
+		).
+		MustBuild()
+
+	testcases := []struct {
+		Filter   string
+		Resource interface{}
+		Expected bool
+		Error    bool
+	}{
+		{Filter: `userName eq "bjensen@example.com"`, Resource: user, Expected: true},
+		{Filter: `userName eq "BJENSEN@EXAMPLE.COM"`, Resource: user, Expected: true},
+		{Filter: `USERNAME eq "bjensen@example.com"`, Resource: user, Expected: true},
+		{Filter: `userName ne "bjensen@example.com"`, Resource: user, Expected: false},
+		// externalId is caseExact
+		{Filter: `externalId eq "bjensen"`, Resource: user, Expected: true},
+		{Filter: `externalId eq "BJENSEN"`, Resource: user, Expected: false},
+		{Filter: `name.familyName co "O'Malley"`, Resource: user, Expected: true},
+		{Filter: `userName sw "J"`, Resource: user, Expected: false},
+		{Filter: `userName sw "B"`, Resource: user, Expected: true},
+		{Filter: `userName ew "example.COM"`, Resource: user, Expected: true},
+		{Filter: `title pr`, Resource: user, Expected: true},
+		{Filter: `nickName pr`, Resource: user, Expected: false},
+		{Filter: `nickName eq null`, Resource: user, Expected: true},
+		{Filter: `title ne null`, Resource: user, Expected: true},
+		{Filter: `active eq true`, Resource: user, Expected: true},
+		{Filter: `active eq false`, Resource: user, Expected: false},
+		{Filter: `active gt true`, Resource: user, Error: true},
+		{Filter: `meta.lastModified gt "2011-05-13T04:42:34Z"`, Resource: user, Expected: false},
+		{Filter: `meta.lastModified ge "2011-05-13T04:42:34Z"`, Resource: user, Expected: true},
+		{Filter: `meta.lastModified lt "2012-01-01T00:00:00+09:00"`, Resource: user, Expected: true},
+		{Filter: `meta.lastModified lt "yesterday"`, Resource: user, Error: true},
+		// multi-valued attributes match if any of the values match
+		{Filter: `emails.type eq "home"`, Resource: user, Expected: true},
+		{Filter: `emails.value co "jensen.org"`, Resource: user, Expected: true},
+		// complex attributes must be compared through their sub-attributes
+		{Filter: `emails co "jensen.org"`, Resource: user, Error: true},
+		{Filter: `emails co "jensen.org"`, Resource: map[string]interface{}{"emails": []interface{}{map[string]interface{}{"value": "bjensen@jensen.org"}}}, Error: true},
+		{Filter: `emails[type eq "work" and value co "@example.com"]`, Resource: user, Expected: true},
+		{Filter: `emails[type eq "home" and value co "@example.com"]`, Resource: user, Expected: false},
+		{Filter: `emails[primary eq true]`, Resource: user, Expected: true},
+		{Filter: `title pr and userType eq "Employee"`, Resource: user, Expected: false},
+		{Filter: `title pr or userType eq "Intern"`, Resource: user, Expected: true},
+		{Filter: `not (title pr)`, Resource: user, Expected: false},
+		{Filter: `userType eq "Employee" or (emails.type eq "work")`, Resource: user, Expected: true},
+		{Filter: `urn:ietf:params:scim:schemas:core:2.0:User:userName sw "bj"`, Resource: user, Expected: true},
+		{Filter: `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber eq "701984"`, Resource: user, Expected: true},
+		{Filter: `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.displayName sw "john"`, Resource: user, Expected: true},
+		{Filter: `displayName eq "tour guides"`, Resource: group, Expected: true},
+		{Filter: `members[value eq "2819c223-7f76-453a-919d-413861904646"]`, Resource: group, Expected: true},
+		{Filter: `members.value eq "902c246b-6245-4190-8e05-00816be7344a"`, Resource: group, Expected: false},
+		{Filter: `score gt 1.25`, Resource: map[string]interface{}{"score": 1.5}, Expected: true},
+		{Filter: `score lt 2`, Resource: map[string]interface{}{"score": 1.5}, Expected: true},
+		{Filter: `score eq 1.5e0`, Resource: map[string]interface{}{"score": 1.5}, Expected: true},
+		{Filter: `score ge -1.5`, Resource: map[string]interface{}{"score": -2}, Expected: false},
+		// dateTime attributes are compared as points in time, even if
+		// they are stored as strings
+		{
+			Filter: `meta.lastModified lt "2011-05-13T04:00:00Z"`,
+			Resource: map[string]interface{}{
+				"schemas": []interface{}{resource.UserSchemaURI},
+				"meta":    map[string]interface{}{"lastModified": "2011-05-13T10:42:34+09:00"},
+			},
+			Expected: true,
+		},
+		{
+			Filter: `x509Certificates.value eq "TUlJRE"`,
+			Resource: map[string]interface{}{
+				"schemas":          []interface{}{resource.UserSchemaURI},
+				"x509Certificates": []interface{}{map[string]interface{}{"value": "MIIDQzCCAqygAwIBAgICEAAwDQYJKoZIhvcNAQEFBQAw"}},
+			},
+			Expected: false,
+		},
+		{
+			Filter: `x509Certificates.value co "MIID"`,
+			Resource: map[string]interface{}{
+				"schemas":          []interface{}{resource.UserSchemaURI},
+				"x509Certificates": []interface{}{map[string]interface{}{"value": "MIIDQzCCAqygAwIBAgICEAAwDQYJKoZIhvcNAQEFBQAw"}},
+			},
+			Error: true,
+		},
+		{
+			Filter: `emails[type eq "work"] and department eq "sales"`,
+			Resource: map[string]interface{}{
+				"department": "Sales",
+				"emails": []interface{}{
+					map[string]interface{}{"type": "work", "value": "x@example.com"},
+				},
+			},
+			Expected: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Filter, func(t *testing.T) {
+			expr, err := filter.Parse(tc.Filter)
+			require.NoError(t, err, `filter.Parse should succeed`)
+
+			ok, err := filter.Match(expr, tc.Resource)
+			if tc.Error {
+				require.Error(t, err, `filter.Match should fail`)
+				return
+			}
+			require.NoError(t, err, `filter.Match should succeed`)
+			require.Equal(t, tc.Expected, ok, `result should match`)
+		})
+	}
+}
@@ -0,0 +1,426 @@
+// Package mongoquery translates SCIM filters into query documents in
+// the MongoDB query language.
+//
+// The query documents are plain Go maps, which can be passed to MongoDB
+// drivers as is, or converted to the driver's document types.
+package mongoquery
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/cybozu-go/scim/filter"
+	"github.com/cybozu-go/scim/resource"
+	"github.com/cybozu-go/scim/schema"
+)
+
+// Translate translates the filter into a MongoDB query document.
+//
+// Resources are expected to be stored as their JSON representation,
+// so the attribute `name.familyName` is queried using the field
+// `name.familyName`. Attributes of schema extensions are stored under
+// the schema URI, but as URIs such as
+// `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User` contain
+// dots, they cannot be expressed in dot notation. Use
+// `mongoquery.WithFields()` to specify the fields for such attributes.
+//
+// The exception is dateTime attributes, whose values must be stored as
+// BSON dates rather than strings: strings with different time zone
+// offsets or fractional seconds are not ordered chronologically, and
+// BSON never considers strings and dates equal or ordered against each
+// other. `meta.created` and `meta.lastModified` are always treated as
+// dateTime attributes, and the other dateTime attributes are known
+// when the schema is specified using `mongoquery.WithSchema()`.
+//
+// Filters are translated as follows:
+//
+//   - Comparisons use `$eq`, `$ne`, `$gt`, `$gte`, `$lt` and `$lte`.
+//     `eq` and `ne` against strings that are not case-exact use `$regex`
+//     instead, with the `i` option.
+//   - `co`, `sw` and `ew` use `$regex` with the value escaped.
+//   - Values of dateTime attributes are converted into `time.Time` in
+//     UTC, so that they are compared against the stored dates.
+//   - `pr` uses `$exists`, and excludes null values.
+//   - `and`, `or` and `not` use `$and`, `$or` and `$nor`.
+//   - Value paths against multi-valued attributes use `$elemMatch`, so
+//     that all conditions are applied to the same value.
+func Translate(expr filter.Expr, options ...TranslateOption) (map[string]interface{}, error) {
+	var t translator
+
+	//nolint:forcetypeassert
+	for _, option := range options {
+		switch option.Ident() {
+		case identSchema{}:
+			t.schema = option.Value().(*resource.Schema)
+		case identFields{}:
+			fields := option.Value().(map[string]string)
+			t.fields = make(map[string]string, len(fields))
+			for path, field := range fields {
+				t.fields[strings.ToLower(path)] = field
+			}
+		}
+	}
+
+	sc := &scope{}
+	if t.schema != nil {
+		if err := filter.Validate(expr, t.schema); err != nil {
+			return nil, err
+		}
+		sc.attrs = t.schema.Attributes()
+	}
+	return t.translate(sc, expr)
+}
+
+type translator struct {
+	schema *resource.Schema
+	fields map[string]string
+}
+
+// scope describes where the attributes referred to by the filter live
+type scope struct {
+	// path is the attribute path of the enclosing attribute, for filters
+	// inside a value path
+	path string
+	// field is the field of the enclosing attribute, for filters inside
+	// a value path
+	field string
+	// elem is true if field names are relative to the values of a
+	// multi-valued attribute (i.e. inside `$elemMatch`)
+	elem bool
+	// attrs is the list of attributes that may be referred to, if the
+	// schema is known
+	attrs []*resource.SchemaAttribute
+}
+
+func (t *translator) translate(sc *scope, v filter.Expr) (map[string]interface{}, error) {
+	switch v := v.(type) {
+	case filter.PresenceExpr:
+		target, err := t.resolve(sc, v.Attr())
+		if err != nil {
+			return nil, fmt.Errorf(`left hand side of %q is not valid: %w`, v.Operator(), err)
+		}
+		return doc(target.field, doc(`$exists`, true, `$ne`, nil)), nil
+	case filter.CompareExpr:
+		return t.translateComparison(sc, v.LHE(), v.Operator(), v.RHE())
+	case filter.RegexExpr:
+		return t.translateComparison(sc, v.LHE(), v.Operator(), v.Value())
+	case filter.LogExpr:
+		return t.translateLogExpr(sc, v)
+	case filter.ParenExpr:
+		sub, err := t.translate(sc, v.SubExpr())
+		if err != nil {
+			return nil, err
+		}
+		switch v.Operator() {
+		case "":
+			return sub, nil
+		case filter.NotOp:
+			return doc(`$nor`, []interface{}{sub}), nil
+		default:
+			return nil, fmt.Errorf(`unhandled grouping operator %q`, v.Operator())
+		}
+	case filter.ValuePath:
+		return t.translateValuePath(sc, v)
+	default:
+		return nil, fmt.Errorf(`unhandled expression type: %T`, v)
+	}
+}
+
+// target is a resolved attribute path
+type target struct {
+	// path is the attribute path, using the names in the schema if
+	// it is known
+	path string
+	// field is the field in dot notation
+	field string
+	// attr is the attribute that was referred to, if the schema is known
+	attr *resource.SchemaAttribute
+	// parent is the attribute that was referred to without the
+	// sub-attribute, if the schema is known
+	parent *resource.SchemaAttribute
+}
+
+// commonDateTimes lists the dateTime attributes that are common to all
+// resources, which are known without a schema
+var commonDateTimes = map[string]struct{}{
+	`meta.created`:      {},
+	`meta.lastmodified`: {},
+}
+
+// isDateTime reports whether the target is a dateTime attribute
+func (t *target) isDateTime() bool {
+	if t.attr != nil {
+		return t.attr.Type() == resource.DateTime
+	}
+	_, ok := commonDateTimes[strings.ToLower(t.path)]
+	return ok
+}
+
+func (t *translator) resolve(sc *scope, v filter.Expr) (*target, error) {
+	ident, ok := v.(filter.IdentifierExpr)
+	if !ok {
+		return nil, fmt.Errorf(`expected identifier, got %T`, v)
+	}
+
+	uri := ident.SchemaURI()
+	if uri != "" && sc.path != "" {
+		return nil, fmt.Errorf(`schema URI cannot be used inside a value path: %q`, ident.Lit())
+	}
+	name, sub := ident.Attribute(), ident.SubAttribute()
+
+	var target target
+	if sc.attrs != nil {
+		attrs := sc.attrs
+		if uri != "" && strings.EqualFold(uri, t.schema.ID()) {
+			uri = ""
+		} else if uri != "" {
+			ext, ok := schema.Get(uri)
+			if !ok {
+				return nil, fmt.Errorf(`unknown schema %q`, uri)
+			}
+			uri = ext.ID()
+			attrs = ext.Attributes()
+		}
+
+		if attr := schema.FindAttribute(attrs, name); attr != nil {
+			target.parent = attr
+			target.attr = attr
+			name = attr.Name()
+			if sub != "" {
+				target.attr = schema.FindAttribute(attr.SubAttributes(), sub)
+				if target.attr == nil {
+					return nil, fmt.Errorf(`unknown attribute %q`, ident.Lit())
+				}
+				sub = target.attr.Name()
+			}
+		} else if uri != "" || !strings.EqualFold(name, `schemas`) || sub != "" {
+			return nil, fmt.Errorf(`unknown attribute %q`, ident.Lit())
+		}
+	}
+
+	target.path = name
+	if sub != "" {
+		target.path += `.` + sub
+	}
+	if sc.path != "" {
+		target.path = sc.path + `.` + target.path
+	}
+	if uri != "" {
+		target.path = uri + `:` + target.path
+	}
+
+	field, err := t.field(target.path)
+	if err != nil {
+		return nil, err
+	}
+	if sc.elem {
+		// fields inside `$elemMatch` are relative to each value
+		prefix := sc.field + `.`
+		if !strings.HasPrefix(field, prefix) {
+			return nil, fmt.Errorf(`field %q for %q is not inside %q`, field, target.path, sc.field)
+		}
+		field = strings.TrimPrefix(field, prefix)
+	}
+	target.field = field
+	return &target, nil
+}
+
+// field returns the field in dot notation for the attribute path
+func (t *translator) field(path string) (string, error) {
+	if field, ok := t.fields[strings.ToLower(path)]; ok {
+		return field, nil
+	}
+
+	// the sub-attribute of a mapped attribute is stored under the
+	// mapped field
+	if i := strings.LastIndexByte(path, '.'); i > strings.LastIndexByte(path, ':') {
+		if field, ok := t.fields[strings.ToLower(path[:i])]; ok {
+			return field + path[i:], nil
+		}
+	}
+
+	if i := strings.LastIndexByte(path, ':'); i >= 0 {
+		if strings.ContainsRune(path[:i], '.') {
+			return "", fmt.Errorf(`field for %q cannot be expressed in dot notation, and must be specified using mongoquery.WithFields()`, path)
+		}
+		return path[:i] + `.` + path[i+1:], nil
+	}
+	return path, nil
+}
+
+func (t *translator) translateComparison(sc *scope, lhe filter.Expr, op string, rhe interface{}) (map[string]interface{}, error) {
+	target, err := t.resolve(sc, lhe)
+	if err != nil {
+		return nil, fmt.Errorf(`left hand side of %q is not valid: %w`, op, err)
+	}
+
+	value, err := literal(rhe)
+	if err != nil {
+		return nil, fmt.Errorf(`right hand side of %q is not valid: %w`, op, err)
+	}
+
+	// dateTime values are stored as dates, in UTC
+	if s, ok := value.(string); ok && target.isDateTime() {
+		tm, err := resource.ParseDateTime(s)
+		if err != nil {
+			return nil, fmt.Errorf(`failed to parse %q as dateTime: %w`, s, err)
+		}
+		value = tm.UTC()
+	}
+
+	if s, ok := value.(string); ok {
+		caseExact := target.attr != nil && (target.attr.CaseExact() || target.attr.Type() == resource.Binary)
+		pattern := regexp.QuoteMeta(s)
+		switch op {
+		case filter.ContainsOp:
+		case filter.StartsWithOp:
+			pattern = `^` + pattern
+		case filter.EndsWithOp:
+			pattern = pattern + `$`
+		case filter.EqualOp, filter.NotEqualOp:
+			if caseExact {
+				pattern = ""
+			} else {
+				pattern = `^` + pattern + `$`
+			}
+		default:
+			pattern = ""
+		}
+
+		if pattern != "" {
+			re := doc(`$regex`, pattern)
+			if !caseExact {
+				re[`$options`] = `i`
+			}
+			if op == filter.NotEqualOp {
+				return doc(target.field, doc(`$exists`, true, `$not`, re)), nil
+			}
+			return doc(target.field, re), nil
+		}
+	}
+
+	switch op {
+	case filter.EqualOp:
+		return doc(target.field, doc(`$eq`, value)), nil
+	case filter.NotEqualOp:
+		if value == nil {
+			return doc(target.field, doc(`$ne`, nil)), nil
+		}
+		return doc(target.field, doc(`$exists`, true, `$ne`, value)), nil
+	case filter.GreaterThanOp:
+		return doc(target.field, doc(`$gt`, value)), nil
+	case filter.GreaterThanOrEqualToOp:
+		return doc(target.field, doc(`$gte`, value)), nil
+	case filter.LessThanOp:
+		return doc(target.field, doc(`$lt`, value)), nil
+	case filter.LessThanOrEqualToOp:
+		return doc(target.field, doc(`$lte`, value)), nil
+	default:
+		return nil, fmt.Errorf(`operator %q cannot be used against %T values`, op, value)
+	}
+}
+
+// literal converts the comparison value of a filter into a Go value
+func literal(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case filter.AttrValueExpr:
+		return v.Lit(), nil
+	case filter.NumberExpr:
+		return v.Lit(), nil
+	case filter.DecimalExpr:
+		return v.Lit(), nil
+	case filter.BoolExpr:
+		return v.Lit(), nil
+	case filter.IdentifierExpr:
+		if v.Lit() == filter.Null {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(`unexpected identifier %q`, v.Lit())
+	default:
+		return nil, fmt.Errorf(`unhandled value type: %T`, v)
+	}
+}
+
+func (t *translator) translateLogExpr(sc *scope, v filter.LogExpr) (map[string]interface{}, error) {
+	var key string
+	switch v.Operator() {
+	case filter.AndOp:
+		key = `$and`
+	case filter.OrOp:
+		key = `$or`
+	default:
+		return nil, fmt.Errorf(`unhandled logical operator %q`, v.Operator())
+	}
+
+	// chains of the same operator are written as a single list
+	var list []interface{}
+	var operands func(filter.Expr) error
+	operands = func(e filter.Expr) error {
+		if l, ok := e.(filter.LogExpr); ok && l.Operator() == v.Operator() {
+			if err := operands(l.LHE()); err != nil {
+				return err
+			}
+			return operands(l.RHS())
+		}
+		sub, err := t.translate(sc, e)
+		if err != nil {
+			return fmt.Errorf(`failed to translate operand of %q: %w`, v.Operator(), err)
+		}
+		list = append(list, sub)
+		return nil
+	}
+	if err := operands(v); err != nil {
+		return nil, err
+	}
+	return doc(key, list), nil
+}
+
+func (t *translator) translateValuePath(sc *scope, v filter.ValuePath) (map[string]interface{}, error) {
+	if sc.path != "" {
+		return nil, fmt.Errorf(`value paths cannot be nested`)
+	}
+
+	ident, ok := v.ParentAttr().(filter.IdentifierExpr)
+	if !ok || ident.SubAttribute() != "" {
+		return nil, fmt.Errorf(`parent attribute of value path is not valid: %v`, v.ParentAttr())
+	}
+	target, err := t.resolve(sc, ident)
+	if err != nil {
+		return nil, fmt.Errorf(`parent attribute of value path is not valid: %w`, err)
+	}
+
+	if v.SubExpr() == nil {
+		return doc(target.field, doc(`$exists`, true, `$ne`, nil)), nil
+	}
+
+	sub := &scope{
+		path:  target.path,
+		field: target.field,
+		elem:  target.parent == nil || target.parent.MultiValued(),
+	}
+	if target.parent != nil {
+		sub.attrs = target.parent.SubAttributes()
+	}
+
+	query, err := t.translate(sub, v.SubExpr())
+	if err != nil {
+		return nil, fmt.Errorf(`failed to translate filter for value path: %w`, err)
+	}
+	if sub.elem {
+		return doc(target.field, doc(`$elemMatch`, query)), nil
+	}
+	return query, nil
+}
+
+// doc creates a document from the list of keys and values
+func doc(kv ...interface{}) map[string]interface{} {
+	m := make(map[string]interface{}, len(kv)/2)
+	//nolint:forcetypeassert
+	for i := 0; i < len(kv); i += 2 {
+		m[kv[i].(string)] = kv[i+1]
+	}
+	return m
+}
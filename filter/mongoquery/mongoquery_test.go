@@ -0,0 +1,206 @@
+package mongoquery_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cybozu-go/scim/filter"
+	"github.com/cybozu-go/scim/filter/mongoquery"
+	"github.com/cybozu-go/scim/resource"
+	"github.com/cybozu-go/scim/schema"
+	"github.com/stretchr/testify/require"
+)
+
+type M = map[string]interface{}
+type A = []interface{}
+
+func TestTranslate(t *testing.T) {
+	userSchema, ok := schema.Get(resource.UserSchemaURI)
+	require.True(t, ok, `schema.Get should succeed`)
+
+	withSchema := []mongoquery.TranslateOption{mongoquery.WithSchema(userSchema)}
+	testcases := []struct {
+		Filter   string
+		Options  []mongoquery.TranslateOption
+		Expected map[string]interface{}
+		Error    bool
+	}{
+		{
+			Filter:   `userName eq "bjensen"`,
+			Expected: M{`userName`: M{`$regex`: `^bjensen$`, `$options`: `i`}},
+		},
+		{
+			Filter:   `USERNAME eq "b.jensen"`,
+			Options:  withSchema,
+			Expected: M{`userName`: M{`$regex`: `^b\.jensen$`, `$options`: `i`}},
+		},
+		{
+			Filter:   `id eq "2819c223-7f76-453a-919d-413861904646"`,
+			Options:  withSchema,
+			Expected: M{`id`: M{`$regex`: `^2819c223-7f76-453a-919d-413861904646$`, `$options`: `i`}},
+		},
+		{
+			Filter:   `meta.version eq "W/\"3694e05e9dff591\""`,
+			Options:  withSchema,
+			Expected: M{`meta.version`: M{`$eq`: `W/"3694e05e9dff591"`}},
+		},
+		{
+			Filter:   `meta.version ne "W/\"3694e05e9dff591\""`,
+			Options:  withSchema,
+			Expected: M{`meta.version`: M{`$exists`: true, `$ne`: `W/"3694e05e9dff591"`}},
+		},
+		{
+			Filter:   `title ne "Tour Guide"`,
+			Options:  withSchema,
+			Expected: M{`title`: M{`$exists`: true, `$not`: M{`$regex`: `^Tour Guide$`, `$options`: `i`}}},
+		},
+		{
+			Filter:   `name.familyName co "O'Malley (Jr.)"`,
+			Options:  withSchema,
+			Expected: M{`name.familyName`: M{`$regex`: `O'Malley \(Jr\.\)`, `$options`: `i`}},
+		},
+		{
+			Filter:   `userName sw "J*"`,
+			Expected: M{`userName`: M{`$regex`: `^J\*`, `$options`: `i`}},
+		},
+		{
+			Filter:   `userName ew "sen"`,
+			Expected: M{`userName`: M{`$regex`: `sen$`, `$options`: `i`}},
+		},
+		{
+			Filter:   `title pr`,
+			Expected: M{`title`: M{`$exists`: true, `$ne`: nil}},
+		},
+		{
+			Filter:   `nickName eq null`,
+			Expected: M{`nickName`: M{`$eq`: nil}},
+		},
+		{
+			Filter:   `nickName ne null`,
+			Expected: M{`nickName`: M{`$ne`: nil}},
+		},
+		{
+			Filter:   `active eq true`,
+			Options:  withSchema,
+			Expected: M{`active`: M{`$eq`: true}},
+		},
+		{
+			Filter:   `meta.lastModified gt "2011-05-13T04:42:34Z"`,
+			Options:  withSchema,
+			Expected: M{`meta.lastModified`: M{`$gt`: time.Date(2011, 5, 13, 4, 42, 34, 0, time.UTC)}},
+		},
+		{
+			Filter:  `meta.created ge "2011-05-13T13:42:34+09:00" and meta.lastModified lt "2011-05-12T23:42:34.5-0500"`,
+			Options: withSchema,
+			Expected: M{`$and`: A{
+				M{`meta.created`: M{`$gte`: time.Date(2011, 5, 13, 4, 42, 34, 0, time.UTC)}},
+				M{`meta.lastModified`: M{`$lt`: time.Date(2011, 5, 13, 4, 42, 34, 500000000, time.UTC)}},
+			}},
+		},
+		{
+			// meta.lastModified is known to be a dateTime without a schema
+			Filter:   `meta.lastModified gt "2011-05-13T13:42:34+09:00"`,
+			Expected: M{`meta.lastModified`: M{`$gt`: time.Date(2011, 5, 13, 4, 42, 34, 0, time.UTC)}},
+		},
+		{
+			Filter: `meta.lastModified eq "yesterday"`,
+			Error:  true,
+		},
+		{
+			Filter:   `age ge 18 and age lt 65.5`,
+			Expected: M{`$and`: A{M{`age`: M{`$gte`: 18}}, M{`age`: M{`$lt`: 65.5}}}},
+		},
+		{
+			Filter:  `title pr and userType eq "Employee" and active eq true`,
+			Options: withSchema,
+			Expected: M{`$and`: A{
+				M{`title`: M{`$exists`: true, `$ne`: nil}},
+				M{`userType`: M{`$regex`: `^Employee$`, `$options`: `i`}},
+				M{`active`: M{`$eq`: true}},
+			}},
+		},
+		{
+			Filter:  `title pr and (userType eq "Employee" or userType eq "Intern")`,
+			Options: withSchema,
+			Expected: M{`$and`: A{
+				M{`title`: M{`$exists`: true, `$ne`: nil}},
+				M{`$or`: A{
+					M{`userType`: M{`$regex`: `^Employee$`, `$options`: `i`}},
+					M{`userType`: M{`$regex`: `^Intern$`, `$options`: `i`}},
+				}},
+			}},
+		},
+		{
+			Filter:   `not (title pr)`,
+			Expected: M{`$nor`: A{M{`title`: M{`$exists`: true, `$ne`: nil}}}},
+		},
+		{
+			Filter:  `emails[type eq "work" and value co "@example.com"]`,
+			Options: withSchema,
+			Expected: M{`emails`: M{`$elemMatch`: M{`$and`: A{
+				M{`type`: M{`$regex`: `^work$`, `$options`: `i`}},
+				M{`value`: M{`$regex`: `@example\.com`, `$options`: `i`}},
+			}}}},
+		},
+		{
+			Filter:   `emails[primary eq true]`,
+			Expected: M{`emails`: M{`$elemMatch`: M{`primary`: M{`$eq`: true}}}},
+		},
+		{
+			Filter:   `name[familyName eq "Jensen"]`,
+			Options:  withSchema,
+			Expected: M{`name.familyName`: M{`$regex`: `^Jensen$`, `$options`: `i`}},
+		},
+		{
+			Filter: `emails[type eq "work"]`,
+			Options: []mongoquery.TranslateOption{
+				mongoquery.WithFields(map[string]string{`emails`: `contact.emails`}),
+			},
+			Expected: M{`contact.emails`: M{`$elemMatch`: M{`type`: M{`$regex`: `^work$`, `$options`: `i`}}}},
+		},
+		{
+			Filter: `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber eq "701984"`,
+			Options: []mongoquery.TranslateOption{
+				mongoquery.WithSchema(userSchema),
+				mongoquery.WithFields(map[string]string{
+					`urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber`: `enterprise.employeeNumber`,
+				}),
+			},
+			Expected: M{`enterprise.employeeNumber`: M{`$regex`: `^701984$`, `$options`: `i`}},
+		},
+		{
+			Filter: `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber eq "701984"`,
+			Error:  true,
+		},
+		{
+			Filter:  `emails[vaule co "@example.com"]`,
+			Options: withSchema,
+			Error:   true,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Filter, func(t *testing.T) {
+			expr, err := filter.Parse(tc.Filter)
+			require.NoError(t, err, `filter.Parse should succeed`)
+
+			query, err := mongoquery.Translate(expr, tc.Options...)
+			if tc.Error {
+				require.Error(t, err, `Translate should fail`)
+				return
+			}
+			require.NoError(t, err, `Translate should succeed`)
+			require.Equal(t, tc.Expected, query, `query should match`)
+		})
+	}
+
+	t.Run("schema validation", func(t *testing.T) {
+		expr, err := filter.Parse(`active gt true`)
+		require.NoError(t, err, `filter.Parse should succeed`)
+		_, err = mongoquery.Translate(expr, mongoquery.WithSchema(userSchema))
+		var serr *resource.Error
+		require.True(t, errors.As(err, &serr), `error should be a *resource.Error`)
+	})
+}
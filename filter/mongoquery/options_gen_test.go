@@ -1,6 +1,6 @@
 // This file is auto-generated by tools/cmd/genoptions/main.go. DO NOT EDIT
 
-package sql
+package mongoquery
 
 import (
 	"testing"
@@ -9,6 +9,6 @@ import (
 )
 
 func TestOptionIdent(t *testing.T) {
-	require.Equal(t, "WithColumnMapper", identColumnMapper{}.String())
-	require.Equal(t, "WithDialect", identDialect{}.String())
+	require.Equal(t, "WithFields", identFields{}.String())
+	require.Equal(t, "WithSchema", identSchema{}.String())
 }
@@ -0,0 +1,114 @@
+package filter
+
+import (
+	"sort"
+	"strings"
+)
+
+// Normalize rewrites the filter expression into a canonical form, so
+// that equivalent filters result in identical expressions. The
+// following transformations are applied:
+//
+//   - Grouping parentheses are removed. `filter.Format()` adds them
+//     back where they are required.
+//   - Chains of logical expressions using the same operator are
+//     flattened, duplicate operands are removed, and the operands are
+//     sorted, as both `and` and `or` are commutative.
+//   - `not` is pushed down into logical expressions using De Morgan's
+//     laws, and double negations are removed. Note that `not` is never
+//     applied to comparisons directly, as `not (x eq "a")` is not
+//     equivalent to `x ne "a"` for multi-valued or absent attributes.
+//   - Operators are converted to lower case.
+//
+// Attribute names are left untouched. The original expression is not
+// modified.
+func Normalize(e Expr) Expr {
+	switch e := e.(type) {
+	case PresenceExpr:
+		return NewPresenceExpr(e.Attr(), strings.ToLower(e.Operator()))
+	case CompareExpr:
+		return NewCompareExpr(e.LHE(), strings.ToLower(e.Operator()), e.RHE())
+	case RegexExpr:
+		return NewRegexExpr(e.LHE(), strings.ToLower(e.Operator()), e.Value())
+	case LogExpr:
+		op := strings.ToLower(e.Operator())
+		return normalizeLogExpr(op, []Expr{Normalize(e.LHE()), Normalize(e.RHS())})
+	case ParenExpr:
+		sub := Normalize(e.SubExpr())
+		if strings.ToLower(e.Operator()) == NotOp {
+			return negate(sub)
+		}
+		return sub
+	case ValuePath:
+		var sub Expr
+		if e.SubExpr() != nil {
+			sub = Normalize(e.SubExpr())
+		}
+		return NewValuePath(e.ParentAttr(), e.SubAttr(), sub)
+	default:
+		return e
+	}
+}
+
+// negate returns the negation of the normalized expression
+func negate(e Expr) Expr {
+	switch e := e.(type) {
+	case ParenExpr:
+		// the only ParenExpr in a normalized expression is `not`
+		return e.SubExpr()
+	case LogExpr:
+		op := AndOp
+		if e.Operator() == AndOp {
+			op = OrOp
+		}
+
+		operands := flattenLogExpr(e.Operator(), e, nil)
+		for i, operand := range operands {
+			operands[i] = negate(operand)
+		}
+		return normalizeLogExpr(op, operands)
+	default:
+		return NewParenExpr(NotOp, e)
+	}
+}
+
+// normalizeLogExpr combines the normalized operands using op
+func normalizeLogExpr(op string, operands []Expr) Expr {
+	var list []Expr
+	for _, operand := range operands {
+		list = flattenLogExpr(op, operand, list)
+	}
+
+	// remove duplicates, and sort using the canonical representation
+	seen := make(map[string]struct{})
+	keys := make(map[Expr]string)
+	uniq := list[:0]
+	for _, operand := range list {
+		key := Format(operand)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		keys[operand] = key
+		uniq = append(uniq, operand)
+	}
+	sort.SliceStable(uniq, func(i, j int) bool {
+		return keys[uniq[i]] < keys[uniq[j]]
+	})
+
+	e := uniq[0]
+	for _, operand := range uniq[1:] {
+		e = NewLogExpr(e, op, operand)
+	}
+	return e
+}
+
+// flattenLogExpr appends the operands of a chain of logical expressions
+// using op to list
+func flattenLogExpr(op string, e Expr, list []Expr) []Expr {
+	if l, ok := e.(LogExpr); ok && l.Operator() == op {
+		list = flattenLogExpr(op, l.LHE(), list)
+		return flattenLogExpr(op, l.RHS(), list)
+	}
+	return append(list, e)
+}
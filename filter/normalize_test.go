@@ -0,0 +1,72 @@
+package filter_test
+
+import (
+	"testing"
+
+	"github.com/cybozu-go/scim/filter"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	testcases := []struct {
+		Filter   string
+		Expected string
+	}{
+		{Filter: `userName eq "bjensen"`, Expected: `userName eq "bjensen"`},
+		{Filter: `((userName eq "bjensen"))`, Expected: `userName eq "bjensen"`},
+		{Filter: `b pr and a pr`, Expected: `a pr and b pr`},
+		{Filter: `c pr and (b pr and a pr)`, Expected: `a pr and b pr and c pr`},
+		{Filter: `(c pr and b pr) and (a pr and c pr)`, Expected: `a pr and b pr and c pr`},
+		{Filter: `b pr or a pr or b pr`, Expected: `a pr or b pr`},
+		{Filter: `d pr and (c pr or b pr)`, Expected: `(b pr or c pr) and d pr`},
+		{Filter: `not (not (title pr))`, Expected: `title pr`},
+		{Filter: `not (b pr and a pr)`, Expected: `not (a pr) or not (b pr)`},
+		{Filter: `not (a pr and not (b pr or c pr))`, Expected: `b pr or c pr or not (a pr)`},
+		{Filter: `not (userType eq "Employee")`, Expected: `not (userType eq "Employee")`},
+		{Filter: `emails[value co "@example.com" and type eq "work"]`, Expected: `emails[type eq "work" and value co "@example.com"]`},
+		{Filter: `not (emails[type eq "work"])`, Expected: `not (emails[type eq "work"])`},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Filter, func(t *testing.T) {
+			expr, err := filter.Parse(tc.Filter)
+			require.NoError(t, err, `filter.Parse should succeed`)
+			require.Equal(t, tc.Expected, filter.Format(filter.Normalize(expr)), `normalized expressions should match`)
+		})
+	}
+
+	t.Run("equivalent filters", func(t *testing.T) {
+		list := []string{
+			`title pr and (userType eq "Employee" or userType eq "Intern")`,
+			`(userType eq "Intern" or userType eq "Employee") and title pr`,
+			`not (not (title pr)) and userType eq "Employee" or userType eq "Intern"`,
+			`not (not (title pr) or not (userType eq "Intern" or userType eq "Employee"))`,
+		}
+
+		var expected filter.Expr
+		for _, s := range list {
+			expr, err := filter.Parse(s)
+			require.NoError(t, err, `filter.Parse should succeed`)
+			normalized := filter.Normalize(expr)
+			if expected == nil {
+				expected = normalized
+				continue
+			}
+			require.Equal(t, expected, normalized, `%q should normalize to the same expression`, s)
+		}
+	})
+
+	t.Run("operators are converted to lower case", func(t *testing.T) {
+		expr := filter.NewLogExpr(
+			filter.NewPresenceExpr(filter.NewIdentifierExpr(`title`), `PR`),
+			`AND`,
+			filter.NewRegexExpr(filter.NewIdentifierExpr(`userName`), `SW`, `J`),
+		)
+		normalized, ok := filter.Normalize(expr).(filter.LogExpr)
+		require.True(t, ok, `normalized expression should be a LogExpr`)
+		require.Equal(t, filter.AndOp, normalized.Operator())
+		require.Equal(t, filter.PresenceOp, normalized.LHE().(filter.PresenceExpr).Operator())
+		require.Equal(t, filter.StartsWithOp, normalized.RHS().(filter.RegexExpr).Operator())
+	})
+}
@@ -3,11 +3,24 @@
 package filter
 
 import (
+	"github.com/cybozu-go/scim/resource"
 	"github.com/lestrrat-go/option"
 )
 
 type Option = option.Interface
 
+// MatchOption describes an Option that can be passed to `Match()`.
+type MatchOption interface {
+	Option
+	matchOption()
+}
+
+type matchOption struct {
+	Option
+}
+
+func (*matchOption) matchOption() {}
+
 // ParseOption describes an Option that can be passed to `Parse()`.
 type ParseOption interface {
 	Option
@@ -20,14 +33,60 @@ type parseOption struct {
 
 func (*parseOption) parseOption() {}
 
+type identMaxDepth struct{}
+type identMaxLength struct{}
+type identMaxNodes struct{}
 type identPatchExpression struct{}
+type identSchema struct{}
+
+func (identMaxDepth) String() string {
+	return "WithMaxDepth"
+}
+
+func (identMaxLength) String() string {
+	return "WithMaxLength"
+}
+
+func (identMaxNodes) String() string {
+	return "WithMaxNodes"
+}
 
 func (identPatchExpression) String() string {
 	return "WithPatchExpression"
 }
 
+func (identSchema) String() string {
+	return "WithSchema"
+}
+
+// WithMaxDepth specifies how deeply parentheses and value path
+// brackets may be nested. Zero means that there is no limit
+func WithMaxDepth(v int) ParseOption {
+	return &parseOption{option.New(identMaxDepth{}, v)}
+}
+
+// WithMaxLength specifies the maximum length of the filter in bytes.
+// Zero means that there is no limit
+func WithMaxLength(v int) ParseOption {
+	return &parseOption{option.New(identMaxLength{}, v)}
+}
+
+// WithMaxNodes specifies the maximum number of expressions
+// (comparisons, logical operators, groupings and value paths)
+// that the filter may contain. Zero means that there is no limit
+func WithMaxNodes(v int) ParseOption {
+	return &parseOption{option.New(identMaxNodes{}, v)}
+}
+
 // WithPatchExpression specifies that the parser accept expressions
 // that can be used for PATCH `path` fields
 func WithPatchExpression(v bool) ParseOption {
 	return &parseOption{option.New(identPatchExpression{}, v)}
 }
+
+// WithSchema specifies the schema that describes the resource being
+// evaluated. By default the schema is deduced from the type of the
+// resource, or from its `schemas` attribute
+func WithSchema(v *resource.Schema) MatchOption {
+	return &matchOption{option.New(identSchema{}, v)}
+}
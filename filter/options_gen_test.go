@@ -9,5 +9,9 @@ import (
 )
 
 func TestOptionIdent(t *testing.T) {
+	require.Equal(t, "WithMaxDepth", identMaxDepth{}.String())
+	require.Equal(t, "WithMaxLength", identMaxLength{}.String())
+	require.Equal(t, "WithMaxNodes", identMaxNodes{}.String())
 	require.Equal(t, "WithPatchExpression", identPatchExpression{}.String())
+	require.Equal(t, "WithSchema", identSchema{}.String())
 }
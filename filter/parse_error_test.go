@@ -0,0 +1,104 @@
+package filter_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cybozu-go/scim/filter"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseError(t *testing.T) {
+	testcases := []struct {
+		Filter   string
+		Options  []filter.ParseOption
+		Line     int
+		Column   int
+		Token    string
+		Expected []string
+		Message  string
+	}{
+		{
+			Filter:   `userName eq "bjensen" and and`,
+			Line:     1,
+			Column:   27,
+			Token:    `and`,
+			Expected: []string{`attribute path`, `"not"`, `"("`},
+			Message:  `unexpected "and" at column 27, expected attribute path, "not", or "("`,
+		},
+		{
+			Filter:   `userName eq`,
+			Line:     1,
+			Column:   12,
+			Expected: []string{`string`, `number`, `"true"`, `"false"`, `"null"`},
+			Message:  `unexpected end of input at column 12, expected string, number, "true", "false", or "null"`,
+		},
+		{
+			Filter:   `title pr )`,
+			Line:     1,
+			Column:   10,
+			Token:    `)`,
+			Expected: []string{`"and"`, `"or"`, `end of input`},
+			Message:  `unexpected ")" at column 10, expected "and", "or", or end of input`,
+		},
+		{
+			Filter:   `userName co true`,
+			Line:     1,
+			Column:   13,
+			Token:    `true`,
+			Expected: []string{`string`},
+			Message:  `unexpected "true" at column 13, expected string`,
+		},
+		{
+			Filter:   "title pr and\n  (userType eq",
+			Line:     2,
+			Column:   15,
+			Expected: []string{`string`, `number`, `"true"`, `"false"`, `"null"`},
+			Message:  `unexpected end of input at line 2, column 15, expected string, number, "true", "false", or "null"`,
+		},
+		{
+			Filter:  `userName eq "bjensen`,
+			Line:    1,
+			Column:  13,
+			Message: `unterminated string at column 13`,
+		},
+		{
+			Filter:  `userName@example eq "bjensen"`,
+			Line:    1,
+			Column:  9,
+			Message: `invalid character '@' at column 9`,
+		},
+		{
+			Filter:  `foo:userName eq "bjensen"`,
+			Line:    1,
+			Column:  1,
+			Token:   `foo:userName`,
+			Message: `attribute path "foo:userName" contains a colon, but is not prefixed by a schema URI at column 1`,
+		},
+		{
+			Filter:   `emails[type eq "work"] pr`,
+			Options:  []filter.ParseOption{filter.WithPatchExpression(true)},
+			Line:     1,
+			Column:   24,
+			Token:    `pr`,
+			Expected: []string{`"."`, `end of input`},
+			Message:  `unexpected "pr" at column 24, expected "." or end of input`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Filter, func(t *testing.T) {
+			_, err := filter.Parse(tc.Filter, tc.Options...)
+			require.Error(t, err, `filter.Parse should fail`)
+
+			var perr *filter.ParseError
+			require.True(t, errors.As(err, &perr), `error should be a *filter.ParseError`)
+			require.Equal(t, tc.Line, perr.Line, `line should match`)
+			require.Equal(t, tc.Column, perr.Column, `column should match`)
+			require.Equal(t, tc.Token, perr.Token, `token should match`)
+			require.Equal(t, tc.Expected, perr.Expected, `expected tokens should match`)
+			require.Equal(t, tc.Message, perr.Error(), `message should match`)
+		})
+	}
+}
@@ -156,6 +156,22 @@ func TestParse(t *testing.T) {
 			Filter: `[foo bar baz)`,
 			Error:  true,
 		},
+		{
+			Filter: `ham:spam eq "spam"`,
+			Error:  true,
+		},
+		{
+			Filter: `urn:ietf:params:scim:schemas:core:2.0:User: eq "spam"`,
+			Error:  true,
+		},
+		{
+			Filter: `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.value eq "spam"`,
+			Expr: filter.NewCompareExpr(
+				filter.NewIdentifierExpr("urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.value"),
+				filter.EqualOp,
+				filter.NewAttrValueExpr("spam"),
+			),
+		},
 		{
 			Filter: `ham eq "spam"`,
 			Expr: filter.NewCompareExpr(
@@ -220,6 +236,34 @@ func TestParse(t *testing.T) {
 				filter.NewNumberExpr(123),
 			),
 		},
+		{
+			Filter: `ham eq -123`,
+			Expr: filter.NewCompareExpr(
+				filter.NewIdentifierExpr("ham"),
+				filter.EqualOp,
+				filter.NewNumberExpr(-123),
+			),
+		},
+		{
+			Filter: `ham gt 1.5`,
+			Expr: filter.NewCompareExpr(
+				filter.NewIdentifierExpr("ham"),
+				filter.GreaterThanOp,
+				filter.NewDecimalExpr(1.5),
+			),
+		},
+		{
+			Filter: `ham le 1.5e-3`,
+			Expr: filter.NewCompareExpr(
+				filter.NewIdentifierExpr("ham"),
+				filter.LessThanOrEqualToOp,
+				filter.NewDecimalExpr(0.0015),
+			),
+		},
+		{
+			Filter: `ham co 1.5`,
+			Error:  true,
+		},
 		{
 			Filter: "ham eq true",
 			Expr: filter.NewCompareExpr(
@@ -0,0 +1,120 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cybozu-go/scim/filter/internal/expr"
+)
+
+// Path is the parsed form of the `path` attribute of PATCH operations
+// (RFC7644 Section 3.5.2).
+//
+//	PATH = attrPath / valuePath [subAttr]
+//
+// For example, `members[value eq "2819c223"].display` is parsed into
+// a Path whose Attribute is `members`, Filter is `value eq "2819c223"`,
+// and SubAttribute is `display`.
+type Path struct {
+	// SchemaURI is the schema URI that the attribute was qualified with,
+	// or an empty string if the attribute is not qualified
+	SchemaURI string
+
+	// Attribute is the name of the attribute
+	Attribute string
+
+	// Filter selects the values of a multi-valued attribute that the
+	// operation applies to. It is nil if the path does not contain a
+	// value filter. Attribute paths in the filter are relative to
+	// the attribute (e.g. `value`, rather than `members.value`)
+	Filter Expr
+
+	// SubAttribute is the name of the sub-attribute, or an empty string
+	// if the path refers to the attribute as a whole
+	SubAttribute string
+}
+
+// ParsePath parses the `path` attribute of a PATCH operation.
+// The `options` parameter accepts the same options as `filter.Parse()`,
+// except that filter.WithPatchExpression() is always enabled.
+//
+// If the source cannot be parsed, a *filter.ParseError is returned.
+func ParsePath(src string, options ...ParseOption) (*Path, error) {
+	e, err := Parse(src, append(options, WithPatchExpression(true))...)
+	if err != nil {
+		return nil, err
+	}
+
+	vp, ok := e.(ValuePath)
+	if !ok {
+		return nil, fmt.Errorf(`expected value path, got %T`, e)
+	}
+
+	parent, ok := vp.ParentAttr().(IdentifierExpr)
+	if !ok {
+		return nil, fmt.Errorf(`expected attribute path, got %T`, vp.ParentAttr())
+	}
+	if err := expr.ValidateAttrPath(parent.Lit()); err != nil {
+		return nil, err
+	}
+
+	path := Path{
+		SchemaURI:    parent.SchemaURI(),
+		Attribute:    parent.Attribute(),
+		Filter:       vp.SubExpr(),
+		SubAttribute: parent.SubAttribute(),
+	}
+
+	if path.Filter != nil && path.SubAttribute != "" {
+		return nil, fmt.Errorf(`value filter in %q must follow the attribute name, not a sub-attribute`, src)
+	}
+
+	if sub := vp.SubAttr(); sub != nil {
+		ident, ok := sub.(IdentifierExpr)
+		if !ok {
+			return nil, fmt.Errorf(`expected sub-attribute name, got %T`, sub)
+		}
+		path.SubAttribute = ident.Lit()
+	}
+
+	if strings.ContainsAny(path.SubAttribute, `.:`) {
+		return nil, fmt.Errorf(`sub-attribute %q in %q must be a single attribute name`, path.SubAttribute, src)
+	}
+	return &path, nil
+}
+
+// AttrPath returns the attribute path that the path refers to,
+// without the value filter (e.g. `members.display`)
+func (p *Path) AttrPath() string {
+	var sb strings.Builder
+	if p.SchemaURI != "" {
+		sb.WriteString(p.SchemaURI)
+		sb.WriteByte(':')
+	}
+	sb.WriteString(p.Attribute)
+	if p.SubAttribute != "" {
+		sb.WriteByte('.')
+		sb.WriteString(p.SubAttribute)
+	}
+	return sb.String()
+}
+
+// String returns the path in the form accepted by `filter.ParsePath()`
+func (p *Path) String() string {
+	var sb strings.Builder
+	if p.SchemaURI != "" {
+		sb.WriteString(p.SchemaURI)
+		sb.WriteByte(':')
+	}
+	sb.WriteString(p.Attribute)
+	if p.Filter != nil {
+		sb.WriteByte('[')
+		sb.WriteString(Format(p.Filter))
+		sb.WriteByte(']')
+	}
+	if p.SubAttribute != "" {
+		sb.WriteByte('.')
+		sb.WriteString(p.SubAttribute)
+	}
+	return sb.String()
+}
@@ -0,0 +1,108 @@
+package filter_test
+
+import (
+	"testing"
+
+	"github.com/cybozu-go/scim/filter"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePath(t *testing.T) {
+	testcases := []struct {
+		Path         string
+		Error        bool
+		SchemaURI    string
+		Attribute    string
+		Filter       string
+		SubAttribute string
+		AttrPath     string
+	}{
+		{
+			Path:      `userName`,
+			Attribute: `userName`,
+			AttrPath:  `userName`,
+		},
+		{
+			Path:         `name.familyName`,
+			Attribute:    `name`,
+			SubAttribute: `familyName`,
+			AttrPath:     `name.familyName`,
+		},
+		{
+			Path:      `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber`,
+			SchemaURI: `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User`,
+			Attribute: `employeeNumber`,
+			AttrPath:  `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber`,
+		},
+		{
+			Path:         `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.value`,
+			SchemaURI:    `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User`,
+			Attribute:    `manager`,
+			SubAttribute: `value`,
+			AttrPath:     `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.value`,
+		},
+		{
+			Path:      `emails[type eq "work"]`,
+			Attribute: `emails`,
+			Filter:    `type eq "work"`,
+			AttrPath:  `emails`,
+		},
+		{
+			Path:         `members[value eq "2819c223"].display`,
+			Attribute:    `members`,
+			Filter:       `value eq "2819c223"`,
+			SubAttribute: `display`,
+			AttrPath:     `members.display`,
+		},
+		{
+			Path:         `addresses[type eq "work" and primary eq true].streetAddress`,
+			Attribute:    `addresses`,
+			Filter:       `type eq "work" and primary eq true`,
+			SubAttribute: `streetAddress`,
+			AttrPath:     `addresses.streetAddress`,
+		},
+		{
+			Path:  `name.familyName.foo`,
+			Error: true,
+		},
+		{
+			Path:  `emails[type eq "work"].value.foo`,
+			Error: true,
+		},
+		{
+			Path:  `name.familyName[value eq "x"]`,
+			Error: true,
+		},
+		{
+			Path:  `userName eq "bjensen"`,
+			Error: true,
+		},
+		{
+			Path:  `emails[type eq "work"`,
+			Error: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Path, func(t *testing.T) {
+			path, err := filter.ParsePath(tc.Path)
+			if tc.Error {
+				require.Error(t, err, `filter.ParsePath should fail`)
+				return
+			}
+			require.NoError(t, err, `filter.ParsePath should succeed`)
+
+			require.Equal(t, tc.SchemaURI, path.SchemaURI, `SchemaURI should match`)
+			require.Equal(t, tc.Attribute, path.Attribute, `Attribute should match`)
+			require.Equal(t, tc.SubAttribute, path.SubAttribute, `SubAttribute should match`)
+			if tc.Filter == "" {
+				require.Nil(t, path.Filter, `Filter should be nil`)
+			} else {
+				require.Equal(t, tc.Filter, filter.Format(path.Filter), `Filter should match`)
+			}
+			require.Equal(t, tc.AttrPath, path.AttrPath(), `AttrPath should match`)
+			require.Equal(t, tc.Path, path.String(), `String should round trip`)
+		})
+	}
+}
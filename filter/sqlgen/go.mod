@@ -0,0 +1,19 @@
+module github.com/cybozu-go/scim/filter/sqlgen
+
+go 1.17
+
+require (
+	github.com/cybozu-go/scim v0.0.0-20220520091855-11f56e98670d
+	github.com/doug-martin/goqu/v9 v9.18.0
+	github.com/lestrrat-go/option v1.0.0
+	github.com/stretchr/testify v1.8.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/lestrrat-go/blackmagic v1.0.2-0.20220926062815-509018abad40 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/cybozu-go/scim => ../../
@@ -0,0 +1,619 @@
+package sqlgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/cybozu-go/scim/filter"
+	"github.com/cybozu-go/scim/resource"
+	"github.com/cybozu-go/scim/schema"
+	goqu "github.com/doug-martin/goqu/v9"
+	_ "github.com/doug-martin/goqu/v9/dialect/postgres" // for the "postgres" dialect
+	"github.com/doug-martin/goqu/v9/exp"
+)
+
+// schemasAttr describes the `schemas` attribute, which is common to
+// all resources but is not listed in the schema definitions
+var schemasAttr = resource.NewSchemaAttributeBuilder().
+	Name(`schemas`).
+	Type(resource.Reference).
+	MultiValued(true).
+	CaseExact(true).
+	MustBuild()
+
+// JSONB translates SCIM filters into PostgreSQL predicates against a
+// JSONB column that holds the JSON representation of the resources.
+// Use `sqlgen.NewJSONB()` to create one.
+//
+// Filters are translated as follows:
+//
+//   - `pr` against single-valued attributes uses the `?` operator, and
+//     excludes JSON null values (e.g.
+//     `("data"->'title' <> 'null'::jsonb AND "data" ? 'title')`)
+//   - `eq` against case-exact strings, booleans and numbers uses the
+//     `@>` operator, so that GIN indexes on the column can be used. This
+//     includes sub-attributes of multi-valued attributes
+//     (e.g. `"data" @> '{"emails":[{"value":"..."}]}'`)
+//   - Other comparisons against single-valued attributes compare the
+//     values extracted by the `->>` operator. The values are
+//     lower-cased if the attribute is not case-exact, cast to `numeric`
+//     for numbers, and to `timestamptz` for dateTime values.
+//   - Other filters against multi-valued attributes, including value
+//     paths, use `jsonb_path_exists()`, so that all conditions inside a
+//     value path are applied to the same value. Values are embedded into
+//     the JSON path expression, which is passed as an argument to the
+//     prepared statement. dateTime values are compared using the
+//     `datetime()` method, after being converted to UTC.
+type JSONB struct {
+	table  string
+	column string
+	schema *resource.Schema
+}
+
+// NewJSONB creates a new JSONB translator for resources stored in the
+// JSONB column of table, and described by the schema s. Attributes of
+// schema extensions are expected to be stored under the schema URI,
+// as they are in the JSON representation of the resource.
+func NewJSONB(table, column string, s *resource.Schema) *JSONB {
+	return &JSONB{
+		table:  table,
+		column: column,
+		schema: s,
+	}
+}
+
+// SQL translates the filter into a `SELECT` statement against the
+// table. Values are passed as arguments to the prepared statement.
+func (t *JSONB) SQL(expr filter.Expr) (string, []interface{}, error) {
+	where, err := t.Where(expr)
+	if err != nil {
+		return "", nil, err
+	}
+	return goqu.Dialect(`postgres`).From(t.table).Prepared(true).Where(where).ToSQL()
+}
+
+// Where translates the filter into an expression that can be used in
+// the `WHERE` clause of a query against the table.
+//
+// As with `(*sqlgen.Translator).Where()`, the filter is validated against
+// the schema before it is translated.
+func (t *JSONB) Where(expr filter.Expr) (exp.Expression, error) {
+	if t.schema == nil {
+		return nil, fmt.Errorf(`sqlgen: schema must be specified`)
+	}
+	if err := filter.Validate(expr, t.schema); err != nil {
+		return nil, err
+	}
+	return t.translate(&jsonbScope{attrs: t.schema.Attributes()}, expr)
+}
+
+// jsonbScope describes where the attributes referred to by the filter live
+type jsonbScope struct {
+	// keys is the list of keys that lead to the enclosing attribute, for
+	// filters inside a value path
+	keys []string
+	// attrs is the list of attributes that may be referred to
+	attrs []*resource.SchemaAttribute
+}
+
+// jsonbTarget is a resolved attribute path
+type jsonbTarget struct {
+	// keys is the list of keys that lead to the attribute, starting
+	// from the root of the document
+	keys []string
+	// attr is the attribute that was referred to
+	attr *resource.SchemaAttribute
+	// array is the number of keys that lead to the multi-valued
+	// attribute, or 0 if no multi-valued attribute is involved
+	array int
+}
+
+// element returns the keys that lead to the attribute, relative to
+// each value of the multi-valued attribute
+func (target *jsonbTarget) element() []string {
+	return target.keys[target.array:]
+}
+
+func (t *JSONB) resolve(sc *jsonbScope, v filter.Expr) (*jsonbTarget, error) {
+	ident, ok := v.(filter.IdentifierExpr)
+	if !ok {
+		return nil, fmt.Errorf(`expected identifier, got %T`, v)
+	}
+
+	keys := append([]string(nil), sc.keys...)
+	attrs := sc.attrs
+	if u := ident.SchemaURI(); u != "" && !strings.EqualFold(u, t.schema.ID()) {
+		if len(sc.keys) > 0 {
+			return nil, fmt.Errorf(`schema URI cannot be used inside a value path: %q`, ident.Lit())
+		}
+		ext, ok := schema.Get(u)
+		if !ok {
+			return nil, fmt.Errorf(`unknown schema %q`, u)
+		}
+		keys = append(keys, ext.ID())
+		attrs = ext.Attributes()
+	}
+
+	attr := schema.FindAttribute(attrs, ident.Attribute())
+	if attr == nil {
+		if len(sc.keys) > 0 || ident.SchemaURI() != "" || !strings.EqualFold(ident.Attribute(), schemasAttr.Name()) {
+			return nil, fmt.Errorf(`unknown attribute %q`, ident.Lit())
+		}
+		attr = schemasAttr
+	}
+	keys = append(keys, attr.Name())
+
+	target := &jsonbTarget{attr: attr}
+	if attr.MultiValued() {
+		target.array = len(keys)
+	}
+
+	if name := ident.SubAttribute(); name != "" {
+		sub := schema.FindAttribute(attr.SubAttributes(), name)
+		if sub == nil {
+			return nil, fmt.Errorf(`unknown attribute %q`, ident.Lit())
+		}
+		keys = append(keys, sub.Name())
+		target.attr = sub
+	}
+	target.keys = keys
+	return target, nil
+}
+
+func (t *JSONB) translate(sc *jsonbScope, v filter.Expr) (exp.Expression, error) {
+	switch v := v.(type) {
+	case filter.PresenceExpr:
+		return t.translatePresenceExpr(sc, v)
+	case filter.CompareExpr:
+		return t.translateComparison(sc, v.LHE(), v.Operator(), v.RHE())
+	case filter.RegexExpr:
+		return t.translateComparison(sc, v.LHE(), v.Operator(), v.Value())
+	case filter.LogExpr:
+		lhs, err := t.translate(sc, v.LHE())
+		if err != nil {
+			return nil, fmt.Errorf(`failed to translate left hand side of %q: %w`, v.Operator(), err)
+		}
+		rhs, err := t.translate(sc, v.RHS())
+		if err != nil {
+			return nil, fmt.Errorf(`failed to translate right hand side of %q: %w`, v.Operator(), err)
+		}
+		switch v.Operator() {
+		case filter.AndOp:
+			return goqu.And(lhs, rhs), nil
+		case filter.OrOp:
+			return goqu.Or(lhs, rhs), nil
+		default:
+			return nil, fmt.Errorf(`unhandled logical operator %q`, v.Operator())
+		}
+	case filter.ParenExpr:
+		sub, err := t.translate(sc, v.SubExpr())
+		if err != nil {
+			return nil, err
+		}
+		switch v.Operator() {
+		case "":
+			return sub, nil
+		case filter.NotOp:
+			return negate(sub), nil
+		default:
+			return nil, fmt.Errorf(`unhandled grouping operator %q`, v.Operator())
+		}
+	case filter.ValuePath:
+		return t.translateValuePath(sc, v)
+	default:
+		return nil, fmt.Errorf(`unhandled expression type: %T`, v)
+	}
+}
+
+// document returns the column, followed by the `->` operators to
+// access the object that holds the last key
+func (t *JSONB) document(keys []string) exp.Expression {
+	var sb strings.Builder
+	sb.WriteString(`?`)
+	for _, key := range keys[:len(keys)-1] {
+		sb.WriteString(`->`)
+		sb.WriteString(quoteSQL(key))
+	}
+	return goqu.L(sb.String(), goqu.C(t.column).Table(t.table))
+}
+
+// text returns the expression to access the value at keys as text
+func (t *JSONB) text(keys []string) exp.LiteralExpression {
+	return goqu.L(`?->>`+quoteSQL(keys[len(keys)-1]), t.document(keys))
+}
+
+// exists creates an expression that checks that the JSON path expression
+// matches any item in the document
+func (t *JSONB) exists(path string) exp.Expression {
+	return goqu.L(`jsonb_path_exists(?, ?::jsonpath)`, goqu.C(t.column).Table(t.table), path)
+}
+
+// contains creates an expression that checks that the document contains
+// the value at keys. The value is wrapped in an array at the index
+// array, if it is non-zero
+func (t *JSONB) contains(keys []string, array int, value interface{}) (exp.Expression, error) {
+	for i := len(keys) - 1; i >= 0; i-- {
+		if i+1 == array {
+			value = []interface{}{value}
+		}
+		value = map[string]interface{}{keys[i]: value}
+	}
+	doc, err := marshalJSON(value)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to encode value: %w`, err)
+	}
+	return goqu.L(`(? @> ?::jsonb)`, goqu.C(t.column).Table(t.table), doc), nil
+}
+
+func (t *JSONB) translatePresenceExpr(sc *jsonbScope, v filter.PresenceExpr) (exp.Expression, error) {
+	target, err := t.resolve(sc, v.Attr())
+	if err != nil {
+		return nil, fmt.Errorf(`left hand side of %q is not valid: %w`, v.Operator(), err)
+	}
+	return t.presence(target)
+}
+
+func (t *JSONB) presence(target *jsonbTarget) (exp.Expression, error) {
+	if target.array == 0 || target.array == len(target.keys) {
+		// the `?` operator is written out after all arguments have
+		// been consumed, so that it is not treated as a placeholder.
+		// Keys holding JSON null are not present
+		key := quoteSQL(target.keys[len(target.keys)-1])
+		return goqu.L(`(?->`+key+` <> 'null'::jsonb AND ? ? `+key+`)`, t.document(target.keys), t.document(target.keys)), nil
+	}
+
+	pred := jsonpathPresence(target.element())
+	return t.exists(jsonpathArray(target.keys[:target.array]) + ` ? (` + pred + `)`), nil
+}
+
+func (t *JSONB) translateComparison(sc *jsonbScope, lhe filter.Expr, op string, rhe interface{}) (exp.Expression, error) {
+	target, err := t.resolve(sc, lhe)
+	if err != nil {
+		return nil, fmt.Errorf(`left hand side of %q is not valid: %w`, op, err)
+	}
+
+	value, err := literal(rhe)
+	if err != nil {
+		return nil, fmt.Errorf(`right hand side of %q is not valid: %w`, op, err)
+	}
+
+	if value == nil {
+		present, err := t.presence(target)
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case filter.EqualOp:
+			return negate(present), nil
+		case filter.NotEqualOp:
+			return present, nil
+		default:
+			return nil, fmt.Errorf(`operator %q cannot be used against null`, op)
+		}
+	}
+
+	if op == filter.EqualOp && containable(target.attr, value) {
+		return t.contains(target.keys, target.array, value)
+	}
+
+	if target.array > 0 {
+		pred, err := jsonpathCompare(target.element(), target.attr, op, value)
+		if err != nil {
+			return nil, err
+		}
+		return t.exists(jsonpathArray(target.keys[:target.array]) + ` ? (` + pred + `)`), nil
+	}
+
+	var lhs comparableExpr = t.text(target.keys)
+	switch value.(type) {
+	case bool:
+		lhs = goqu.L(`(?)::boolean`, lhs)
+	case int, float64:
+		lhs = goqu.L(`(?)::numeric`, lhs)
+	case string:
+		if target.attr.Type() == resource.DateTime {
+			lhs = goqu.L(`(?)::timestamptz`, lhs)
+		}
+	}
+	return compare(lhs, target.attr, op, value)
+}
+
+// containable returns true if `eq` against the value can be translated
+// using the `@>` operator
+func containable(attr *resource.SchemaAttribute, value interface{}) bool {
+	switch value.(type) {
+	case bool, int, float64:
+		return true
+	case string:
+		switch attr.Type() {
+		case resource.DateTime:
+			return false
+		case resource.Binary:
+			return true
+		default:
+			return attr.CaseExact()
+		}
+	default:
+		return false
+	}
+}
+
+func (t *JSONB) translateValuePath(sc *jsonbScope, v filter.ValuePath) (exp.Expression, error) {
+	if len(sc.keys) > 0 {
+		return nil, fmt.Errorf(`value paths cannot be nested`)
+	}
+
+	ident, ok := v.ParentAttr().(filter.IdentifierExpr)
+	if !ok || ident.SubAttribute() != "" {
+		return nil, fmt.Errorf(`parent attribute of value path is not valid: %v`, v.ParentAttr())
+	}
+	target, err := t.resolve(sc, ident)
+	if err != nil {
+		return nil, fmt.Errorf(`parent attribute of value path is not valid: %w`, err)
+	}
+
+	if v.SubExpr() == nil {
+		return t.presence(target)
+	}
+
+	if target.array == 0 {
+		// single-valued complex attributes are filtered in place
+		return t.translate(&jsonbScope{keys: target.keys, attrs: target.attr.SubAttributes()}, v.SubExpr())
+	}
+
+	pred, err := t.predicate(target.attr.SubAttributes(), v.SubExpr())
+	if err != nil {
+		return nil, fmt.Errorf(`failed to translate filter for value path: %w`, err)
+	}
+	return t.exists(jsonpathArray(target.keys) + ` ? (` + pred + `)`), nil
+}
+
+// predicate translates the filter applied to each value of a
+// multi-valued attribute into a JSON path predicate
+func (t *JSONB) predicate(attrs []*resource.SchemaAttribute, v filter.Expr) (string, error) {
+	switch v := v.(type) {
+	case filter.PresenceExpr:
+		attr, err := subAttribute(attrs, v.Attr())
+		if err != nil {
+			return "", fmt.Errorf(`left hand side of %q is not valid: %w`, v.Operator(), err)
+		}
+		return jsonpathPresence([]string{attr.Name()}), nil
+	case filter.CompareExpr:
+		return t.predicateComparison(attrs, v.LHE(), v.Operator(), v.RHE())
+	case filter.RegexExpr:
+		return t.predicateComparison(attrs, v.LHE(), v.Operator(), v.Value())
+	case filter.LogExpr:
+		lhs, err := t.predicate(attrs, v.LHE())
+		if err != nil {
+			return "", fmt.Errorf(`failed to translate left hand side of %q: %w`, v.Operator(), err)
+		}
+		rhs, err := t.predicate(attrs, v.RHS())
+		if err != nil {
+			return "", fmt.Errorf(`failed to translate right hand side of %q: %w`, v.Operator(), err)
+		}
+		switch v.Operator() {
+		case filter.AndOp:
+			return `(` + lhs + ` && ` + rhs + `)`, nil
+		case filter.OrOp:
+			return `(` + lhs + ` || ` + rhs + `)`, nil
+		default:
+			return "", fmt.Errorf(`unhandled logical operator %q`, v.Operator())
+		}
+	case filter.ParenExpr:
+		sub, err := t.predicate(attrs, v.SubExpr())
+		if err != nil {
+			return "", err
+		}
+		switch v.Operator() {
+		case "":
+			return sub, nil
+		case filter.NotOp:
+			// predicates on missing, null, or mismatched values are
+			// unknown, and !(unknown) is unknown as well. Such values do
+			// not match the predicate, so they must match its negation
+			return `((` + sub + `) is unknown || !(` + sub + `))`, nil
+		default:
+			return "", fmt.Errorf(`unhandled grouping operator %q`, v.Operator())
+		}
+	case filter.ValuePath:
+		return "", fmt.Errorf(`value paths cannot be nested`)
+	default:
+		return "", fmt.Errorf(`unhandled expression type: %T`, v)
+	}
+}
+
+func (t *JSONB) predicateComparison(attrs []*resource.SchemaAttribute, lhe filter.Expr, op string, rhe interface{}) (string, error) {
+	attr, err := subAttribute(attrs, lhe)
+	if err != nil {
+		return "", fmt.Errorf(`left hand side of %q is not valid: %w`, op, err)
+	}
+
+	value, err := literal(rhe)
+	if err != nil {
+		return "", fmt.Errorf(`right hand side of %q is not valid: %w`, op, err)
+	}
+	return jsonpathCompare([]string{attr.Name()}, attr, op, value)
+}
+
+// subAttribute resolves the attribute path inside a value path
+func subAttribute(attrs []*resource.SchemaAttribute, v filter.Expr) (*resource.SchemaAttribute, error) {
+	ident, ok := v.(filter.IdentifierExpr)
+	if !ok {
+		return nil, fmt.Errorf(`expected identifier, got %T`, v)
+	}
+	if ident.SchemaURI() != "" || ident.SubAttribute() != "" {
+		return nil, fmt.Errorf(`invalid attribute inside a value path: %q`, ident.Lit())
+	}
+	attr := schema.FindAttribute(attrs, ident.Attribute())
+	if attr == nil {
+		return nil, fmt.Errorf(`unknown attribute %q`, ident.Lit())
+	}
+	return attr, nil
+}
+
+// jsonpathArray returns the JSON path expression that iterates over
+// the values of the multi-valued attribute at keys
+func jsonpathArray(keys []string) string {
+	return jsonpathAccessor(`$`, keys) + `[*]`
+}
+
+func jsonpathAccessor(base string, keys []string) string {
+	var sb strings.Builder
+	sb.WriteString(base)
+	for _, key := range keys {
+		sb.WriteByte('.')
+		sb.WriteString(quoteJSONPath(key))
+	}
+	return sb.String()
+}
+
+// jsonpathPresence returns the JSON path predicate that checks that
+// the value at keys, relative to the current item, is not null
+func jsonpathPresence(keys []string) string {
+	return `exists(` + jsonpathAccessor(`@`, keys) + ` ? (@ != null))`
+}
+
+// jsonpathCompare returns the JSON path predicate that compares the
+// value at keys, relative to the current item, against value
+func jsonpathCompare(keys []string, attr *resource.SchemaAttribute, op string, value interface{}) (string, error) {
+	lhs := jsonpathAccessor(`@`, keys)
+	if value == nil {
+		pred := jsonpathPresence(keys)
+		switch op {
+		case filter.EqualOp:
+			return `!` + pred, nil
+		case filter.NotEqualOp:
+			return pred, nil
+		default:
+			return "", fmt.Errorf(`operator %q cannot be used against null`, op)
+		}
+	}
+
+	if s, ok := value.(string); ok && attr.Type() == resource.DateTime {
+		return jsonpathCompareDateTime(lhs, op, s)
+	}
+
+	var caseExact bool
+	if s, ok := value.(string); ok {
+		switch attr.Type() {
+		case resource.Binary:
+			caseExact = true
+		default:
+			caseExact = attr.CaseExact()
+		}
+
+		var pattern string
+		switch op {
+		case filter.ContainsOp:
+			pattern = regexp.QuoteMeta(s)
+		case filter.StartsWithOp:
+			pattern = `^` + regexp.QuoteMeta(s)
+		case filter.EndsWithOp:
+			pattern = regexp.QuoteMeta(s) + `$`
+		case filter.EqualOp, filter.NotEqualOp:
+			if !caseExact {
+				pattern = `^` + regexp.QuoteMeta(s) + `$`
+			}
+		}
+
+		if pattern != "" {
+			quoted, err := marshalJSON(pattern)
+			if err != nil {
+				return "", fmt.Errorf(`failed to encode value: %w`, err)
+			}
+			pred := lhs + ` like_regex ` + quoted
+			if !caseExact {
+				pred += ` flag "i"`
+			}
+			if op == filter.NotEqualOp {
+				return `(` + jsonpathPresence(keys) + ` && !(` + pred + `))`, nil
+			}
+			return pred, nil
+		}
+	}
+
+	rhs, err := marshalJSON(value)
+	if err != nil {
+		return "", fmt.Errorf(`failed to encode value: %w`, err)
+	}
+
+	var jsonOp string
+	switch op {
+	case filter.EqualOp:
+		jsonOp = `==`
+	case filter.NotEqualOp:
+		jsonOp = `!=`
+	case filter.GreaterThanOp:
+		jsonOp = `>`
+	case filter.GreaterThanOrEqualToOp:
+		jsonOp = `>=`
+	case filter.LessThanOp:
+		jsonOp = `<`
+	case filter.LessThanOrEqualToOp:
+		jsonOp = `<=`
+	default:
+		return "", fmt.Errorf(`operator %q cannot be used against %T values`, op, value)
+	}
+	return lhs + ` ` + jsonOp + ` ` + rhs, nil
+}
+
+// jsonpathCompareDateTime returns the JSON path predicate that compares
+// the dateTime value at lhs against s as dates
+func jsonpathCompareDateTime(lhs, op, s string) (string, error) {
+	tm, err := resource.ParseDateTime(s)
+	if err != nil {
+		return "", fmt.Errorf(`failed to parse %q as dateTime: %w`, s, err)
+	}
+
+	var jsonOp string
+	switch op {
+	case filter.EqualOp:
+		jsonOp = `==`
+	case filter.NotEqualOp:
+		jsonOp = `!=`
+	case filter.GreaterThanOp:
+		jsonOp = `>`
+	case filter.GreaterThanOrEqualToOp:
+		jsonOp = `>=`
+	case filter.LessThanOp:
+		jsonOp = `<`
+	case filter.LessThanOrEqualToOp:
+		jsonOp = `<=`
+	default:
+		return "", fmt.Errorf(`operator %q cannot be used against dateTime values`, op)
+	}
+
+	// the offset is written as `+00:00`, which `datetime()` accepts
+	rhs, err := marshalJSON(tm.UTC().Format(`2006-01-02T15:04:05.999999999-07:00`))
+	if err != nil {
+		return "", fmt.Errorf(`failed to encode value: %w`, err)
+	}
+	return lhs + `.datetime() ` + jsonOp + ` ` + rhs + `.datetime()`, nil
+}
+
+// marshalJSON encodes v as JSON without escaping HTML characters. The
+// result can also be used as a string or numeric literal in JSON paths
+func marshalJSON(v interface{}) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// quoteJSONPath quotes the key for use in JSON path expressions
+func quoteJSONPath(key string) string {
+	// strings can always be encoded
+	s, _ := marshalJSON(key)
+	return s
+}
+
+// quoteSQL quotes the key for use as a string literal in SQL
+func quoteSQL(key string) string {
+	return `'` + strings.ReplaceAll(key, `'`, `''`) + `'`
+}
@@ -0,0 +1,216 @@
+package sqlgen_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cybozu-go/scim/filter"
+	"github.com/cybozu-go/scim/filter/sqlgen"
+	"github.com/cybozu-go/scim/resource"
+	"github.com/cybozu-go/scim/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONB(t *testing.T) {
+	userSchema, ok := schema.Get(resource.UserSchemaURI)
+	require.True(t, ok, `schema.Get should succeed`)
+
+	testcases := []struct {
+		Filter       string
+		ExpectedSQL  string
+		ExpectedArgs []interface{}
+		Error        bool
+	}{
+		{
+			Filter:       `userName eq "bjensen"`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE (LOWER("users"."data"->>'userName') = $1)`,
+			ExpectedArgs: []interface{}{`bjensen`},
+		},
+		{
+			Filter:       `meta.version eq "W/\"3694e05e9dff591\""`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE ("users"."data" @> $1::jsonb)`,
+			ExpectedArgs: []interface{}{`{"meta":{"version":"W/\"3694e05e9dff591\""}}`},
+		},
+		{
+			Filter:       `active eq true`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE ("users"."data" @> $1::jsonb)`,
+			ExpectedArgs: []interface{}{`{"active":true}`},
+		},
+		{
+			Filter:       `active ne true`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE (("users"."data"->>'active')::boolean != $1)`,
+			ExpectedArgs: []interface{}{true},
+		},
+		// keys holding JSON null (e.g. `{"title": null}`) are not present
+		{
+			Filter:       `title pr`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE ("users"."data"->'title' <> 'null'::jsonb AND "users"."data" ? 'title')`,
+			ExpectedArgs: []interface{}{},
+		},
+		{
+			Filter:       `name.familyName pr`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE ("users"."data"->'name'->'familyName' <> 'null'::jsonb AND "users"."data"->'name' ? 'familyName')`,
+			ExpectedArgs: []interface{}{},
+		},
+		{
+			Filter:       `nickName eq null`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE NOT COALESCE(("users"."data"->'nickName' <> 'null'::jsonb AND "users"."data" ? 'nickName'), FALSE)`,
+			ExpectedArgs: []interface{}{},
+		},
+		{
+			Filter:       `name.familyName co "O'Malley"`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE (LOWER("users"."data"->'name'->>'familyName') LIKE $1 ESCAPE '!')`,
+			ExpectedArgs: []interface{}{`%o'malley%`},
+		},
+		{
+			Filter:       `displayName ew "100%_off!"`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE (LOWER("users"."data"->>'displayName') LIKE $1 ESCAPE '!')`,
+			ExpectedArgs: []interface{}{`%100!%!_off!!`},
+		},
+		{
+			Filter:       `meta.lastModified gt "2011-05-13T04:42:34Z"`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE (("users"."data"->'meta'->>'lastModified')::timestamptz > $1)`,
+			ExpectedArgs: []interface{}{time.Date(2011, 5, 13, 4, 42, 34, 0, time.UTC)},
+		},
+		{
+			Filter:       `emails pr`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE ("users"."data"->'emails' <> 'null'::jsonb AND "users"."data" ? 'emails')`,
+			ExpectedArgs: []interface{}{},
+		},
+		{
+			Filter:       `emails.type pr`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE jsonb_path_exists("users"."data", $1::jsonpath)`,
+			ExpectedArgs: []interface{}{`$."emails"[*] ? (exists(@."type" ? (@ != null)))`},
+		},
+		{
+			Filter:       `emails.primary eq true`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE ("users"."data" @> $1::jsonb)`,
+			ExpectedArgs: []interface{}{`{"emails":[{"primary":true}]}`},
+		},
+		{
+			Filter:       `emails.value co "@example.com"`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE jsonb_path_exists("users"."data", $1::jsonpath)`,
+			ExpectedArgs: []interface{}{`$."emails"[*] ? (@."value" like_regex "@example\\.com" flag "i")`},
+		},
+		{
+			Filter:       `emails[type eq "work" and value co "@example.com"]`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE jsonb_path_exists("users"."data", $1::jsonpath)`,
+			ExpectedArgs: []interface{}{`$."emails"[*] ? ((@."type" like_regex "^work$" flag "i" && @."value" like_regex "@example\\.com" flag "i"))`},
+		},
+		{
+			Filter:       `emails[type ne "work" or not (primary eq true)]`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE jsonb_path_exists("users"."data", $1::jsonpath)`,
+			ExpectedArgs: []interface{}{`$."emails"[*] ? (((exists(@."type" ? (@ != null)) && !(@."type" like_regex "^work$" flag "i")) || ((@."primary" == true) is unknown || !(@."primary" == true))))`},
+		},
+		{
+			Filter:       `emails[value pr and display eq null]`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE jsonb_path_exists("users"."data", $1::jsonpath)`,
+			ExpectedArgs: []interface{}{`$."emails"[*] ? ((exists(@."value" ? (@ != null)) && !exists(@."display" ? (@ != null))))`},
+		},
+		{
+			Filter:       `schemas eq "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE ("users"."data" @> $1::jsonb)`,
+			ExpectedArgs: []interface{}{`{"schemas":["urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"]}`},
+		},
+		{
+			Filter:       `name[familyName eq "Jensen"]`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE (LOWER("users"."data"->'name'->>'familyName') = $1)`,
+			ExpectedArgs: []interface{}{`jensen`},
+		},
+		{
+			Filter:       `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.displayName sw "John"`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE (LOWER("users"."data"->'urn:ietf:params:scim:schemas:extension:enterprise:2.0:User'->'manager'->>'displayName') LIKE $1 ESCAPE '!')`,
+			ExpectedArgs: []interface{}{`john%`},
+		},
+		{
+			Filter:       `title pr and not (userType eq "Employee" or userType eq "Intern")`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE (("users"."data"->'title' <> 'null'::jsonb AND "users"."data" ? 'title') AND NOT COALESCE(((LOWER("users"."data"->>'userType') = $1) OR (LOWER("users"."data"->>'userType') = $2)), FALSE))`,
+			ExpectedArgs: []interface{}{`employee`, `intern`},
+		},
+		{
+			// documents in which userType is missing or null do not match
+			// the comparison, and must therefore match its negation
+			Filter:       `not (userType eq "Employee")`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE NOT COALESCE((LOWER("users"."data"->>'userType') = $1), FALSE)`,
+			ExpectedArgs: []interface{}{`employee`},
+		},
+		{
+			Filter: `emails[vaule co "@example.com"]`,
+			Error:  true,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Filter, func(t *testing.T) {
+			expr, err := filter.Parse(tc.Filter)
+			require.NoError(t, err, `filter.Parse should succeed`)
+
+			sql, args, err := sqlgen.NewJSONB(`users`, `data`, userSchema).SQL(expr)
+			if tc.Error {
+				require.Error(t, err, `SQL should fail`)
+				var serr *resource.Error
+				require.True(t, errors.As(err, &serr), `error should be a *resource.Error`)
+				return
+			}
+			require.NoError(t, err, `SQL should succeed`)
+			require.Equal(t, tc.ExpectedSQL, sql, `SQL should match`)
+			require.Equal(t, tc.ExpectedArgs, args, `arguments should match`)
+		})
+	}
+}
+
+func TestJSONBDateTime(t *testing.T) {
+	deviceSchema := resource.NewSchemaBuilder().
+		ID(`urn:example:params:scim:schemas:core:2.0:Device`).
+		Name(`Device`).
+		Attributes(
+			resource.NewSchemaAttributeBuilder().
+				Name(`certificates`).
+				Type(resource.Complex).
+				MultiValued(true).
+				SubAttributes(
+					resource.NewSchemaAttributeBuilder().
+						Name(`value`).
+						Type(resource.Binary).
+						MultiValued(false).
+						MustBuild(),
+					resource.NewSchemaAttributeBuilder().
+						Name(`expires`).
+						Type(resource.DateTime).
+						MultiValued(false).
+						MustBuild(),
+				).
+				MustBuild(),
+		).
+		MustBuild()
+
+	testcases := []struct {
+		Filter       string
+		ExpectedArgs []interface{}
+	}{
+		{
+			Filter:       `certificates.expires lt "2011-05-13T13:42:34+09:00"`,
+			ExpectedArgs: []interface{}{`$."certificates"[*] ? (@."expires".datetime() < "2011-05-13T04:42:34+00:00".datetime())`},
+		},
+		{
+			Filter:       `certificates[expires eq "2011-05-13T04:42:34.5Z" and value pr]`,
+			ExpectedArgs: []interface{}{`$."certificates"[*] ? ((@."expires".datetime() == "2011-05-13T04:42:34.5+00:00".datetime() && exists(@."value" ? (@ != null))))`},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Filter, func(t *testing.T) {
+			expr, err := filter.Parse(tc.Filter)
+			require.NoError(t, err, `filter.Parse should succeed`)
+			require.NoError(t, filter.Validate(expr, deviceSchema), `filter.Validate should succeed`)
+
+			sql, args, err := sqlgen.NewJSONB(`devices`, `data`, deviceSchema).SQL(expr)
+			require.NoError(t, err, `SQL should succeed`)
+			require.Equal(t, `SELECT * FROM "devices" WHERE jsonb_path_exists("devices"."data", $1::jsonpath)`, sql, `SQL should match`)
+			require.Equal(t, tc.ExpectedArgs, args, `arguments should match`)
+		})
+	}
+}
@@ -0,0 +1,16 @@
+// This file is auto-generated by tools/cmd/genoptions/main.go. DO NOT EDIT
+
+package sqlgen
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptionIdent(t *testing.T) {
+	require.Equal(t, "WithColumns", identColumns{}.String())
+	require.Equal(t, "WithDialect", identDialect{}.String())
+	require.Equal(t, "WithPrimaryKey", identPrimaryKey{}.String())
+	require.Equal(t, "WithTables", identTables{}.String())
+}
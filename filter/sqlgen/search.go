@@ -0,0 +1,135 @@
+package sqlgen
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/cybozu-go/scim/filter"
+	"github.com/cybozu-go/scim/resource"
+	"github.com/cybozu-go/scim/schema"
+	goqu "github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+)
+
+// Search creates the queries needed to serve the search request.
+//
+// The data query selects the resources in the page requested by the
+// `filter`, `sortBy`, `sortOrder`, `startIndex` and `count` parameters.
+// `sortOrder` is case-insensitive, as in `server.ListUsersEndpoint()`.
+// The count query selects the number of resources that match the filter,
+// regardless of pagination, which should be reported as `totalResults`.
+// If `count` is 0, only the total number of resources is requested, and
+// the data query is nil.
+//
+// Resources are sorted by the attribute given in `sortBy`, and then by
+// the primary key so that pagination is stable. String attributes that
+// are not case-exact are sorted regardless of their case. Multi-valued
+// attributes are sorted using the primary value, or any of the values if
+// none of them is primary (RFC 7644 Section 3.4.2.3).
+//
+// Both queries select from the resource table only. Use
+// `(*goqu.SelectDataset).Select()` to choose the columns to retrieve.
+//
+// Problems with the request are reported as a *resource.Error, which can
+// be returned to the client as is.
+func (t *Translator) Search(req *resource.SearchRequest) (*goqu.SelectDataset, *goqu.SelectDataset, error) {
+	if t.schema == nil {
+		return nil, nil, fmt.Errorf(`sqlgen: schema must be specified`)
+	}
+
+	ds := t.dialect.From(t.table).Prepared(true)
+	if req.HasFilter() && req.Filter() != "" {
+		expr, err := filter.Parse(req.Filter())
+		if err != nil {
+			return nil, nil, resource.NewErrorBuilder().
+				Status(http.StatusBadRequest).
+				SCIMType(resource.ErrInvalidFilter).
+				Detail(err.Error()).
+				MustBuild()
+		}
+		where, err := t.Where(expr)
+		if err != nil {
+			return nil, nil, err
+		}
+		ds = ds.Where(where)
+	}
+
+	count := ds.Select(goqu.COUNT(goqu.Star()))
+
+	if req.HasCount() && req.Count() <= 0 {
+		return nil, count, nil
+	}
+
+	var order []exp.OrderedExpression
+	if req.HasSortBy() && req.SortBy() != "" {
+		sortKey, err := t.sortKey(req.SortBy())
+		if err != nil {
+			return nil, nil, resource.NewErrorBuilder().
+				Status(http.StatusBadRequest).
+				SCIMType(resource.ErrInvalidValue).
+				Detail(fmt.Sprintf(`invalid sortBy %q: %s`, req.SortBy(), err)).
+				MustBuild()
+		}
+
+		switch strings.ToLower(req.SortOrder()) {
+		case "", resource.SortAscending:
+			order = append(order, sortKey.Asc())
+		case resource.SortDescending:
+			order = append(order, sortKey.Desc())
+		default:
+			return nil, nil, resource.NewErrorBuilder().
+				Status(http.StatusBadRequest).
+				SCIMType(resource.ErrInvalidValue).
+				Detail(fmt.Sprintf(`invalid sortOrder %q`, req.SortOrder())).
+				MustBuild()
+		}
+	}
+	order = append(order, goqu.C(t.primaryKey).Table(t.table).Asc())
+
+	data := ds.Order(order...)
+	if startIndex := req.StartIndex(); startIndex > 1 {
+		data = data.Offset(uint(startIndex - 1))
+	}
+	if req.HasCount() {
+		data = data.Limit(uint(req.Count()))
+	}
+	return data, count, nil
+}
+
+// sortKey returns the expression that resources should be sorted by,
+// for the attribute path given in `sortBy`
+func (t *Translator) sortKey(sortBy string) (exp.Orderable, error) {
+	target, err := t.resolve(t.rootScope(), filter.NewIdentifierExpr(sortBy))
+	if err != nil {
+		return nil, err
+	}
+	if target.subColumns != nil {
+		return nil, fmt.Errorf(`complex attribute %q cannot be used for sorting`, target.attr.Name())
+	}
+
+	var key interface {
+		exp.Expression
+		exp.Orderable
+	} = target.column
+	if target.attr.Type() == resource.String && !target.attr.CaseExact() {
+		key = goqu.Func(`LOWER`, target.column)
+	}
+
+	if target.table == nil {
+		return key, nil
+	}
+
+	// pick the primary value, or any of the values if there are none
+	values := t.dialect.From(target.table.Name).
+		Select(key).
+		Where(goqu.C(target.table.ForeignKey).Table(target.table.Name).Eq(goqu.C(t.primaryKey).Table(t.table))).
+		Limit(1)
+	if primary := schema.FindAttribute(target.parent.SubAttributes(), `primary`); primary != nil {
+		column := goqu.C(t.column(target.path+`.`+primary.Name(), primary.Name())).Table(target.table.Name)
+		values = values.Order(goqu.Case().When(column.Eq(goqu.V(true)), 0).Else(1).Asc(), key.Asc())
+	} else {
+		values = values.Order(key.Asc())
+	}
+	return goqu.L(`?`, values), nil
+}
@@ -0,0 +1,141 @@
+package sqlgen_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/cybozu-go/scim/filter/sqlgen"
+	"github.com/cybozu-go/scim/resource"
+	"github.com/cybozu-go/scim/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearch(t *testing.T) {
+	userSchema, ok := schema.Get(resource.UserSchemaURI)
+	require.True(t, ok, `schema.Get should succeed`)
+
+	testcases := []struct {
+		Name              string
+		Request           *resource.SearchRequest
+		ExpectedSQL       string
+		ExpectedArgs      []interface{}
+		ExpectedCountSQL  string
+		ExpectedCountArgs []interface{}
+		Error             resource.ErrorType
+	}{
+		{
+			Name:              `empty request`,
+			Request:           resource.NewSearchRequestBuilder().MustBuild(),
+			ExpectedSQL:       `SELECT * FROM "users" ORDER BY "users"."id" ASC`,
+			ExpectedArgs:      []interface{}{},
+			ExpectedCountSQL:  `SELECT COUNT(*) FROM "users"`,
+			ExpectedCountArgs: []interface{}{},
+		},
+		{
+			Name: `filter, sorting and pagination`,
+			Request: resource.NewSearchRequestBuilder().
+				Filter(`title pr`).
+				SortBy(`userName`).
+				SortOrder(resource.SortDescending).
+				StartIndex(11).
+				Count(10).
+				MustBuild(),
+			ExpectedSQL:       `SELECT * FROM "users" WHERE ("users"."title" IS NOT NULL) ORDER BY LOWER("users"."userName") DESC, "users"."id" ASC LIMIT ? OFFSET ?`,
+			ExpectedArgs:      []interface{}{int64(10), int64(10)},
+			ExpectedCountSQL:  `SELECT COUNT(*) FROM "users" WHERE ("users"."title" IS NOT NULL)`,
+			ExpectedCountArgs: []interface{}{},
+		},
+		{
+			Name: `case-insensitive sortOrder`,
+			Request: resource.NewSearchRequestBuilder().
+				SortBy(`userName`).
+				SortOrder(`Ascending`).
+				MustBuild(),
+			ExpectedSQL:       `SELECT * FROM "users" ORDER BY LOWER("users"."userName") ASC, "users"."id" ASC`,
+			ExpectedArgs:      []interface{}{},
+			ExpectedCountSQL:  `SELECT COUNT(*) FROM "users"`,
+			ExpectedCountArgs: []interface{}{},
+		},
+		{
+			Name: `sort by case-exact attribute`,
+			Request: resource.NewSearchRequestBuilder().
+				SortBy(`meta.lastModified`).
+				MustBuild(),
+			ExpectedSQL:       `SELECT * FROM "users" ORDER BY "users"."meta_lastModified" ASC, "users"."id" ASC`,
+			ExpectedArgs:      []interface{}{},
+			ExpectedCountSQL:  `SELECT COUNT(*) FROM "users"`,
+			ExpectedCountArgs: []interface{}{},
+		},
+		{
+			Name: `sort by multi-valued attribute`,
+			Request: resource.NewSearchRequestBuilder().
+				SortBy(`emails`).
+				StartIndex(0).
+				MustBuild(),
+			ExpectedSQL:       `SELECT * FROM "users" ORDER BY (SELECT LOWER("emails"."value") FROM "emails" WHERE ("emails"."users_id" = "users"."id") ORDER BY CASE  WHEN ("emails"."primary" = ?) THEN ? ELSE ? END ASC, LOWER("emails"."value") ASC LIMIT ?) ASC, "users"."id" ASC`,
+			ExpectedArgs:      []interface{}{true, int64(0), int64(1), int64(1)},
+			ExpectedCountSQL:  `SELECT COUNT(*) FROM "users"`,
+			ExpectedCountArgs: []interface{}{},
+		},
+		{
+			Name: `count only`,
+			Request: resource.NewSearchRequestBuilder().
+				Filter(`userName sw "j"`).
+				Count(0).
+				MustBuild(),
+			ExpectedCountSQL:  `SELECT COUNT(*) FROM "users" WHERE (LOWER("users"."userName") LIKE ? ESCAPE '!')`,
+			ExpectedCountArgs: []interface{}{`j%`},
+		},
+		{
+			Name:    `malformed filter`,
+			Request: resource.NewSearchRequestBuilder().Filter(`userName eq`).MustBuild(),
+			Error:   resource.ErrInvalidFilter,
+		},
+		{
+			Name:    `unknown sortBy`,
+			Request: resource.NewSearchRequestBuilder().SortBy(`foo`).MustBuild(),
+			Error:   resource.ErrInvalidValue,
+		},
+		{
+			Name:    `complex sortBy`,
+			Request: resource.NewSearchRequestBuilder().SortBy(`name`).MustBuild(),
+			Error:   resource.ErrInvalidValue,
+		},
+		{
+			Name:    `invalid sortOrder`,
+			Request: resource.NewSearchRequestBuilder().SortBy(`userName`).SortOrder(`up`).MustBuild(),
+			Error:   resource.ErrInvalidValue,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			data, count, err := sqlgen.New(`users`, userSchema).Search(tc.Request)
+			if tc.Error != "" {
+				require.Error(t, err, `Search should fail`)
+				var serr *resource.Error
+				require.True(t, errors.As(err, &serr), `error should be a *resource.Error`)
+				require.Equal(t, http.StatusBadRequest, serr.Status(), `status should be 400`)
+				require.Equal(t, tc.Error, serr.SCIMType(), `scimType should match`)
+				return
+			}
+			require.NoError(t, err, `Search should succeed`)
+
+			if tc.ExpectedSQL == "" {
+				require.Nil(t, data, `data query should be nil`)
+			} else {
+				sql, args, err := data.ToSQL()
+				require.NoError(t, err, `ToSQL should succeed`)
+				require.Equal(t, tc.ExpectedSQL, sql, `SQL should match`)
+				require.Equal(t, tc.ExpectedArgs, args, `arguments should match`)
+			}
+
+			sql, args, err := count.ToSQL()
+			require.NoError(t, err, `ToSQL should succeed`)
+			require.Equal(t, tc.ExpectedCountSQL, sql, `count SQL should match`)
+			require.Equal(t, tc.ExpectedCountArgs, args, `count arguments should match`)
+		})
+	}
+}
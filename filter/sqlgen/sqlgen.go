@@ -0,0 +1,544 @@
+// Package sqlgen translates SCIM filters into SQL expressions.
+//
+// The translator assumes that single-valued attributes of a resource are
+// stored as columns of the resource table, and that the values of each
+// multi-valued attribute are stored in a separate table, one value per row,
+// which refers to the resource table by a foreign key. Filters against
+// multi-valued attributes are translated into `EXISTS` sub-queries, so
+// that all conditions inside a value path (e.g. `emails[type eq "work"
+// and value co "@example.com"]`) are applied to the same value.
+package sqlgen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cybozu-go/scim/filter"
+	"github.com/cybozu-go/scim/resource"
+	"github.com/cybozu-go/scim/schema"
+	goqu "github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+)
+
+// Table describes a table that stores the values of a multi-valued
+// attribute
+type Table struct {
+	// Name is the name of the table
+	Name string
+	// ForeignKey is the column that refers to the primary key of the
+	// resource table
+	ForeignKey string
+}
+
+// likeEscape is the escape character used in LIKE patterns. A backslash
+// is avoided, as it needs to be escaped in string literals of some
+// databases (e.g. MySQL)
+const likeEscape = '!'
+
+// Translator translates SCIM filters against a resource type into
+// SQL expressions. Use `sqlgen.New()` to create one.
+type Translator struct {
+	dialect    goqu.DialectWrapper
+	table      string
+	schema     *resource.Schema
+	columns    map[string]string
+	tables     map[string]Table
+	primaryKey string
+}
+
+// New creates a new Translator for resources stored in table, and
+// described by the schema s.
+//
+// Attributes are mapped to columns using their attribute paths:
+//
+//   - Single-valued attributes are stored in a column named after the
+//     attribute (e.g. `userName`). Sub-attributes of single-valued complex
+//     attributes are stored in a column named after the attribute and
+//     the sub-attribute, joined by an underscore (e.g. `name_familyName`
+//     for `name.familyName`).
+//   - Values of multi-valued attributes are stored in a table named after
+//     the attribute (e.g. `emails`), with a foreign key column named
+//     after the resource table (e.g. `users_id`). The sub-attributes
+//     are stored in columns named after the sub-attribute (e.g. `value`
+//     and `type` for `emails.value` and `emails.type`). The values of
+//     multi-valued attributes without sub-attributes are stored in
+//     the `value` column.
+//
+// Use `sqlgen.WithColumns()` and `sqlgen.WithTables()` to change the
+// defaults. The keys are the attribute paths as they appear in the schema
+// (e.g. `name.familyName`, `emails.value`), prefixed by the schema URI
+// for attributes of schema extensions (e.g.
+// `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber`).
+func New(table string, s *resource.Schema, options ...NewOption) *Translator {
+	t := &Translator{
+		dialect:    goqu.Dialect(`default`),
+		table:      table,
+		schema:     s,
+		primaryKey: `id`,
+	}
+
+	//nolint:forcetypeassert
+	for _, option := range options {
+		switch option.Ident() {
+		case identDialect{}:
+			t.dialect = goqu.Dialect(option.Value().(string))
+		case identColumns{}:
+			t.columns = option.Value().(map[string]string)
+		case identTables{}:
+			t.tables = option.Value().(map[string]Table)
+		case identPrimaryKey{}:
+			t.primaryKey = option.Value().(string)
+		}
+	}
+	return t
+}
+
+// SQL translates the filter into a `SELECT` statement against the
+// resource table. Values are passed as arguments to the prepared statement.
+func (t *Translator) SQL(expr filter.Expr) (string, []interface{}, error) {
+	where, err := t.Where(expr)
+	if err != nil {
+		return "", nil, err
+	}
+	return t.dialect.From(t.table).Prepared(true).Where(where).ToSQL()
+}
+
+// Where translates the filter into an expression that can be used in
+// the `WHERE` clause of a query against the resource table.
+//
+// The filter is validated against the schema using `filter.Validate()`
+// before it is translated, so filters that refer to unknown attributes, or
+// compare values of the wrong type, result in an error that can be
+// returned to the client as is.
+func (t *Translator) Where(expr filter.Expr) (exp.Expression, error) {
+	if t.schema == nil {
+		return nil, fmt.Errorf(`sqlgen: schema must be specified`)
+	}
+	if err := filter.Validate(expr, t.schema); err != nil {
+		return nil, err
+	}
+	return t.translate(t.rootScope(), expr)
+}
+
+// scope describes where the attributes referred to by the filter live
+type scope struct {
+	// table is the table where single-valued attributes are stored
+	table string
+	// prefix is the attribute path of the enclosing attribute, for
+	// filters inside a value path
+	prefix string
+	// attrs is the list of attributes that may be referred to
+	attrs []*resource.SchemaAttribute
+	// multiValued is true if the scope is a single value of a
+	// multi-valued attribute
+	multiValued bool
+}
+
+func (t *Translator) rootScope() *scope {
+	return &scope{
+		table: t.table,
+		attrs: t.schema.Attributes(),
+	}
+}
+
+func (t *Translator) translate(sc *scope, v filter.Expr) (exp.Expression, error) {
+	switch v := v.(type) {
+	case filter.PresenceExpr:
+		return t.translatePresenceExpr(sc, v)
+	case filter.CompareExpr:
+		return t.translateComparison(sc, v.LHE(), v.Operator(), v.RHE())
+	case filter.RegexExpr:
+		return t.translateComparison(sc, v.LHE(), v.Operator(), v.Value())
+	case filter.LogExpr:
+		return t.translateLogExpr(sc, v)
+	case filter.ParenExpr:
+		return t.translateParenExpr(sc, v)
+	case filter.ValuePath:
+		return t.translateValuePath(sc, v)
+	default:
+		return nil, fmt.Errorf(`unhandled expression type: %T`, v)
+	}
+}
+
+// target is a resolved attribute path
+type target struct {
+	// parent is the attribute that was referred to, without the
+	// sub-attribute
+	parent *resource.SchemaAttribute
+	// path is the attribute path of parent
+	path string
+	// attr is the attribute that was referred to. It is the "value"
+	// sub-attribute if a complex multi-valued attribute was referred
+	// to without a sub-attribute
+	attr *resource.SchemaAttribute
+	// column is the column where the values are stored, or the zero
+	// value if the attribute is a single-valued complex attribute
+	column exp.IdentifierExpression
+	// subColumns are the columns of the sub-attributes of a
+	// single-valued complex attribute
+	subColumns []exp.IdentifierExpression
+	// table is non-nil if the values are stored in a separate table
+	table *Table
+}
+
+// resolve resolves the attribute path in the given scope
+func (t *Translator) resolve(sc *scope, v filter.Expr) (*target, error) {
+	ident, ok := v.(filter.IdentifierExpr)
+	if !ok {
+		return nil, fmt.Errorf(`expected identifier, got %T`, v)
+	}
+
+	attrs := sc.attrs
+	var uri string
+	if u := ident.SchemaURI(); u != "" && !strings.EqualFold(u, t.schema.ID()) {
+		if sc.prefix != "" {
+			return nil, fmt.Errorf(`schema URI cannot be used inside a value path: %q`, ident.Lit())
+		}
+		ext, ok := schema.Get(u)
+		if !ok {
+			return nil, fmt.Errorf(`unknown schema %q`, u)
+		}
+		uri = ext.ID()
+		attrs = ext.Attributes()
+	}
+
+	attr := schema.FindAttribute(attrs, ident.Attribute())
+	if attr == nil {
+		return nil, fmt.Errorf(`unknown attribute %q`, ident.Lit())
+	}
+
+	var sub *resource.SchemaAttribute
+	if name := ident.SubAttribute(); name != "" {
+		sub = schema.FindAttribute(attr.SubAttributes(), name)
+		if sub == nil {
+			return nil, fmt.Errorf(`unknown attribute %q`, ident.Lit())
+		}
+	}
+
+	path := attr.Name()
+	if sc.prefix != "" {
+		path = sc.prefix + `.` + path
+	}
+	if uri != "" {
+		path = uri + `:` + path
+	}
+
+	if attr.MultiValued() && !sc.multiValued {
+		table := t.tableFor(path, attr)
+		column := `value`
+		target := &target{parent: attr, path: path, attr: attr, table: &table}
+		if sub != nil {
+			target.attr = sub
+			column = sub.Name()
+		} else if value := schema.FindAttribute(attr.SubAttributes(), `value`); value != nil {
+			target.attr = value
+		}
+		target.column = goqu.C(t.column(path+`.`+column, column)).Table(table.Name)
+		return target, nil
+	}
+
+	// values of multi-valued attributes are stored in separate tables,
+	// so the sub-attributes are named after themselves
+	defaultColumn := func(path string) string {
+		if sc.multiValued {
+			return path[strings.LastIndexByte(path, '.')+1:]
+		}
+		if i := strings.LastIndexByte(path, ':'); i >= 0 {
+			path = path[i+1:]
+		}
+		return strings.ReplaceAll(path, `.`, `_`)
+	}
+
+	target := &target{parent: attr, path: path, attr: attr}
+	switch {
+	case sub != nil:
+		path := path + `.` + sub.Name()
+		target.attr = sub
+		target.column = goqu.C(t.column(path, defaultColumn(path))).Table(sc.table)
+	case attr.Type() == resource.Complex:
+		for _, sub := range attr.SubAttributes() {
+			path := path + `.` + sub.Name()
+			target.subColumns = append(target.subColumns, goqu.C(t.column(path, defaultColumn(path))).Table(sc.table))
+		}
+	default:
+		target.column = goqu.C(t.column(path, defaultColumn(path))).Table(sc.table)
+	}
+	return target, nil
+}
+
+func (t *Translator) column(path, def string) string {
+	if column, ok := t.columns[path]; ok {
+		return column
+	}
+	return def
+}
+
+// tableFor returns the table that stores the values of the multi-valued
+// attribute at path
+func (t *Translator) tableFor(path string, attr *resource.SchemaAttribute) Table {
+	if table, ok := t.tables[path]; ok {
+		return table
+	}
+	return Table{
+		Name:       attr.Name(),
+		ForeignKey: t.table + `_id`,
+	}
+}
+
+// exists creates an `EXISTS` sub-query against the table that stores the
+// values of a multi-valued attribute
+func (t *Translator) exists(table *Table, conds ...exp.Expression) exp.Expression {
+	conds = append([]exp.Expression{
+		goqu.C(table.ForeignKey).Table(table.Name).Eq(goqu.C(t.primaryKey).Table(t.table)),
+	}, conds...)
+	return goqu.L(`EXISTS ?`, t.dialect.From(table.Name).Select(goqu.L(`1`)).Where(conds...))
+}
+
+func (t *Translator) translatePresenceExpr(sc *scope, v filter.PresenceExpr) (exp.Expression, error) {
+	target, err := t.resolve(sc, v.Attr())
+	if err != nil {
+		return nil, fmt.Errorf(`left hand side of %q is not valid: %w`, v.Operator(), err)
+	}
+	return t.presence(target), nil
+}
+
+func (t *Translator) presence(target *target) exp.Expression {
+	if target.subColumns != nil {
+		list := make([]exp.Expression, len(target.subColumns))
+		for i, column := range target.subColumns {
+			list[i] = column.IsNotNull()
+		}
+		return goqu.Or(list...)
+	}
+
+	if target.table != nil {
+		return t.exists(target.table, target.column.IsNotNull())
+	}
+	return target.column.IsNotNull()
+}
+
+func (t *Translator) translateComparison(sc *scope, lhe filter.Expr, op string, rhe interface{}) (exp.Expression, error) {
+	target, err := t.resolve(sc, lhe)
+	if err != nil {
+		return nil, fmt.Errorf(`left hand side of %q is not valid: %w`, op, err)
+	}
+
+	value, err := literal(rhe)
+	if err != nil {
+		return nil, fmt.Errorf(`right hand side of %q is not valid: %w`, op, err)
+	}
+
+	if value == nil {
+		present := t.presence(target)
+		switch op {
+		case filter.EqualOp:
+			return negate(present), nil
+		case filter.NotEqualOp:
+			return present, nil
+		default:
+			return nil, fmt.Errorf(`operator %q cannot be used against null`, op)
+		}
+	}
+
+	if target.subColumns != nil {
+		return nil, fmt.Errorf(`complex attribute %q cannot be compared`, target.attr.Name())
+	}
+
+	expr, err := compare(target.column, target.attr, op, value)
+	if err != nil {
+		return nil, err
+	}
+	if target.table != nil {
+		return t.exists(target.table, expr), nil
+	}
+	return expr, nil
+}
+
+// literal converts the comparison value of a filter into a Go value
+func literal(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case filter.AttrValueExpr:
+		return v.Lit(), nil
+	case filter.NumberExpr:
+		return v.Lit(), nil
+	case filter.DecimalExpr:
+		return v.Lit(), nil
+	case filter.BoolExpr:
+		return v.Lit(), nil
+	case filter.IdentifierExpr:
+		if v.Lit() == filter.Null {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(`unexpected identifier %q`, v.Lit())
+	default:
+		return nil, fmt.Errorf(`unhandled value type: %T`, v)
+	}
+}
+
+// comparableExpr is an expression that can be compared against a value
+type comparableExpr interface {
+	exp.Expression
+	exp.Comparable
+}
+
+// compare creates the comparison of the value of an attribute against
+// the value in the filter
+func compare(lhs comparableExpr, attr *resource.SchemaAttribute, op string, value interface{}) (exp.Expression, error) {
+
+	if s, ok := value.(string); ok {
+		switch attr.Type() {
+		case resource.DateTime:
+			t, err := resource.ParseDateTime(s)
+			if err != nil {
+				return nil, fmt.Errorf(`failed to parse %q as dateTime: %w`, s, err)
+			}
+			value = t
+		case resource.Binary:
+			// base64 encoded values are always case-exact
+		default:
+			if !attr.CaseExact() {
+				lhs = goqu.Func(`LOWER`, lhs)
+				s = strings.ToLower(s)
+				value = s
+			}
+		}
+
+		switch op {
+		case filter.ContainsOp:
+			return like(lhs, `%`+escapeLike(s)+`%`), nil
+		case filter.StartsWithOp:
+			return like(lhs, escapeLike(s)+`%`), nil
+		case filter.EndsWithOp:
+			return like(lhs, `%`+escapeLike(s)), nil
+		}
+	}
+
+	// goqu translates comparisons against booleans into `IS TRUE` and
+	// `IS NOT TRUE`, but `ne` must not match when the value is NULL
+	if _, ok := value.(bool); ok {
+		value = goqu.V(value)
+	}
+
+	switch op {
+	case filter.EqualOp:
+		return lhs.Eq(value), nil
+	case filter.NotEqualOp:
+		return lhs.Neq(value), nil
+	case filter.GreaterThanOp:
+		return lhs.Gt(value), nil
+	case filter.GreaterThanOrEqualToOp:
+		return lhs.Gte(value), nil
+	case filter.LessThanOp:
+		return lhs.Lt(value), nil
+	case filter.LessThanOrEqualToOp:
+		return lhs.Lte(value), nil
+	default:
+		return nil, fmt.Errorf(`operator %q cannot be used against %T values`, op, value)
+	}
+}
+
+func like(lhs exp.Expression, pattern string) exp.Expression {
+	return goqu.L(`(? LIKE ? ESCAPE '`+string(likeEscape)+`')`, lhs, pattern)
+}
+
+// escapeLike escapes the characters that have special meanings in
+// LIKE patterns
+func escapeLike(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch r {
+		case '%', '_', likeEscape:
+			sb.WriteRune(likeEscape)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func (t *Translator) translateLogExpr(sc *scope, v filter.LogExpr) (exp.Expression, error) {
+	lhs, err := t.translate(sc, v.LHE())
+	if err != nil {
+		return nil, fmt.Errorf(`failed to translate left hand side of %q: %w`, v.Operator(), err)
+	}
+	rhs, err := t.translate(sc, v.RHS())
+	if err != nil {
+		return nil, fmt.Errorf(`failed to translate right hand side of %q: %w`, v.Operator(), err)
+	}
+
+	switch v.Operator() {
+	case filter.AndOp:
+		return goqu.And(lhs, rhs), nil
+	case filter.OrOp:
+		return goqu.Or(lhs, rhs), nil
+	default:
+		return nil, fmt.Errorf(`unhandled logical operator %q`, v.Operator())
+	}
+}
+
+func (t *Translator) translateParenExpr(sc *scope, v filter.ParenExpr) (exp.Expression, error) {
+	sub, err := t.translate(sc, v.SubExpr())
+	if err != nil {
+		return nil, err
+	}
+
+	switch v.Operator() {
+	case "":
+		return sub, nil
+	case filter.NotOp:
+		return negate(sub), nil
+	default:
+		return nil, fmt.Errorf(`unhandled grouping operator %q`, v.Operator())
+	}
+}
+
+// negate negates the boolean expression e. Comparisons against NULL
+// evaluate to NULL rather than false in SQL, and a bare NOT would
+// keep them NULL, excluding the rows in which the attribute is not
+// present. As such rows do not match the expression, they must match
+// its negation, which is how `filter.Match()` treats them.
+func negate(e exp.Expression) exp.Expression {
+	return goqu.L(`NOT COALESCE(?, FALSE)`, e)
+}
+
+func (t *Translator) translateValuePath(sc *scope, v filter.ValuePath) (exp.Expression, error) {
+	if sc.prefix != "" {
+		return nil, fmt.Errorf(`value paths cannot be nested`)
+	}
+
+	ident, ok := v.ParentAttr().(filter.IdentifierExpr)
+	if !ok || ident.SubAttribute() != "" {
+		return nil, fmt.Errorf(`parent attribute of value path is not valid: %v`, v.ParentAttr())
+	}
+	target, err := t.resolve(sc, ident)
+	if err != nil {
+		return nil, fmt.Errorf(`parent attribute of value path is not valid: %w`, err)
+	}
+
+	if v.SubExpr() == nil {
+		return t.presence(target), nil
+	}
+
+	// The filter is applied to each of the values, which are stored as
+	// rows of a separate table for multi-valued attributes, or as columns
+	// of the current table for single-valued attributes
+	sub := &scope{
+		table:  sc.table,
+		prefix: target.path,
+		attrs:  target.parent.SubAttributes(),
+	}
+	if target.table != nil {
+		sub.table = target.table.Name
+		sub.multiValued = true
+	}
+
+	expr, err := t.translate(sub, v.SubExpr())
+	if err != nil {
+		return nil, fmt.Errorf(`failed to translate filter for value path: %w`, err)
+	}
+	if target.table != nil {
+		return t.exists(target.table, expr), nil
+	}
+	return expr, nil
+}
@@ -0,0 +1,230 @@
+package sqlgen_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cybozu-go/scim/filter"
+	"github.com/cybozu-go/scim/filter/sqlgen"
+	"github.com/cybozu-go/scim/resource"
+	"github.com/cybozu-go/scim/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQL(t *testing.T) {
+	userSchema, ok := schema.Get(resource.UserSchemaURI)
+	require.True(t, ok, `schema.Get should succeed`)
+
+	testcases := []struct {
+		Filter       string
+		Options      []sqlgen.NewOption
+		ExpectedSQL  string
+		ExpectedArgs []interface{}
+		Error        bool
+	}{
+		{
+			Filter:       `userName eq "bjensen"`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE (LOWER("users"."userName") = ?)`,
+			ExpectedArgs: []interface{}{`bjensen`},
+		},
+		{
+			Filter:       `USERNAME eq "BJensen"`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE (LOWER("users"."userName") = ?)`,
+			ExpectedArgs: []interface{}{`bjensen`},
+		},
+		{
+			Filter:       `name.familyName co "O'Malley"`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE (LOWER("users"."name_familyName") LIKE ? ESCAPE '!')`,
+			ExpectedArgs: []interface{}{`%o'malley%`},
+		},
+		{
+			Filter: `name.familyName sw "J"`,
+			Options: []sqlgen.NewOption{
+				sqlgen.WithColumns(map[string]string{`name.familyName`: `family_name`}),
+			},
+			ExpectedSQL:  `SELECT * FROM "users" WHERE (LOWER("users"."family_name") LIKE ? ESCAPE '!')`,
+			ExpectedArgs: []interface{}{`j%`},
+		},
+		{
+			Filter:       `displayName ew "100%_off!"`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE (LOWER("users"."displayName") LIKE ? ESCAPE '!')`,
+			ExpectedArgs: []interface{}{`%100!%!_off!!`},
+		},
+		{
+			Filter:       `title pr`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE ("users"."title" IS NOT NULL)`,
+			ExpectedArgs: []interface{}{},
+		},
+		{
+			Filter:       `name pr`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE (("users"."name_formatted" IS NOT NULL) OR ("users"."name_familyName" IS NOT NULL) OR ("users"."name_givenName" IS NOT NULL) OR ("users"."name_middleName" IS NOT NULL) OR ("users"."name_honorificPrefix" IS NOT NULL) OR ("users"."name_honorificSuffix" IS NOT NULL))`,
+			ExpectedArgs: []interface{}{},
+		},
+		{
+			Filter:       `nickName eq null`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE NOT COALESCE(("users"."nickName" IS NOT NULL), FALSE)`,
+			ExpectedArgs: []interface{}{},
+		},
+		{
+			Filter:       `active eq true`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE ("users"."active" = ?)`,
+			ExpectedArgs: []interface{}{true},
+		},
+		{
+			Filter:       `meta.lastModified gt "2011-05-13T04:42:34Z"`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE ("users"."meta_lastModified" > ?)`,
+			ExpectedArgs: []interface{}{time.Date(2011, 5, 13, 4, 42, 34, 0, time.UTC)},
+		},
+		{
+			Filter:       `meta.version eq "W/\"3694e05e9dff591\""`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE ("users"."meta_version" = ?)`,
+			ExpectedArgs: []interface{}{`W/"3694e05e9dff591"`},
+		},
+		{
+			Filter:       `title pr and not (userType eq "Employee" or userType eq "Intern")`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE (("users"."title" IS NOT NULL) AND NOT COALESCE(((LOWER("users"."userType") = ?) OR (LOWER("users"."userType") = ?)), FALSE))`,
+			ExpectedArgs: []interface{}{`employee`, `intern`},
+		},
+		{
+			// rows in which userType is NULL do not match the comparison,
+			// and must therefore match its negation, as in filter.Match()
+			Filter:       `not (userType eq "Employee")`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE NOT COALESCE((LOWER("users"."userType") = ?), FALSE)`,
+			ExpectedArgs: []interface{}{`employee`},
+		},
+		{
+			Filter: `meta.lastModified ge "2011-05-13T04:42:34Z"`,
+			Options: []sqlgen.NewOption{
+				sqlgen.WithColumns(map[string]string{`meta.lastModified`: `lastModified`}),
+			},
+			ExpectedSQL:  `SELECT * FROM "users" WHERE ("users"."lastModified" >= ?)`,
+			ExpectedArgs: []interface{}{time.Date(2011, 5, 13, 4, 42, 34, 0, time.UTC)},
+		},
+		{
+			Filter: `meta.lastModified lt "2011-05-13T04:42:34Z"`,
+			Options: []sqlgen.NewOption{
+				sqlgen.WithColumns(map[string]string{`meta.lastModified`: `lastModified`}),
+			},
+			ExpectedSQL:  `SELECT * FROM "users" WHERE ("users"."lastModified" < ?)`,
+			ExpectedArgs: []interface{}{time.Date(2011, 5, 13, 4, 42, 34, 0, time.UTC)},
+		},
+		{
+			Filter: `meta.lastModified le "2011-05-13T04:42:34Z"`,
+			Options: []sqlgen.NewOption{
+				sqlgen.WithColumns(map[string]string{`meta.lastModified`: `lastModified`}),
+			},
+			ExpectedSQL:  `SELECT * FROM "users" WHERE ("users"."lastModified" <= ?)`,
+			ExpectedArgs: []interface{}{time.Date(2011, 5, 13, 4, 42, 34, 0, time.UTC)},
+		},
+		{
+			Filter:       `title pr or userType eq "Intern"`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE (("users"."title" IS NOT NULL) OR (LOWER("users"."userType") = ?))`,
+			ExpectedArgs: []interface{}{`intern`},
+		},
+		{
+			Filter:       `userType eq "Employee" and (emails.type eq "work")`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE ((LOWER("users"."userType") = ?) AND EXISTS (SELECT 1 FROM "emails" WHERE (("emails"."users_id" = "users"."id") AND (LOWER("emails"."type") = ?))))`,
+			ExpectedArgs: []interface{}{`employee`, `work`},
+		},
+		{
+			// complex attributes are compared through their sub-attributes,
+			// as `emails co "example.com"` is rejected by filter.Validate()
+			Filter:       `userType ne "Employee" and not (emails.value co "example.com" or emails.value co "example.org")`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE ((LOWER("users"."userType") != ?) AND NOT COALESCE((EXISTS (SELECT 1 FROM "emails" WHERE (("emails"."users_id" = "users"."id") AND (LOWER("emails"."value") LIKE ? ESCAPE '!'))) OR EXISTS (SELECT 1 FROM "emails" WHERE (("emails"."users_id" = "users"."id") AND (LOWER("emails"."value") LIKE ? ESCAPE '!')))), FALSE))`,
+			ExpectedArgs: []interface{}{`employee`, `%example.com%`, `%example.org%`},
+		},
+		{
+			Filter:       `emails[type eq "work" and value co "@example.com"] or ims[type eq "xmpp" and value co "@foo.com"]`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE (EXISTS (SELECT 1 FROM "emails" WHERE (("emails"."users_id" = "users"."id") AND ((LOWER("emails"."type") = ?) AND (LOWER("emails"."value") LIKE ? ESCAPE '!')))) OR EXISTS (SELECT 1 FROM "ims" WHERE (("ims"."users_id" = "users"."id") AND ((LOWER("ims"."type") = ?) AND (LOWER("ims"."value") LIKE ? ESCAPE '!')))))`,
+			ExpectedArgs: []interface{}{`work`, `%@example.com%`, `xmpp`, `%@foo.com%`},
+		},
+		{
+			Filter:       `emails.value co "@example.com"`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE EXISTS (SELECT 1 FROM "emails" WHERE (("emails"."users_id" = "users"."id") AND (LOWER("emails"."value") LIKE ? ESCAPE '!')))`,
+			ExpectedArgs: []interface{}{`%@example.com%`},
+		},
+		{
+			Filter:       `emails.type eq "work"`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE EXISTS (SELECT 1 FROM "emails" WHERE (("emails"."users_id" = "users"."id") AND (LOWER("emails"."type") = ?)))`,
+			ExpectedArgs: []interface{}{`work`},
+		},
+		{
+			Filter: `emails[type eq "work" and value co "@example.com"]`,
+			Options: []sqlgen.NewOption{
+				sqlgen.WithTables(map[string]sqlgen.Table{`emails`: {Name: `user_emails`, ForeignKey: `user_id`}}),
+				sqlgen.WithColumns(map[string]string{`emails.value`: `email`}),
+				sqlgen.WithPrimaryKey(`pk`),
+			},
+			ExpectedSQL:  `SELECT * FROM "users" WHERE EXISTS (SELECT 1 FROM "user_emails" WHERE (("user_emails"."user_id" = "users"."pk") AND ((LOWER("user_emails"."type") = ?) AND (LOWER("user_emails"."email") LIKE ? ESCAPE '!'))))`,
+			ExpectedArgs: []interface{}{`work`, `%@example.com%`},
+		},
+		{
+			Filter:       `emails pr`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE EXISTS (SELECT 1 FROM "emails" WHERE (("emails"."users_id" = "users"."id") AND ("emails"."value" IS NOT NULL)))`,
+			ExpectedArgs: []interface{}{},
+		},
+		{
+			Filter:       `name[familyName eq "Jensen"]`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE (LOWER("users"."name_familyName") = ?)`,
+			ExpectedArgs: []interface{}{`jensen`},
+		},
+		{
+			Filter:       `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber eq "701984"`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE (LOWER("users"."employeeNumber") = ?)`,
+			ExpectedArgs: []interface{}{`701984`},
+		},
+		{
+			Filter:       `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.displayName sw "John"`,
+			ExpectedSQL:  `SELECT * FROM "users" WHERE (LOWER("users"."manager_displayName") LIKE ? ESCAPE '!')`,
+			ExpectedArgs: []interface{}{`john%`},
+		},
+		{
+			Filter: `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager[displayName sw "John"]`,
+			Options: []sqlgen.NewOption{
+				sqlgen.WithColumns(map[string]string{`urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.displayName`: `manager_name`}),
+			},
+			ExpectedSQL:  `SELECT * FROM "users" WHERE (LOWER("users"."manager_name") LIKE ? ESCAPE '!')`,
+			ExpectedArgs: []interface{}{`john%`},
+		},
+		{
+			Filter: `userName eq "bjensen"`,
+			Options: []sqlgen.NewOption{
+				sqlgen.WithDialect(`postgres`),
+			},
+			ExpectedSQL:  `SELECT * FROM "users" WHERE (LOWER("users"."userName") = $1)`,
+			ExpectedArgs: []interface{}{`bjensen`},
+		},
+		{
+			Filter: `emails.vaule eq "bjensen@example.com"`,
+			Error:  true,
+		},
+		{
+			Filter: `active gt true`,
+			Error:  true,
+		},
+		{
+			Filter: `emails co "example.com"`,
+			Error:  true,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Filter, func(t *testing.T) {
+			expr, err := filter.Parse(tc.Filter)
+			require.NoError(t, err, `filter.Parse should succeed`)
+
+			sql, args, err := sqlgen.New(`users`, userSchema, tc.Options...).SQL(expr)
+			if tc.Error {
+				require.Error(t, err, `SQL should fail`)
+				var serr *resource.Error
+				require.True(t, errors.As(err, &serr), `error should be a *resource.Error`)
+				return
+			}
+			require.NoError(t, err, `SQL should succeed`)
+			require.Equal(t, tc.ExpectedSQL, sql, `SQL should match`)
+			require.Equal(t, tc.ExpectedArgs, args, `arguments should match`)
+		})
+	}
+}
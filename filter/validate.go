@@ -0,0 +1,208 @@
+package filter
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/cybozu-go/scim/resource"
+	"github.com/cybozu-go/scim/schema"
+)
+
+// schemasAttr describes the `schemas` attribute, which is common to
+// all resources but is not listed in the schema definitions
+var schemasAttr = resource.NewSchemaAttributeBuilder().
+	Name(`schemas`).
+	Type(resource.Reference).
+	MultiValued(true).
+	CaseExact(true).
+	MustBuild()
+
+// Validate checks the filter expression against the schema, and
+// reports the first problem that it finds.
+//
+// Attribute paths, including those qualified by the schema URI of an
+// extension (e.g. `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber`),
+// must refer to an attribute that is defined in the schema, and the
+// operator must be applicable to the type of the attribute and the
+// comparison value. For example, `gt` cannot be used against boolean
+// attributes, and `co` cannot be used against complex attributes.
+//
+// The error is returned as a *resource.Error with its status set to
+// 400 and its scimType set to `invalidFilter`, so that it can be sent
+// to the client as is.
+func Validate(expr Expr, s *resource.Schema) error {
+	if s == nil {
+		return fmt.Errorf(`filter.Validate: schema must not be nil`)
+	}
+	v := &validator{uri: s.ID(), attrs: s.Attributes()}
+	return v.validate(expr)
+}
+
+func invalidFilter(f string, args ...interface{}) error {
+	return resource.NewErrorBuilder().
+		Status(http.StatusBadRequest).
+		SCIMType(resource.ErrInvalidFilter).
+		Detail(fmt.Sprintf(f, args...)).
+		MustBuild()
+}
+
+type validator struct {
+	uri   string
+	attrs []*resource.SchemaAttribute
+}
+
+func (v *validator) validate(e Expr) error {
+	switch e := e.(type) {
+	case PresenceExpr:
+		_, err := v.attribute(e.Attr())
+		return err
+	case CompareExpr:
+		return v.validateComparison(e.LHE(), e.Operator(), e.RHE())
+	case RegexExpr:
+		return v.validateComparison(e.LHE(), e.Operator(), e.Value())
+	case LogExpr:
+		switch e.Operator() {
+		case AndOp, OrOp:
+		default:
+			return invalidFilter(`invalid logical operator %q`, e.Operator())
+		}
+		if err := v.validate(e.LHE()); err != nil {
+			return err
+		}
+		return v.validate(e.RHS())
+	case ParenExpr:
+		switch e.Operator() {
+		case "", NotOp:
+		default:
+			return invalidFilter(`invalid grouping operator %q`, e.Operator())
+		}
+		return v.validate(e.SubExpr())
+	case ValuePath:
+		attr, err := v.attribute(e.ParentAttr())
+		if err != nil {
+			return err
+		}
+		if attr.Type() != resource.Complex {
+			return invalidFilter(`attribute %q is not a complex attribute, and cannot be used in a value path`, attr.Name())
+		}
+		if e.SubExpr() == nil {
+			return nil
+		}
+		sub := &validator{attrs: attr.SubAttributes()}
+		return sub.validate(e.SubExpr())
+	default:
+		return invalidFilter(`unexpected expression type %T`, e)
+	}
+}
+
+// attribute resolves the attribute path expression to its definition
+func (v *validator) attribute(e Expr) (*resource.SchemaAttribute, error) {
+	name, err := attrName(e)
+	if err != nil {
+		return nil, invalidFilter(`invalid attribute path: %s`, err)
+	}
+
+	path := parseAttrPath(name)
+	attrs := v.attrs
+	if path.uri != "" && !strings.EqualFold(path.uri, v.uri) {
+		s, ok := schema.Get(path.uri)
+		if !ok {
+			return nil, invalidFilter(`unknown schema %q in attribute path %q`, path.uri, name)
+		}
+		attrs = s.Attributes()
+	}
+
+	if v.uri != "" && path.uri == "" && len(path.names) == 1 && strings.EqualFold(path.names[0], schemasAttr.Name()) {
+		return schemasAttr, nil
+	}
+
+	attr := findAttributePath(attrs, path.names)
+	if attr == nil {
+		return nil, invalidFilter(`unknown attribute %q`, name)
+	}
+	return attr, nil
+}
+
+func (v *validator) validateComparison(lhe Expr, op string, rhe interface{}) error {
+	attr, err := v.attribute(lhe)
+	if err != nil {
+		return err
+	}
+
+	rhs, err := literal(rhe)
+	if err != nil {
+		return invalidFilter(`invalid comparison value for %q: %s`, attr.Name(), err)
+	}
+
+	if rhs == nil {
+		switch op {
+		case EqualOp, NotEqualOp:
+			return nil
+		default:
+			return invalidFilter(`operator %q cannot be used against null`, op)
+		}
+	}
+
+	switch typ := attr.Type(); typ {
+	case resource.Complex:
+		return invalidFilter(`operator %q cannot be used against complex attribute %q`, op, attr.Name())
+	case resource.Boolean:
+		switch op {
+		case EqualOp, NotEqualOp:
+		default:
+			return invalidFilter(`operator %q cannot be used against boolean attribute %q`, op, attr.Name())
+		}
+		if _, ok := rhs.(bool); !ok {
+			return invalidFilter(`attribute %q expects a boolean value`, attr.Name())
+		}
+	case resource.Integer, resource.Decimal:
+		if err := validateOrderedOp(op, attr); err != nil {
+			return err
+		}
+		if _, ok := rhs.(float64); !ok {
+			return invalidFilter(`attribute %q expects a numeric value`, attr.Name())
+		}
+	case resource.DateTime:
+		if err := validateOrderedOp(op, attr); err != nil {
+			return err
+		}
+		s, ok := rhs.(string)
+		if !ok {
+			return invalidFilter(`attribute %q expects a dateTime value`, attr.Name())
+		}
+		if _, err := resource.ParseDateTime(s); err != nil {
+			return invalidFilter(`attribute %q expects a dateTime value: %s`, attr.Name(), err)
+		}
+	case resource.Binary:
+		switch op {
+		case EqualOp, NotEqualOp:
+		default:
+			return invalidFilter(`operator %q cannot be used against binary attribute %q`, op, attr.Name())
+		}
+		s, ok := rhs.(string)
+		if !ok {
+			return invalidFilter(`attribute %q expects a base64 encoded value`, attr.Name())
+		}
+		if _, err := base64.StdEncoding.DecodeString(s); err != nil {
+			return invalidFilter(`attribute %q expects a base64 encoded value: %s`, attr.Name(), err)
+		}
+	default:
+		if _, ok := rhs.(string); !ok {
+			return invalidFilter(`attribute %q expects a string value`, attr.Name())
+		}
+	}
+	return nil
+}
+
+// validateOrderedOp checks that op can be used against attributes
+// whose values are ordered, but are not strings
+func validateOrderedOp(op string, attr *resource.SchemaAttribute) error {
+	switch op {
+	case EqualOp, NotEqualOp, GreaterThanOp, GreaterThanOrEqualToOp, LessThanOp, LessThanOrEqualToOp:
+		return nil
+	default:
+		return invalidFilter(`operator %q cannot be used against %s attribute %q`, op, attr.Type(), attr.Name())
+	}
+}
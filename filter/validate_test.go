@@ -0,0 +1,78 @@
+package filter_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/cybozu-go/scim/filter"
+	"github.com/cybozu-go/scim/resource"
+	"github.com/cybozu-go/scim/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	userSchema, ok := schema.Get(resource.UserSchemaURI)
+	require.True(t, ok, `schema.Get should succeed`)
+	groupSchema, ok := schema.Get(resource.GroupSchemaURI)
+	require.True(t, ok, `schema.Get should succeed`)
+
+	testcases := []struct {
+		Filter string
+		Schema *resource.Schema
+		Error  bool
+	}{
+		{Filter: `userName eq "bjensen"`, Schema: userSchema},
+		{Filter: `USERNAME sw "b"`, Schema: userSchema},
+		{Filter: `name.familyName co "O'Malley"`, Schema: userSchema},
+		{Filter: `emails.value ew "@example.com"`, Schema: userSchema},
+		{Filter: `emails.vaule ew "@example.com"`, Schema: userSchema, Error: true},
+		{Filter: `emails[type eq "work" and value co "@example.com"]`, Schema: userSchema},
+		{Filter: `emails[type eq "work" and vaule co "@example.com"]`, Schema: userSchema, Error: true},
+		{Filter: `userName[type eq "work"]`, Schema: userSchema, Error: true},
+		{Filter: `title pr`, Schema: userSchema},
+		{Filter: `nickName eq null`, Schema: userSchema},
+		{Filter: `nickName gt null`, Schema: userSchema, Error: true},
+		{Filter: `active eq true`, Schema: userSchema},
+		{Filter: `active gt true`, Schema: userSchema, Error: true},
+		{Filter: `active eq "x"`, Schema: userSchema, Error: true},
+		{Filter: `userName eq true`, Schema: userSchema, Error: true},
+		{Filter: `emails co "example.com"`, Schema: userSchema, Error: true},
+		{Filter: `meta.lastModified gt "2011-05-13T04:42:34Z"`, Schema: userSchema},
+		{Filter: `meta.lastModified gt "yesterday"`, Schema: userSchema, Error: true},
+		{Filter: `meta.lastModified co "2011"`, Schema: userSchema, Error: true},
+		{Filter: `x509Certificates.value eq "TUlJRFF6Q0NBcXlnQXdJQkFnSUNFQUF3"`, Schema: userSchema},
+		{Filter: `x509Certificates.value eq "not base64!"`, Schema: userSchema, Error: true},
+		{Filter: `x509Certificates.value sw "TUlJRFF6"`, Schema: userSchema, Error: true},
+		{Filter: `userName gt 1.5`, Schema: userSchema, Error: true},
+		{Filter: `schemas eq "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"`, Schema: userSchema},
+		{Filter: `title pr and not (userType eq "Employee" or foo eq "bar")`, Schema: userSchema, Error: true},
+		{Filter: `urn:ietf:params:scim:schemas:core:2.0:User:userName sw "bj"`, Schema: userSchema},
+		{Filter: `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber eq "701984"`, Schema: userSchema},
+		{Filter: `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.displayName sw "john"`, Schema: userSchema},
+		{Filter: `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:userName eq "bjensen"`, Schema: userSchema, Error: true},
+		{Filter: `urn:example:unknown:2.0:User:userName eq "bjensen"`, Schema: userSchema, Error: true},
+		{Filter: `members[value eq "2819c223-7f76-453a-919d-413861904646"]`, Schema: groupSchema},
+		{Filter: `userName eq "bjensen"`, Schema: groupSchema, Error: true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Filter, func(t *testing.T) {
+			expr, err := filter.Parse(tc.Filter)
+			require.NoError(t, err, `filter.Parse should succeed`)
+
+			err = filter.Validate(expr, tc.Schema)
+			if !tc.Error {
+				require.NoError(t, err, `filter.Validate should succeed`)
+				return
+			}
+			require.Error(t, err, `filter.Validate should fail`)
+
+			var serr *resource.Error
+			require.True(t, errors.As(err, &serr), `error should be a *resource.Error`)
+			require.Equal(t, http.StatusBadRequest, serr.Status(), `status should be 400`)
+			require.Equal(t, resource.ErrInvalidFilter, serr.SCIMType(), `scimType should be invalidFilter`)
+		})
+	}
+}
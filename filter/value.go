@@ -0,0 +1,149 @@
+package filter
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/cybozu-go/scim/filter/internal/expr"
+	"github.com/cybozu-go/scim/resource"
+	"github.com/cybozu-go/scim/schema"
+)
+
+// getter is implemented by all resource objects
+type getter interface {
+	Get(string, interface{}) error
+}
+
+// keyer is implemented by all resource objects
+type keyer interface {
+	Keys() []string
+}
+
+// lookupKey fetches the value associated with name from v, which
+// may be either a resource object (i.e. anything that implements
+// both Keys() and Get()), or a map[string]interface{}.
+//
+// Attribute names in SCIM are case-insensitive (RFC7643 Section 2.1),
+// so the key is compared without regards to its case.
+func lookupKey(v interface{}, name string) (interface{}, bool) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if val, ok := v[name]; ok {
+			return val, true
+		}
+		for key, val := range v {
+			if strings.EqualFold(key, name) {
+				return val, true
+			}
+		}
+	case keyer:
+		g, ok := v.(getter)
+		if !ok {
+			return nil, false
+		}
+		for _, key := range v.Keys() {
+			if !strings.EqualFold(key, name) {
+				continue
+			}
+			var val interface{}
+			if err := g.Get(key, &val); err != nil {
+				return nil, false
+			}
+			return val, true
+		}
+	}
+	return nil, false
+}
+
+// flatten converts v to a list of values. Slices are expanded
+// into their elements, and nil values are dropped
+func flatten(v interface{}) []interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return v
+	case []string:
+		list := make([]interface{}, len(v))
+		for i, e := range v {
+			list[i] = e
+		}
+		return list
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		list := make([]interface{}, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			list = append(list, rv.Index(i).Interface())
+		}
+		return list
+	case reflect.Ptr, reflect.Map, reflect.Interface:
+		if rv.IsNil() {
+			return nil
+		}
+	}
+	return []interface{}{v}
+}
+
+// resolve follows the list of attribute names starting from each
+// of the given values, and returns the list of values found at
+// the end of the path. Multi-valued attributes found along the
+// way are expanded, so the result contains all possible values
+func resolve(values []interface{}, names []string) []interface{} {
+	for _, name := range names {
+		var next []interface{}
+		for _, v := range values {
+			val, ok := lookupKey(v, name)
+			if !ok {
+				continue
+			}
+			next = append(next, flatten(val)...)
+		}
+		values = next
+	}
+	return values
+}
+
+// isComplex returns true if the value is a complex attribute value
+func isComplex(v interface{}) bool {
+	switch v.(type) {
+	case map[string]interface{}:
+		return true
+	case keyer:
+		_, ok := v.(getter)
+		return ok
+	}
+	return false
+}
+
+// attrPath represents an attribute path such as `name.familyName` or
+// `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber`
+type attrPath struct {
+	uri   string
+	names []string
+}
+
+func parseAttrPath(s string) attrPath {
+	uri, attr, sub := expr.SplitAttrPath(s)
+	names := []string{attr}
+	if sub != "" {
+		names = append(names, strings.Split(sub, ".")...)
+	}
+	return attrPath{uri: uri, names: names}
+}
+
+// findAttributePath looks for the attribute specified by the list of
+// names, descending into sub-attributes as necessary
+func findAttributePath(attrs []*resource.SchemaAttribute, names []string) *resource.SchemaAttribute {
+	var attr *resource.SchemaAttribute
+	for _, name := range names {
+		attr = schema.FindAttribute(attrs, name)
+		if attr == nil {
+			return nil
+		}
+		attrs = attr.SubAttributes()
+	}
+	return attr
+}
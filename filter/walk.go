@@ -0,0 +1,215 @@
+package filter
+
+import "fmt"
+
+// Visitor is used to traverse the filter expression using `filter.Walk()`.
+//
+// Visit is called for each expression encountered by Walk. If the
+// returned visitor w is not nil, Walk visits each of the children of
+// the expression with w, followed by a call to w.Visit(nil).
+type Visitor interface {
+	Visit(Expr) (w Visitor)
+}
+
+// Walk traverses the filter expression in depth-first order. It starts
+// by calling v.Visit(e), and proceeds as described in the documentation
+// for `filter.Visitor`.
+//
+// Note that the literal `null` is represented as an IdentifierExpr,
+// just like attribute paths are.
+func Walk(v Visitor, e Expr) {
+	if e == nil {
+		return
+	}
+
+	if v = v.Visit(e); v == nil {
+		return
+	}
+
+	for _, child := range children(e) {
+		Walk(v, child)
+	}
+	v.Visit(nil)
+}
+
+type inspector func(Expr) bool
+
+func (f inspector) Visit(e Expr) Visitor {
+	if f(e) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the filter expression in depth-first order, calling
+// f for each of the expressions. If f returns true, Inspect proceeds
+// to traverse the children of the expression. After all of the children
+// are traversed, f is called with a nil value.
+func Inspect(e Expr, f func(Expr) bool) {
+	Walk(inspector(f), e)
+}
+
+// children returns the list of non-nil sub-expressions of e
+func children(e Expr) []Expr {
+	var list []Expr
+	switch e := e.(type) {
+	case PresenceExpr:
+		list = []Expr{e.Attr()}
+	case CompareExpr:
+		list = []Expr{e.LHE(), e.RHE()}
+	case RegexExpr:
+		list = []Expr{e.LHE()}
+		if v, ok := e.Value().(Expr); ok {
+			list = append(list, v)
+		}
+	case LogExpr:
+		list = []Expr{e.LHE(), e.RHS()}
+	case ParenExpr:
+		list = []Expr{e.SubExpr()}
+	case ValuePath:
+		list = []Expr{e.ParentAttr(), e.SubExpr(), e.SubAttr()}
+	}
+
+	filtered := list[:0]
+	for _, child := range list {
+		if child != nil {
+			filtered = append(filtered, child)
+		}
+	}
+	return filtered
+}
+
+// RewriteFunc is called by `filter.Rewrite()` for each expression
+type RewriteFunc func(Expr) (Expr, error)
+
+// Rewrite traverses the filter expression in depth-first order, and
+// replaces each of the expressions with the value returned by fn.
+//
+// The children of an expression are rewritten before the expression
+// itself, so fn always receives an expression whose sub-expressions
+// have already been rewritten. Expressions that are returned by fn
+// are not traversed again. For example, to add a restriction to the
+// entire filter, wrap the expression when fn receives the outermost
+// expression, which is always the last one to be passed.
+//
+// If fn returns nil, the expression is removed. Logical expressions
+// that lose one of their operands are replaced by the remaining
+// operand, and any other expression that loses a required operand is
+// removed as well. If the entire expression is removed, Rewrite
+// returns a nil Expr.
+//
+// Note that the literal `null` is represented as an IdentifierExpr,
+// just like attribute paths are.
+func Rewrite(e Expr, fn RewriteFunc) (Expr, error) {
+	if e == nil {
+		return nil, nil
+	}
+
+	switch v := e.(type) {
+	case PresenceExpr:
+		attr, err := Rewrite(v.Attr(), fn)
+		if err != nil {
+			return nil, err
+		}
+		if attr == nil {
+			return nil, nil
+		}
+		if attr != v.Attr() {
+			e = NewPresenceExpr(attr, v.Operator())
+		}
+	case CompareExpr:
+		lhe, err := Rewrite(v.LHE(), fn)
+		if err != nil {
+			return nil, err
+		}
+		rhe, err := Rewrite(v.RHE(), fn)
+		if err != nil {
+			return nil, err
+		}
+		if lhe == nil || rhe == nil {
+			return nil, nil
+		}
+		if lhe != v.LHE() || rhe != v.RHE() {
+			e = NewCompareExpr(lhe, v.Operator(), rhe)
+		}
+	case RegexExpr:
+		lhe, err := Rewrite(v.LHE(), fn)
+		if err != nil {
+			return nil, err
+		}
+		if lhe == nil {
+			return nil, nil
+		}
+
+		value := v.Value()
+		if sub, ok := value.(Expr); ok {
+			rewritten, err := Rewrite(sub, fn)
+			if err != nil {
+				return nil, err
+			}
+			if rewritten == nil {
+				return nil, nil
+			}
+			value = rewritten
+		}
+		if lhe != v.LHE() || value != v.Value() {
+			e = NewRegexExpr(lhe, v.Operator(), value)
+		}
+	case LogExpr:
+		lhe, err := Rewrite(v.LHE(), fn)
+		if err != nil {
+			return nil, err
+		}
+		rhs, err := Rewrite(v.RHS(), fn)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case lhe == nil && rhs == nil:
+			return nil, nil
+		case lhe == nil:
+			return rhs, nil
+		case rhs == nil:
+			return lhe, nil
+		}
+		if lhe != v.LHE() || rhs != v.RHS() {
+			e = NewLogExpr(lhe, v.Operator(), rhs)
+		}
+	case ParenExpr:
+		sub, err := Rewrite(v.SubExpr(), fn)
+		if err != nil {
+			return nil, err
+		}
+		if sub == nil {
+			return nil, nil
+		}
+		if sub != v.SubExpr() {
+			e = NewParenExpr(v.Operator(), sub)
+		}
+	case ValuePath:
+		parent, err := Rewrite(v.ParentAttr(), fn)
+		if err != nil {
+			return nil, err
+		}
+		sub, err := Rewrite(v.SubExpr(), fn)
+		if err != nil {
+			return nil, err
+		}
+		subAttr, err := Rewrite(v.SubAttr(), fn)
+		if err != nil {
+			return nil, err
+		}
+		if parent == nil || (sub == nil && v.SubExpr() != nil) || (subAttr == nil && v.SubAttr() != nil) {
+			return nil, nil
+		}
+		if parent != v.ParentAttr() || sub != v.SubExpr() || subAttr != v.SubAttr() {
+			e = NewValuePath(parent, subAttr, sub)
+		}
+	}
+
+	rewritten, err := fn(e)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to rewrite %q: %w`, e.String(), err)
+	}
+	return rewritten, nil
+}
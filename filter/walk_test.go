@@ -0,0 +1,146 @@
+package filter_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cybozu-go/scim/filter"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalk(t *testing.T) {
+	expr, err := filter.Parse(`userName eq "bjensen" and (emails[type eq "work"] or not (title pr))`)
+	require.NoError(t, err, `filter.Parse should succeed`)
+
+	var attrs []string
+	filter.Inspect(expr, func(e filter.Expr) bool {
+		if ident, ok := e.(filter.IdentifierExpr); ok {
+			attrs = append(attrs, ident.Lit())
+		}
+		return true
+	})
+	require.Equal(t, []string{`userName`, `emails`, `type`, `title`}, attrs, `attribute names should match`)
+
+	// stop descending into value paths
+	attrs = attrs[:0]
+	filter.Inspect(expr, func(e filter.Expr) bool {
+		switch e := e.(type) {
+		case filter.ValuePath:
+			return false
+		case filter.IdentifierExpr:
+			attrs = append(attrs, e.Lit())
+		}
+		return true
+	})
+	require.Equal(t, []string{`userName`, `title`}, attrs, `attribute names should match`)
+}
+
+func TestRewrite(t *testing.T) {
+	columns := map[string]string{
+		`username`: `user_name`,
+		`title`:    `job_title`,
+	}
+
+	testcases := []struct {
+		Name     string
+		Filter   string
+		Rewrite  filter.RewriteFunc
+		Expected string
+		Error    bool
+	}{
+		{
+			Name:   "rename attributes",
+			Filter: `userName eq "bjensen" and not (title pr) and nickName eq null`,
+			Rewrite: func(e filter.Expr) (filter.Expr, error) {
+				if ident, ok := e.(filter.IdentifierExpr); ok {
+					if col, ok := columns[strings.ToLower(ident.Lit())]; ok {
+						return filter.NewIdentifierExpr(col), nil
+					}
+				}
+				return e, nil
+			},
+			Expected: `user_name eq "bjensen" and not (job_title pr) and nickName eq null`,
+		},
+		{
+			Name:   "strip unsupported expressions",
+			Filter: `userName eq "bjensen" and (emails[type eq "work"] or title pr)`,
+			Rewrite: func(e filter.Expr) (filter.Expr, error) {
+				if _, ok := e.(filter.ValuePath); ok {
+					return nil, nil
+				}
+				return e, nil
+			},
+			Expected: `userName eq "bjensen" and (title pr)`,
+		},
+		{
+			Name:   "strip value path filter",
+			Filter: `emails[type eq "work"] and title pr`,
+			Rewrite: func(e filter.Expr) (filter.Expr, error) {
+				if c, ok := e.(filter.CompareExpr); ok {
+					if ident, ok := c.LHE().(filter.IdentifierExpr); ok && ident.Lit() == `type` {
+						return nil, nil
+					}
+				}
+				return e, nil
+			},
+			Expected: `title pr`,
+		},
+		{
+			Name:   "strip everything",
+			Filter: `title pr`,
+			Rewrite: func(e filter.Expr) (filter.Expr, error) {
+				return nil, nil
+			},
+		},
+		{
+			Name:   "error",
+			Filter: `userName eq "bjensen" and emails[type eq "work"]`,
+			Rewrite: func(e filter.Expr) (filter.Expr, error) {
+				if _, ok := e.(filter.ValuePath); ok {
+					return nil, fmt.Errorf(`value paths are not supported`)
+				}
+				return e, nil
+			},
+			Error: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			expr, err := filter.Parse(tc.Filter)
+			require.NoError(t, err, `filter.Parse should succeed`)
+			original := expr.String()
+
+			rewritten, err := filter.Rewrite(expr, tc.Rewrite)
+			if tc.Error {
+				require.Error(t, err, `filter.Rewrite should fail`)
+				return
+			}
+			require.NoError(t, err, `filter.Rewrite should succeed`)
+			require.Equal(t, original, expr.String(), `original expression should not be modified`)
+
+			if tc.Expected == "" {
+				require.Nil(t, rewritten, `rewritten expression should be nil`)
+				return
+			}
+			require.Equal(t, tc.Expected, filter.Format(rewritten), `rewritten expression should match`)
+		})
+	}
+
+	t.Run("inject tenant restriction", func(t *testing.T) {
+		expr, err := filter.Parse(`userName eq "bjensen" or title pr`)
+		require.NoError(t, err, `filter.Parse should succeed`)
+
+		tenant := filter.NewCompareExpr(filter.NewIdentifierExpr(`tenantId`), filter.EqualOp, filter.NewAttrValueExpr(`x`))
+		rewritten, err := filter.Rewrite(expr, func(e filter.Expr) (filter.Expr, error) {
+			if e != expr {
+				return e, nil
+			}
+			return filter.NewLogExpr(filter.NewParenExpr("", e), filter.AndOp, tenant), nil
+		})
+		require.NoError(t, err, `filter.Rewrite should succeed`)
+		require.Equal(t, `(userName eq "bjensen" or title pr) and tenantId eq "x"`, filter.Format(rewritten), `rewritten expression should match`)
+	})
+}
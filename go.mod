@@ -8,6 +8,7 @@ require (
 	github.com/lestrrat-go/codegen v1.0.4
 	github.com/lestrrat-go/mux v0.0.0-20220525044338-e2775b70cf3d
 	github.com/lestrrat-go/option v1.0.0
+	github.com/lestrrat-go/xstrings v0.0.0-20210804220435-4dd8b234342b
 	github.com/stretchr/testify v1.8.0
 )
 
@@ -15,7 +16,6 @@ require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/fatih/color v1.10.0 // indirect
 	github.com/kr/text v0.2.0 // indirect
-	github.com/lestrrat-go/xstrings v0.0.0-20210804220435-4dd8b234342b // indirect
 	github.com/mattn/go-colorable v0.1.12 // indirect
 	github.com/mattn/go-isatty v0.0.14 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
@@ -0,0 +1,322 @@
+package patch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/cybozu-go/scim/resource"
+	"github.com/cybozu-go/scim/schema"
+)
+
+// Diff compares two versions of a resource, and returns the PATCH
+// request that turns `from` into `to`. Both resources must be of the
+// same type, such as *resource.User or *resource.Group.
+//
+// Each modified attribute results in as few operations as possible:
+//
+//   - Attributes that were added or removed are added or removed using
+//     their attribute paths (e.g. `title`).
+//   - Modified sub-attributes of single-valued complex attributes are
+//     replaced using a single `replace` operation on the attribute
+//     (e.g. `name`), and removed sub-attributes are removed using their
+//     attribute paths (e.g. `name.middleName`).
+//   - Values of multi-valued attributes are identified by their `value`
+//     sub-attribute. Values that were removed are removed using a value
+//     filter (e.g. `members[value eq "2819c223"]`), modified values are
+//     replaced using a value filter, and new values are added using
+//     a single `add` operation. The order of the values is not taken
+//     into account. If the values cannot be identified by their `value`
+//     sub-attribute, the attribute is replaced as a whole.
+//   - Attributes of schema extensions are handled in the same way,
+//     using fully qualified attribute paths.
+//
+// `id`, `meta` and `schemas` are managed by the service provider,
+// and are not compared. Neither are other attributes whose mutability
+// is `readOnly` (e.g. `groups`). The schema of the resource is
+// determined using `schema.ForResource()`, and the schemas of its
+// extensions are looked up in the `schema` package. If the resources
+// are identical, the returned PATCH request contains no operations.
+func Diff(from, to interface{}) (*resource.PatchRequest, error) {
+	if reflect.TypeOf(from) != reflect.TypeOf(to) {
+		return nil, fmt.Errorf(`patch.Diff: resources must be of the same type (got %T and %T)`, from, to)
+	}
+
+	fromObj, err := diffObject(from)
+	if err != nil {
+		return nil, fmt.Errorf(`patch.Diff: failed to process old resource: %w`, err)
+	}
+	toObj, err := diffObject(to)
+	if err != nil {
+		return nil, fmt.Errorf(`patch.Diff: failed to process new resource: %w`, err)
+	}
+
+	// read-only attributes cannot be modified by PATCH requests
+	if s, ok := schema.ForResource(from); ok {
+		stripReadOnly(fromObj, s.Attributes())
+		stripReadOnly(toObj, s.Attributes())
+	}
+
+	var d differ
+	for _, key := range []string{`id`, `meta`, `schemas`} {
+		delete(fromObj, key)
+		delete(toObj, key)
+	}
+	d.diffAttributes(``, fromObj, toObj)
+	if d.err != nil {
+		return nil, fmt.Errorf(`patch.Diff: %w`, d.err)
+	}
+
+	return resource.NewPatchRequestBuilder().
+		Schemas(resource.PatchRequestSchemaURI).
+		Operations(d.operations...).
+		Build()
+}
+
+// diffObject converts the resource into its JSON representation
+func diffObject(v interface{}) (map[string]interface{}, error) {
+	serialized, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to serialize resource: %w`, err)
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal(serialized, &obj); err != nil {
+		return nil, fmt.Errorf(`failed to deserialize resource: %w`, err)
+	}
+	return obj, nil
+}
+
+// stripReadOnly removes the attributes whose mutability is `readOnly`
+// from the JSON representation of a resource or of a complex value.
+// Attributes of schema extensions are looked up in their schemas
+func stripReadOnly(obj map[string]interface{}, attrs []*resource.SchemaAttribute) {
+	for key, value := range obj {
+		if strings.HasPrefix(strings.ToLower(key), `urn:`) {
+			if ext, ok := lookupSchema(key); ok {
+				if extObj, ok := value.(map[string]interface{}); ok {
+					stripReadOnly(extObj, ext.Attributes())
+				}
+			}
+			continue
+		}
+
+		attr := schema.FindAttribute(attrs, key)
+		if attr == nil {
+			continue
+		}
+		if attr.Mutability() == resource.MutReadOnly {
+			delete(obj, key)
+			continue
+		}
+		if attr.Type() != resource.Complex {
+			continue
+		}
+		switch value := value.(type) {
+		case map[string]interface{}:
+			stripReadOnly(value, attr.SubAttributes())
+		case []interface{}:
+			for _, elem := range value {
+				if elem, ok := elem.(map[string]interface{}); ok {
+					stripReadOnly(elem, attr.SubAttributes())
+				}
+			}
+		}
+	}
+}
+
+type differ struct {
+	operations []*resource.PatchOperation
+	err        error
+}
+
+func (d *differ) emit(op resource.PatchOperationType, path string, value interface{}) {
+	if d.err != nil {
+		return
+	}
+	b := resource.NewPatchOperationBuilder().Op(op).Path(path)
+	if value != nil {
+		b.Value(value)
+	}
+	operation, err := b.Build()
+	if err != nil {
+		d.err = fmt.Errorf(`failed to build %q operation for %q: %w`, op, path, err)
+		return
+	}
+	d.operations = append(d.operations, operation)
+}
+
+// diffAttributes compares the attributes of a resource, or of a schema
+// extension. prefix is the schema URI followed by a colon for
+// attributes of schema extensions
+func (d *differ) diffAttributes(prefix string, from, to map[string]interface{}) {
+	for _, key := range unionKeys(from, to) {
+		oldValue, inOld := from[key]
+		newValue, inNew := to[key]
+		if inOld && inNew && reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+
+		// schema extensions are compared attribute by attribute
+		if prefix == `` && strings.HasPrefix(strings.ToLower(key), `urn:`) {
+			oldExt, _ := oldValue.(map[string]interface{})
+			newExt, _ := newValue.(map[string]interface{})
+			if (oldExt != nil || !inOld) && (newExt != nil || !inNew) {
+				d.diffAttributes(key+`:`, oldExt, newExt)
+				continue
+			}
+		}
+
+		path := prefix + key
+		switch {
+		case !inNew || newValue == nil:
+			d.emit(resource.PatchRemove, path, nil)
+		case !inOld || oldValue == nil:
+			d.emit(resource.PatchAdd, path, newValue)
+		default:
+			d.diffValue(path, oldValue, newValue)
+		}
+	}
+}
+
+// diffValue compares two values of the same attribute
+func (d *differ) diffValue(path string, from, to interface{}) {
+	switch to := to.(type) {
+	case map[string]interface{}:
+		if from, ok := from.(map[string]interface{}); ok {
+			d.diffComplex(path, from, to)
+			return
+		}
+	case []interface{}:
+		if from, ok := from.([]interface{}); ok {
+			d.diffMultiValued(path, from, to)
+			return
+		}
+	}
+	d.emit(resource.PatchReplace, path, to)
+}
+
+// diffComplex compares two values of a single-valued complex attribute
+func (d *differ) diffComplex(path string, from, to map[string]interface{}) {
+	modified := make(map[string]interface{})
+	var removed []string
+	for _, key := range unionKeys(from, to) {
+		oldValue, inOld := from[key]
+		newValue, inNew := to[key]
+		switch {
+		case !inNew || newValue == nil:
+			if inOld && oldValue != nil {
+				removed = append(removed, key)
+			}
+		case !inOld || !reflect.DeepEqual(oldValue, newValue):
+			modified[key] = newValue
+		}
+	}
+
+	if len(modified) == 0 && len(removed) > 0 && len(removed) == len(from) {
+		d.emit(resource.PatchRemove, path, nil)
+		return
+	}
+	for _, key := range removed {
+		d.emit(resource.PatchRemove, path+`.`+key, nil)
+	}
+	if len(modified) > 0 {
+		// sub-attributes that are not specified are left unchanged
+		d.emit(resource.PatchReplace, path, modified)
+	}
+}
+
+// diffMultiValued compares two sets of values of a multi-valued attribute
+func (d *differ) diffMultiValued(path string, from, to []interface{}) {
+	if len(to) == 0 {
+		d.emit(resource.PatchRemove, path, nil)
+		return
+	}
+
+	oldValues, oldKeys, ok := indexByValue(from)
+	if !ok {
+		d.emit(resource.PatchReplace, path, to)
+		return
+	}
+	newValues, newKeys, ok := indexByValue(to)
+	if !ok {
+		d.emit(resource.PatchReplace, path, to)
+		return
+	}
+
+	// values that were removed are removed using a single filter
+	var removed []string
+	for _, key := range oldKeys {
+		if _, ok := newValues[key]; !ok {
+			removed = append(removed, valueFilter(key))
+		}
+	}
+	if len(removed) > 0 {
+		d.emit(resource.PatchRemove, path+`[`+strings.Join(removed, ` or `)+`]`, nil)
+	}
+
+	var added []interface{}
+	for _, key := range newKeys {
+		oldValue, ok := oldValues[key]
+		switch {
+		case !ok:
+			added = append(added, newValues[key])
+		case !reflect.DeepEqual(oldValue, newValues[key]):
+			d.emit(resource.PatchReplace, path+`[`+valueFilter(key)+`]`, newValues[key])
+		}
+	}
+	if len(added) > 0 {
+		d.emit(resource.PatchAdd, path, added)
+	}
+}
+
+// indexByValue indexes the values of a multi-valued complex attribute
+// by their `value` sub-attribute. The keys are returned in the order
+// that they appear. If any of the values does not have a unique string
+// `value` sub-attribute, false is returned
+func indexByValue(values []interface{}) (map[string]interface{}, []string, bool) {
+	index := make(map[string]interface{}, len(values))
+	keys := make([]string, 0, len(values))
+	for _, v := range values {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, nil, false
+		}
+		key, ok := obj[`value`].(string)
+		if !ok {
+			return nil, nil, false
+		}
+		if _, ok := index[key]; ok {
+			return nil, nil, false
+		}
+		index[key] = v
+		keys = append(keys, key)
+	}
+	return index, keys, true
+}
+
+// valueFilter returns the filter that selects the value of
+// a multi-valued attribute
+func valueFilter(value string) string {
+	// filter strings use the same syntax as JSON strings
+	var sb strings.Builder
+	enc := json.NewEncoder(&sb)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(value)
+	return `value eq ` + strings.TrimSuffix(sb.String(), "\n")
+}
+
+func unionKeys(a, b map[string]interface{}) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
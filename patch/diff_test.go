@@ -0,0 +1,188 @@
+package patch_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cybozu-go/scim/patch"
+	"github.com/cybozu-go/scim/resource"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	const base = `{
+  "schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+  "id": "2819c223-7f76-453a-919d-413861904646",
+  "userName": "bjensen",
+  "title": "Tour Guide",
+  "name": {"familyName": "Jensen", "givenName": "Barbara", "middleName": "Jane"},
+  "emails": [
+    {"value": "bjensen@example.com", "type": "work", "primary": true},
+    {"value": "babs@jensen.org", "type": "home"}
+  ],
+  "meta": {"resourceType": "User", "version": "W/\"1\""}
+}`
+
+	testcases := []struct {
+		Name       string
+		To         string
+		Operations string
+	}{
+		{
+			Name:       `identical`,
+			To:         base,
+			Operations: `null`,
+		},
+		{
+			Name: `meta is ignored`,
+			To: `{
+  "schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+  "id": "2819c223-7f76-453a-919d-413861904646",
+  "userName": "bjensen",
+  "title": "Tour Guide",
+  "name": {"familyName": "Jensen", "givenName": "Barbara", "middleName": "Jane"},
+  "emails": [
+    {"value": "babs@jensen.org", "type": "home"},
+    {"value": "bjensen@example.com", "type": "work", "primary": true}
+  ],
+  "meta": {"resourceType": "User", "version": "W/\"2\""}
+}`,
+			Operations: `null`,
+		},
+		{
+			Name: `read-only attributes are ignored`,
+			To: `{
+  "schemas": ["urn:ietf:params:scim:schemas:core:2.0:User", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"],
+  "userName": "bjensen",
+  "title": "Tour Guide",
+  "name": {"familyName": "Jensen", "givenName": "Barbara", "middleName": "Jane"},
+  "emails": [
+    {"value": "bjensen@example.com", "type": "work", "primary": true},
+    {"value": "babs@jensen.org", "type": "home"}
+  ],
+  "groups": [
+    {"value": "e9e30dba-f08f-4109-8486-d5c6a331660a", "display": "Tour Guides"}
+  ],
+  "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {
+    "manager": {"value": "26118915-6090-4610-87e4-49d8ca9f808d", "displayName": "John Smith"}
+  }
+}`,
+			Operations: `[
+  {"op":"add","path":"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager","value":{"value":"26118915-6090-4610-87e4-49d8ca9f808d"}}
+]`,
+		},
+		{
+			Name: `single-valued attributes`,
+			To: `{
+  "userName": "barbara",
+  "displayName": "Babs Jensen",
+  "name": {"familyName": "Jensen", "givenName": "Babs"},
+  "emails": [
+    {"value": "bjensen@example.com", "type": "work", "primary": true},
+    {"value": "babs@jensen.org", "type": "home"}
+  ]
+}`,
+			Operations: `[
+  {"op":"add","path":"displayName","value":"Babs Jensen"},
+  {"op":"remove","path":"name.middleName"},
+  {"op":"replace","path":"name","value":{"givenName":"Babs"}},
+  {"op":"remove","path":"title"},
+  {"op":"replace","path":"userName","value":"barbara"}
+]`,
+		},
+		{
+			Name: `multi-valued attributes`,
+			To: `{
+  "userName": "bjensen",
+  "title": "Tour Guide",
+  "name": {"familyName": "Jensen", "givenName": "Barbara", "middleName": "Jane"},
+  "emails": [
+    {"value": "bjensen@example.com", "type": "other", "primary": true},
+    {"value": "babs@example.org", "type": "home"},
+    {"value": "barbara@example.org", "type": "home"}
+  ]
+}`,
+			Operations: `[
+  {"op":"remove","path":"emails[value eq \"babs@jensen.org\"]"},
+  {"op":"replace","path":"emails[value eq \"bjensen@example.com\"]","value":{"value":"bjensen@example.com","type":"other","primary":true}},
+  {"op":"add","path":"emails","value":[{"value":"babs@example.org","type":"home"},{"value":"barbara@example.org","type":"home"}]}
+]`,
+		},
+		{
+			Name: `extension attributes`,
+			To: `{
+  "schemas": ["urn:ietf:params:scim:schemas:core:2.0:User", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"],
+  "userName": "bjensen",
+  "title": "Tour Guide",
+  "name": {"familyName": "Jensen", "givenName": "Barbara", "middleName": "Jane"},
+  "emails": [
+    {"value": "bjensen@example.com", "type": "work", "primary": true},
+    {"value": "babs@jensen.org", "type": "home"}
+  ],
+  "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {"employeeNumber": "701984"}
+}`,
+			Operations: `[
+  {"op":"add","path":"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber","value":"701984"}
+]`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			var from, to resource.User
+			require.NoError(t, json.Unmarshal([]byte(base), &from), `json.Unmarshal should succeed`)
+			require.NoError(t, json.Unmarshal([]byte(tc.To), &to), `json.Unmarshal should succeed`)
+
+			req, err := patch.Diff(&from, &to)
+			require.NoError(t, err, `patch.Diff should succeed`)
+
+			serialized, err := json.Marshal(req.Operations())
+			require.NoError(t, err, `json.Marshal should succeed`)
+			require.JSONEq(t, tc.Operations, string(serialized), `operations should match`)
+
+			// applying the operations should result in the new version
+			patched, err := patch.ApplyUser(&from, req)
+			require.NoError(t, err, `patch.ApplyUser should succeed`)
+			require.Empty(t, diffOperations(t, patched, &to), `patched user should match the new version`)
+		})
+	}
+}
+
+func TestDiffGroupMembers(t *testing.T) {
+	from := resource.NewGroupBuilder().
+		DisplayName(`Tour Guides`).
+		Members(
+			resource.NewGroupMemberBuilder().Value(`2819c223`).MustBuild(),
+			resource.NewGroupMemberBuilder().Value(`902c246b`).MustBuild(),
+			resource.NewGroupMemberBuilder().Value(`08e1d05d`).MustBuild(),
+		).
+		MustBuild()
+	to := resource.NewGroupBuilder().
+		DisplayName(`Tour Guides`).
+		Members(
+			resource.NewGroupMemberBuilder().Value(`902c246b`).MustBuild(),
+			resource.NewGroupMemberBuilder().Value(`a1b2c3d4`).MustBuild(),
+		).
+		MustBuild()
+
+	req, err := patch.Diff(from, to)
+	require.NoError(t, err, `patch.Diff should succeed`)
+
+	serialized, err := json.Marshal(req.Operations())
+	require.NoError(t, err, `json.Marshal should succeed`)
+	require.JSONEq(t, `[
+  {"op":"remove","path":"members[value eq \"2819c223\" or value eq \"08e1d05d\"]"},
+  {"op":"add","path":"members","value":[{"value":"a1b2c3d4"}]}
+]`, string(serialized), `operations should match`)
+
+	_, err = patch.Diff(from, resource.NewUserBuilder().UserName(`bjensen`).MustBuild())
+	require.Error(t, err, `patch.Diff should fail for different resource types`)
+}
+
+func diffOperations(t *testing.T, from, to interface{}) []*resource.PatchOperation {
+	t.Helper()
+	req, err := patch.Diff(from, to)
+	require.NoError(t, err, `patch.Diff should succeed`)
+	return req.Operations()
+}
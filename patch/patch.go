@@ -0,0 +1,956 @@
+// Package patch applies SCIM PATCH requests (RFC7644 Section 3.5.2)
+// to resources, and computes PATCH requests from two versions of
+// a resource.
+//
+// Use `patch.Apply()` (or `patch.ApplyUser()` and `patch.ApplyGroup()`)
+// to apply PATCH requests, and `patch.Diff()` to compute them. This
+// functionality is not provided by the `resource` package, as it
+// depends on the `filter` and `schema` packages, which in turn depend
+// on the `resource` package.
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/cybozu-go/scim/filter"
+	"github.com/cybozu-go/scim/resource"
+	"github.com/cybozu-go/scim/schema"
+)
+
+// Apply applies the operations in the PATCH request to v, and returns
+// the patched resource.
+//
+// v is usually a *resource.User or a *resource.Group, but any resource
+// object that implements `Keys()`, `Get()`, `Set()` and `Remove()`
+// methods (such as extension objects), as well as map[string]interface{}
+// values can be patched. v itself is not modified: the result is a new
+// object of the same type, whose attributes are copied from v and
+// modified using these methods.
+//
+// The operations are applied in order, following RFC7644 Section 3.5.2:
+//
+//   - `add` without a path merges the attributes in the value into
+//     the resource. Values are appended to multi-valued attributes, and
+//     sub-attributes are merged into complex attributes.
+//   - `replace` without a filter replaces the attribute. Sub-attributes
+//     of single-valued complex attributes that are not specified in the
+//     value are left unchanged, while multi-valued attributes are
+//     replaced as a whole.
+//   - `replace` with a filter (e.g. `emails[type eq "work"]`) replaces
+//     the matching values, or the specified sub-attribute of the
+//     matching values.
+//   - `remove` requires a path, and removes the attribute, sub-attribute
+//     or the matching values (e.g. `members[value eq "2819c223"]`).
+//
+// Attributes are checked against the schema of the resource, which is
+// deduced from the type of the resource, or from its `schemas`
+// attribute. Use `patch.WithSchema()` to specify it explicitly.
+// Attributes of schema extensions are resolved using the schemas
+// registered in the `schema` package.
+//
+// Problems with the request are reported as a *resource.Error, which can
+// be returned to the client as is: `invalidPath` for paths that cannot
+// be parsed or do not exist in the schema, `noTarget` for filters that
+// do not match any values, `mutability` for attributes that cannot be
+// modified, and `invalidValue` for values that cannot be applied.
+func Apply(v interface{}, req *resource.PatchRequest, options ...ApplyOption) (interface{}, error) {
+	var s *resource.Schema
+	//nolint:forcetypeassert
+	for _, option := range options {
+		switch option.Ident() {
+		case identSchema{}:
+			s = option.Value().(*resource.Schema)
+		}
+	}
+	if s == nil {
+		s, _ = schema.ForResource(v)
+	}
+
+	var doc map[string]interface{}
+	obj, isObject := v.(object)
+	switch v := v.(type) {
+	case map[string]interface{}:
+		doc = v
+	case object:
+		if reflect.ValueOf(v).Kind() != reflect.Ptr {
+			return nil, fmt.Errorf(`patch: expected pointer to resource, got %T`, v)
+		}
+		converted, err := objectDocument(v)
+		if err != nil {
+			return nil, fmt.Errorf(`patch: failed to process resource: %w`, err)
+		}
+		doc = converted
+	default:
+		return nil, fmt.Errorf(`patch: unsupported resource type %T`, v)
+	}
+
+	// the attributes are modified in place, so the operations are
+	// applied to a copy of the document
+	copied, err := copyDocument(doc)
+	if err != nil {
+		return nil, fmt.Errorf(`patch: failed to copy resource: %w`, err)
+	}
+
+	a := &applier{doc: copied, schema: s}
+	for i, op := range req.Operations() {
+		if err := a.apply(op); err != nil {
+			var rerr *resource.Error
+			if errors.As(err, &rerr) {
+				return nil, err
+			}
+			return nil, fmt.Errorf(`patch: failed to apply operation %d: %w`, i, err)
+		}
+	}
+
+	if !isObject {
+		return a.doc, nil
+	}
+	return a.result(obj, doc)
+}
+
+// object is implemented by resource objects, such as *resource.User,
+// *resource.Group and extension objects
+type object interface {
+	Keys() []string
+	Get(string, interface{}) error
+	Set(string, interface{}) error
+	Remove(string) error
+}
+
+// objectDocument returns the JSON representation of the attributes of
+// the resource object, which is what the operations are applied to
+func objectDocument(v object) (map[string]interface{}, error) {
+	doc := make(map[string]interface{})
+	for _, key := range v.Keys() {
+		var value interface{}
+		if err := v.Get(key, &value); err != nil {
+			return nil, fmt.Errorf(`failed to get %q: %w`, key, err)
+		}
+		converted, err := jsonValue(value)
+		if err != nil {
+			return nil, fmt.Errorf(`failed to convert %q: %w`, key, err)
+		}
+		doc[key] = converted
+	}
+	return doc, nil
+}
+
+// jsonValue converts v into its JSON representation, which consists of
+// maps, slices, strings, numbers and booleans
+func jsonValue(v interface{}) (interface{}, error) {
+	serialized, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var converted interface{}
+	if err := json.Unmarshal(serialized, &converted); err != nil {
+		return nil, err
+	}
+	return converted, nil
+}
+
+func copyDocument(doc map[string]interface{}) (map[string]interface{}, error) {
+	converted, err := jsonValue(doc)
+	if err != nil {
+		return nil, err
+	}
+	copied, _ := converted.(map[string]interface{})
+	if copied == nil {
+		copied = make(map[string]interface{})
+	}
+	return copied, nil
+}
+
+// result creates a copy of the resource object v, and applies the
+// modifications to the attributes using its `Set()` and `Remove()`
+// methods. orig is the JSON representation of v before the operations
+// were applied
+func (a *applier) result(v object, orig map[string]interface{}) (interface{}, error) {
+	// the new values are converted into the types that the resource
+	// uses to store them by decoding the patched document the same way
+	// that the resource decodes its JSON representation, which also
+	// checks that the patched resource is valid
+	serialized, err := json.Marshal(a.doc)
+	if err != nil {
+		return nil, fmt.Errorf(`patch: failed to serialize patched resource: %w`, err)
+	}
+	rt := reflect.TypeOf(v).Elem()
+	//nolint:forcetypeassert
+	decoded := reflect.New(rt).Interface().(object)
+	if err := json.Unmarshal(serialized, decoded); err != nil {
+		return nil, newError(resource.ErrInvalidValue, `patched resource is not valid: %s`, err)
+	}
+
+	//nolint:forcetypeassert
+	result := reflect.New(rt).Interface().(object)
+	for _, key := range v.Keys() {
+		var value interface{}
+		if err := v.Get(key, &value); err != nil {
+			return nil, fmt.Errorf(`patch: failed to get %q: %w`, key, err)
+		}
+		if err := result.Set(key, value); err != nil {
+			return nil, fmt.Errorf(`patch: failed to set %q: %w`, key, err)
+		}
+	}
+
+	for _, key := range sortedKeys(orig) {
+		if _, ok := a.doc[key]; !ok {
+			if err := result.Remove(key); err != nil {
+				return nil, fmt.Errorf(`patch: failed to remove %q: %w`, key, err)
+			}
+		}
+	}
+	for _, key := range sortedKeys(a.doc) {
+		if reflect.DeepEqual(orig[key], a.doc[key]) {
+			continue
+		}
+		var value interface{}
+		if err := decoded.Get(key, &value); err != nil {
+			return nil, fmt.Errorf(`patch: failed to get %q: %w`, key, err)
+		}
+		if err := result.Set(key, value); err != nil {
+			return nil, newError(resource.ErrInvalidValue, `value of %q is not valid: %s`, key, err)
+		}
+	}
+	return result, nil
+}
+
+// ApplyUser applies the operations in the PATCH request to the user,
+// and returns the patched user. See `patch.Apply()` for details.
+func ApplyUser(v *resource.User, req *resource.PatchRequest, options ...ApplyOption) (*resource.User, error) {
+	result, err := Apply(v, req, options...)
+	if err != nil {
+		return nil, err
+	}
+	//nolint:forcetypeassert
+	return result.(*resource.User), nil
+}
+
+// ApplyGroup applies the operations in the PATCH request to the group,
+// and returns the patched group. See `patch.Apply()` for details.
+func ApplyGroup(v *resource.Group, req *resource.PatchRequest, options ...ApplyOption) (*resource.Group, error) {
+	result, err := Apply(v, req, options...)
+	if err != nil {
+		return nil, err
+	}
+	//nolint:forcetypeassert
+	return result.(*resource.Group), nil
+}
+
+func newError(typ resource.ErrorType, format string, args ...interface{}) error {
+	return resource.NewErrorBuilder().
+		Status(http.StatusBadRequest).
+		SCIMType(typ).
+		Detail(fmt.Sprintf(format, args...)).
+		MustBuild()
+}
+
+type applier struct {
+	doc    map[string]interface{}
+	schema *resource.Schema
+}
+
+// location is the attribute that an operation applies to
+type location struct {
+	path *filter.Path
+	// extension is the URI of the schema extension that the attribute
+	// belongs to, or an empty string for attributes of the resource
+	extension string
+	// schema is the schema that defines the attribute. It is nil if
+	// the schema of the resource is not known
+	schema  *resource.Schema
+	attr    *resource.SchemaAttribute
+	subAttr *resource.SchemaAttribute
+}
+
+func (l *location) name() string {
+	if l.attr != nil {
+		return l.attr.Name()
+	}
+	return l.path.Attribute
+}
+
+func (l *location) subName() string {
+	if l.subAttr != nil {
+		return l.subAttr.Name()
+	}
+	return l.path.SubAttribute
+}
+
+func (a *applier) apply(op *resource.PatchOperation) error {
+	typ := resource.PatchOperationType(strings.ToLower(string(op.Op())))
+	switch typ {
+	case resource.PatchAdd, resource.PatchReplace, resource.PatchRemove:
+	default:
+		return newError(resource.ErrInvalidSyntax, `invalid operation %q`, op.Op())
+	}
+
+	if op.Path() == "" {
+		if typ == resource.PatchRemove {
+			return newError(resource.ErrNoTarget, `path is required for "remove" operations`)
+		}
+		return a.applyObject(typ, op.Value())
+	}
+
+	path, err := filter.ParsePath(op.Path())
+	if err != nil {
+		return newError(resource.ErrInvalidPath, `invalid path %q: %s`, op.Path(), err)
+	}
+	loc, err := a.locate(path)
+	if err != nil {
+		return err
+	}
+
+	switch typ {
+	case resource.PatchAdd:
+		return a.add(loc, op.Value())
+	case resource.PatchReplace:
+		return a.replace(loc, op.Value())
+	default:
+		return a.remove(loc)
+	}
+}
+
+// applyObject applies an `add` or `replace` operation without a path,
+// in which case the value is an object containing the attributes to
+// be modified
+func (a *applier) applyObject(typ resource.PatchOperationType, value interface{}) error {
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return newError(resource.ErrInvalidValue, `value of %q operation without a path must be an object, got %T`, typ, value)
+	}
+
+	for _, key := range sortedKeys(obj) {
+		if strings.EqualFold(key, `schemas`) {
+			continue
+		}
+
+		paths := []*filter.Path{{Attribute: key}}
+		values := []interface{}{obj[key]}
+		if ext, ok := a.extensionSchema(key); ok {
+			attrs, ok := obj[key].(map[string]interface{})
+			if !ok {
+				return newError(resource.ErrInvalidValue, `value for schema extension %q must be an object, got %T`, key, obj[key])
+			}
+			paths = paths[:0]
+			values = values[:0]
+			for _, name := range sortedKeys(attrs) {
+				paths = append(paths, &filter.Path{SchemaURI: ext.ID(), Attribute: name})
+				values = append(values, attrs[name])
+			}
+		}
+
+		for i, path := range paths {
+			loc, err := a.locate(path)
+			if err != nil {
+				return err
+			}
+			if typ == resource.PatchAdd {
+				err = a.add(loc, values[i])
+			} else {
+				err = a.replace(loc, values[i])
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// extensionSchema returns the schema of the extension identified by
+// the URI, if the URI refers to a schema extension of the resource
+func (a *applier) extensionSchema(uri string) (*resource.Schema, bool) {
+	if !strings.HasPrefix(strings.ToLower(uri), `urn:`) {
+		return nil, false
+	}
+	if a.schema != nil && strings.EqualFold(uri, a.schema.ID()) {
+		return nil, false
+	}
+	return lookupSchema(uri)
+}
+
+// lookupSchema returns the schema registered in the `schema` package
+// by its case-insensitive schema URI
+func lookupSchema(uri string) (*resource.Schema, bool) {
+	for _, s := range schema.All() {
+		if strings.EqualFold(uri, s.ID()) {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// locate resolves the path against the schema of the resource
+func (a *applier) locate(path *filter.Path) (*location, error) {
+	loc := &location{path: path, schema: a.schema}
+	if uri := path.SchemaURI; uri != "" && (a.schema == nil || !strings.EqualFold(uri, a.schema.ID())) {
+		s, ok := a.extensionSchema(uri)
+		if !ok {
+			return nil, newError(resource.ErrInvalidPath, `unknown schema %q in path %q`, uri, path)
+		}
+		loc.schema = s
+		loc.extension = s.ID()
+	}
+
+	if loc.schema == nil {
+		return loc, nil
+	}
+
+	loc.attr = schema.FindAttribute(loc.schema.Attributes(), path.Attribute)
+	if loc.attr == nil {
+		return nil, newError(resource.ErrInvalidPath, `attribute %q is not defined in schema %q`, path.Attribute, loc.schema.ID())
+	}
+	if path.Filter != nil && !loc.attr.MultiValued() {
+		return nil, newError(resource.ErrInvalidPath, `value filter in path %q cannot be applied to single-valued attribute %q`, path, loc.attr.Name())
+	}
+	if sub := path.SubAttribute; sub != "" {
+		loc.subAttr = schema.FindAttribute(loc.attr.SubAttributes(), sub)
+		if loc.subAttr == nil {
+			return nil, newError(resource.ErrInvalidPath, `attribute %q does not have a sub-attribute %q`, loc.attr.Name(), sub)
+		}
+	}
+	return loc, nil
+}
+
+// container returns the object that holds the attribute, which is
+// either the resource itself, or the object for the schema extension.
+// If create is true, missing extension objects are created
+func (a *applier) container(loc *location, create bool) map[string]interface{} {
+	if loc.extension == "" {
+		return a.doc
+	}
+
+	if key, v, ok := lookup(a.doc, loc.extension); ok {
+		if obj, ok := v.(map[string]interface{}); ok {
+			return obj
+		}
+		if !create {
+			return nil
+		}
+		delete(a.doc, key)
+	}
+	if !create {
+		return nil
+	}
+
+	obj := make(map[string]interface{})
+	a.doc[loc.extension] = obj
+
+	// resources must list the schema extensions that they contain
+	list, _ := a.doc[`schemas`].([]interface{})
+	for _, uri := range list {
+		if s, ok := uri.(string); ok && strings.EqualFold(s, loc.extension) {
+			return obj
+		}
+	}
+	a.doc[`schemas`] = append(list, loc.extension)
+	return obj
+}
+
+// prune removes the schema extension object if it no longer contains
+// any attributes
+func (a *applier) prune(loc *location) {
+	if loc.extension == "" {
+		return
+	}
+	key, v, ok := lookup(a.doc, loc.extension)
+	if !ok {
+		return
+	}
+	if obj, ok := v.(map[string]interface{}); !ok || len(obj) > 0 {
+		return
+	}
+	delete(a.doc, key)
+
+	list, _ := a.doc[`schemas`].([]interface{})
+	filtered := list[:0]
+	for _, uri := range list {
+		if s, ok := uri.(string); ok && strings.EqualFold(s, loc.extension) {
+			continue
+		}
+		filtered = append(filtered, uri)
+	}
+	a.doc[`schemas`] = filtered
+}
+
+// checkMutability checks that the attribute can be modified by the
+// operation. exists reports whether the attribute currently has a value
+func checkMutability(typ resource.PatchOperationType, attr *resource.SchemaAttribute, exists bool) error {
+	if attr == nil {
+		return nil
+	}
+	switch attr.Mutability() {
+	case resource.MutReadOnly:
+		return newError(resource.ErrMutability, `attribute %q is read-only`, attr.Name())
+	case resource.MutImmutable:
+		if typ != resource.PatchAdd || exists {
+			return newError(resource.ErrMutability, `attribute %q is immutable, and already has a value`, attr.Name())
+		}
+	}
+	return nil
+}
+
+// checkReadOnly checks that the values of the attribute can be
+// modified. Unlike checkMutability, immutable attributes are accepted,
+// as it is up to their sub-attributes whether they can be modified
+func checkReadOnly(attr *resource.SchemaAttribute) error {
+	if attr != nil && attr.Mutability() == resource.MutReadOnly {
+		return newError(resource.ErrMutability, `attribute %q is read-only`, attr.Name())
+	}
+	return nil
+}
+
+// matches returns the indices of the values of a multi-valued attribute
+// that match the value filter of the path
+func (a *applier) matches(loc *location, values []interface{}) ([]int, error) {
+	// the filter is evaluated as a value path against an object that
+	// only contains the value, so that the attribute characteristics
+	// in the schema are taken into account
+	vp := filter.NewValuePath(filter.NewIdentifierExpr(loc.name()), nil, loc.path.Filter)
+	var options []filter.MatchOption
+	if loc.schema != nil {
+		options = append(options, filter.WithSchema(loc.schema))
+	}
+
+	var indices []int
+	for i, value := range values {
+		ok, err := filter.Match(vp, map[string]interface{}{loc.name(): []interface{}{value}}, options...)
+		if err != nil {
+			return nil, newError(resource.ErrInvalidFilter, `failed to evaluate filter in path %q: %s`, loc.path, err)
+		}
+		if ok {
+			indices = append(indices, i)
+		}
+	}
+	if len(indices) == 0 {
+		return nil, newError(resource.ErrNoTarget, `filter in path %q did not match any values`, loc.path)
+	}
+	return indices, nil
+}
+
+func (a *applier) isMultiValued(loc *location, current interface{}) bool {
+	if loc.attr != nil {
+		return loc.attr.MultiValued()
+	}
+	_, ok := current.([]interface{})
+	return ok
+}
+
+func (a *applier) isComplex(loc *location, current interface{}) bool {
+	if loc.attr != nil {
+		return loc.attr.Type() == resource.Complex
+	}
+	_, ok := current.(map[string]interface{})
+	return ok
+}
+
+func (a *applier) add(loc *location, value interface{}) error {
+	if value == nil {
+		return newError(resource.ErrInvalidValue, `value is required for "add" operations on %q`, loc.path)
+	}
+
+	container := a.container(loc, true)
+	key, current, exists := lookup(container, loc.name())
+	if !exists {
+		key = loc.name()
+	}
+
+	switch {
+	case loc.path.Filter != nil:
+		return a.modifyMatches(resource.PatchAdd, loc, container, key, current, value)
+	case loc.path.SubAttribute != "":
+		return a.modifySubAttribute(resource.PatchAdd, loc, container, key, current, value)
+	}
+
+	if err := checkMutability(resource.PatchAdd, loc.attr, exists && current != nil); err != nil {
+		return err
+	}
+
+	switch {
+	case a.isMultiValued(loc, current) || (loc.attr == nil && isList(value)):
+		// values that are already present are not added again
+		values := asList(current)
+		var added []int
+		for _, v := range asList(value) {
+			if indexOf(values, v) >= 0 {
+				continue
+			}
+			added = append(added, len(values))
+			values = append(values, v)
+		}
+		container[key] = demotePrimary(values, added)
+	case a.isComplex(loc, current):
+		return a.merge(resource.PatchAdd, loc, container, key, current, value)
+	default:
+		container[key] = value
+	}
+	return nil
+}
+
+func (a *applier) replace(loc *location, value interface{}) error {
+	container := a.container(loc, true)
+	key, current, exists := lookup(container, loc.name())
+	if !exists {
+		key = loc.name()
+	}
+
+	if value == nil {
+		return newError(resource.ErrInvalidValue, `value is required for "replace" operations on %q`, loc.path)
+	}
+
+	switch {
+	case loc.path.Filter != nil:
+		return a.modifyMatches(resource.PatchReplace, loc, container, key, current, value)
+	case loc.path.SubAttribute != "":
+		return a.modifySubAttribute(resource.PatchReplace, loc, container, key, current, value)
+	}
+
+	// replacing an attribute that does not exist is the same as adding it
+	typ := resource.PatchReplace
+	if !exists || current == nil {
+		typ = resource.PatchAdd
+	}
+	if err := checkMutability(typ, loc.attr, exists && current != nil); err != nil {
+		return err
+	}
+
+	switch {
+	case a.isMultiValued(loc, current):
+		values := asList(value)
+		added := make([]int, len(values))
+		for i := range values {
+			added[i] = i
+		}
+		container[key] = demotePrimary(values, added)
+	case a.isComplex(loc, current):
+		return a.merge(typ, loc, container, key, current, value)
+	default:
+		container[key] = value
+	}
+	return nil
+}
+
+func (a *applier) remove(loc *location) error {
+	container := a.container(loc, false)
+	key, current, exists := lookup(container, loc.name())
+	if !exists || current == nil {
+		if loc.path.Filter != nil {
+			return newError(resource.ErrNoTarget, `attribute %q has no values to match the filter in path %q`, loc.name(), loc.path)
+		}
+		return nil
+	}
+	defer a.prune(loc)
+
+	switch {
+	case loc.path.Filter != nil:
+		return a.modifyMatches(resource.PatchRemove, loc, container, key, current, nil)
+	case loc.path.SubAttribute != "":
+		return a.modifySubAttribute(resource.PatchRemove, loc, container, key, current, nil)
+	}
+
+	if err := checkMutability(resource.PatchRemove, loc.attr, true); err != nil {
+		return err
+	}
+	if loc.attr != nil && loc.attr.Required() {
+		return newError(resource.ErrInvalidValue, `required attribute %q cannot be removed`, loc.attr.Name())
+	}
+	delete(container, key)
+	return nil
+}
+
+// merge merges the sub-attributes in the value into a single-valued
+// complex attribute
+func (a *applier) merge(typ resource.PatchOperationType, loc *location, container map[string]interface{}, key string, current, value interface{}) error {
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return newError(resource.ErrInvalidValue, `value for complex attribute %q must be an object, got %T`, loc.name(), value)
+	}
+
+	target, _ := current.(map[string]interface{})
+	if target == nil {
+		target = make(map[string]interface{})
+	}
+	if err := a.setSubAttributes(typ, loc, target, obj); err != nil {
+		return err
+	}
+	container[key] = target
+	return nil
+}
+
+// setSubAttributes sets the sub-attributes in src on the complex value dst
+func (a *applier) setSubAttributes(typ resource.PatchOperationType, loc *location, dst, src map[string]interface{}) error {
+	for _, name := range sortedKeys(src) {
+		var subAttr *resource.SchemaAttribute
+		if loc.attr != nil {
+			subAttr = schema.FindAttribute(loc.attr.SubAttributes(), name)
+			if subAttr == nil {
+				return newError(resource.ErrInvalidValue, `attribute %q does not have a sub-attribute %q`, loc.attr.Name(), name)
+			}
+			name = subAttr.Name()
+		}
+
+		value := lookupValue(src, name)
+		key, current, exists := lookup(dst, name)
+		if !exists {
+			key = name
+		}
+		if exists && reflect.DeepEqual(current, value) {
+			continue
+		}
+		if err := checkMutability(typ, subAttr, exists && current != nil); err != nil {
+			return err
+		}
+		dst[key] = value
+	}
+	return nil
+}
+
+// modifySubAttribute applies the operation to the sub-attribute of
+// a complex attribute. If the attribute is multi-valued, the operation
+// is applied to all values
+func (a *applier) modifySubAttribute(typ resource.PatchOperationType, loc *location, container map[string]interface{}, key string, current, value interface{}) error {
+	if err := checkReadOnly(loc.attr); err != nil {
+		return err
+	}
+
+	if a.isMultiValued(loc, current) {
+		values := asList(current)
+		if len(values) == 0 {
+			return newError(resource.ErrNoTarget, `attribute %q has no values to modify in path %q`, loc.name(), loc.path)
+		}
+		indices := make([]int, len(values))
+		for i := range values {
+			indices[i] = i
+		}
+		return a.modifyValues(typ, loc, container, key, values, indices, value)
+	}
+
+	obj, _ := current.(map[string]interface{})
+	if obj == nil {
+		if typ == resource.PatchRemove {
+			return nil
+		}
+		obj = make(map[string]interface{})
+	}
+	if err := a.modifyValue(typ, loc, obj, value); err != nil {
+		return err
+	}
+	if len(obj) == 0 {
+		delete(container, key)
+		return nil
+	}
+	container[key] = obj
+	return nil
+}
+
+// modifyMatches applies the operation to the values of a multi-valued
+// attribute that match the value filter
+func (a *applier) modifyMatches(typ resource.PatchOperationType, loc *location, container map[string]interface{}, key string, current, value interface{}) error {
+	if err := checkReadOnly(loc.attr); err != nil {
+		return err
+	}
+
+	values := asList(current)
+	if len(values) == 0 {
+		return newError(resource.ErrNoTarget, `attribute %q has no values to match the filter in path %q`, loc.name(), loc.path)
+	}
+	indices, err := a.matches(loc, values)
+	if err != nil {
+		return err
+	}
+	return a.modifyValues(typ, loc, container, key, values, indices, value)
+}
+
+// modifyValues applies the operation to the selected values of
+// a multi-valued attribute
+func (a *applier) modifyValues(typ resource.PatchOperationType, loc *location, container map[string]interface{}, key string, values []interface{}, indices []int, value interface{}) error {
+	switch {
+	case typ == resource.PatchRemove && loc.path.SubAttribute == "":
+		if err := checkMutability(typ, loc.attr, true); err != nil {
+			return err
+		}
+		remaining := values[:0]
+		selected := make(map[int]struct{}, len(indices))
+		for _, i := range indices {
+			selected[i] = struct{}{}
+		}
+		for i, v := range values {
+			if _, ok := selected[i]; !ok {
+				remaining = append(remaining, v)
+			}
+		}
+		values = remaining
+	case typ == resource.PatchReplace && loc.path.SubAttribute == "":
+		// the matching values are replaced as a whole
+		for _, i := range indices {
+			values[i] = value
+		}
+		values = demotePrimary(values, indices)
+	default:
+		for _, i := range indices {
+			obj, ok := values[i].(map[string]interface{})
+			if !ok {
+				return newError(resource.ErrInvalidValue, `values of attribute %q are not complex values`, loc.name())
+			}
+			if err := a.modifyValue(typ, loc, obj, value); err != nil {
+				return err
+			}
+		}
+		if typ != resource.PatchRemove {
+			values = demotePrimary(values, indices)
+		}
+	}
+
+	if len(values) == 0 {
+		delete(container, key)
+		return nil
+	}
+	container[key] = values
+	return nil
+}
+
+// modifyValue applies the operation to a single complex value. If the
+// path specifies a sub-attribute, only the sub-attribute is modified.
+// Otherwise the value is an object containing the sub-attributes to
+// be modified
+func (a *applier) modifyValue(typ resource.PatchOperationType, loc *location, obj map[string]interface{}, value interface{}) error {
+	if loc.path.SubAttribute == "" {
+		src, ok := value.(map[string]interface{})
+		if !ok {
+			return newError(resource.ErrInvalidValue, `value for complex attribute %q must be an object, got %T`, loc.name(), value)
+		}
+		return a.setSubAttributes(typ, loc, obj, src)
+	}
+
+	key, current, exists := lookup(obj, loc.subName())
+	if !exists {
+		key = loc.subName()
+	}
+	exists = exists && current != nil
+
+	if typ == resource.PatchRemove {
+		if !exists {
+			return nil
+		}
+		if err := checkMutability(typ, loc.subAttr, true); err != nil {
+			return err
+		}
+		if loc.subAttr != nil && loc.subAttr.Required() {
+			return newError(resource.ErrInvalidValue, `required sub-attribute %q of %q cannot be removed`, loc.subName(), loc.name())
+		}
+		delete(obj, key)
+		return nil
+	}
+
+	if exists && reflect.DeepEqual(current, value) {
+		return nil
+	}
+	if typ == resource.PatchReplace && !exists {
+		typ = resource.PatchAdd
+	}
+	if err := checkMutability(typ, loc.subAttr, exists); err != nil {
+		return err
+	}
+	obj[key] = value
+	return nil
+}
+
+// demotePrimary ensures that at most one value has `primary` set to
+// true. If one of the values that were just written is primary, the
+// other values are no longer primary (RFC7643 Section 2.4)
+func demotePrimary(values []interface{}, written []int) []interface{} {
+	primary := -1
+	for _, i := range written {
+		if isPrimary(values[i]) {
+			primary = i
+		}
+	}
+	if primary < 0 {
+		return values
+	}
+
+	for i, v := range values {
+		if i == primary || !isPrimary(v) {
+			continue
+		}
+		//nolint:forcetypeassert
+		obj := v.(map[string]interface{})
+		key, _, _ := lookup(obj, `primary`)
+		obj[key] = false
+	}
+	return values
+}
+
+func isPrimary(v interface{}) bool {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	_, primary, _ := lookup(obj, `primary`)
+	b, _ := primary.(bool)
+	return b
+}
+
+// lookup fetches the value associated with name from the object.
+// Attribute names are case-insensitive (RFC7643 Section 2.1), so the
+// key that was actually used is returned as well
+func lookup(obj map[string]interface{}, name string) (string, interface{}, bool) {
+	if obj == nil {
+		return "", nil, false
+	}
+	if v, ok := obj[name]; ok {
+		return name, v, true
+	}
+	for key, v := range obj {
+		if strings.EqualFold(key, name) {
+			return key, v, true
+		}
+	}
+	return "", nil, false
+}
+
+func lookupValue(obj map[string]interface{}, name string) interface{} {
+	_, v, _ := lookup(obj, name)
+	return v
+}
+
+func isList(v interface{}) bool {
+	_, ok := v.([]interface{})
+	return ok
+}
+
+func asList(v interface{}) []interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return v
+	default:
+		return []interface{}{v}
+	}
+}
+
+func indexOf(values []interface{}, v interface{}) int {
+	for i, value := range values {
+		if reflect.DeepEqual(value, v) {
+			return i
+		}
+	}
+	return -1
+}
+
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
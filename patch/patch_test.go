@@ -0,0 +1,325 @@
+package patch_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/cybozu-go/scim/patch"
+	"github.com/cybozu-go/scim/resource"
+	"github.com/stretchr/testify/require"
+)
+
+const testUser = `{
+  "schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+  "id": "2819c223-7f76-453a-919d-413861904646",
+  "userName": "bjensen",
+  "name": {"familyName": "Jensen", "givenName": "Barbara"},
+  "emails": [
+    {"value": "bjensen@example.com", "type": "work", "primary": true},
+    {"value": "babs@jensen.org", "type": "home"}
+  ],
+  "meta": {"resourceType": "User", "version": "W/\"3694e05e9dff590\""}
+}`
+
+const testGroup = `{
+  "schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group"],
+  "id": "e9e30dba-f08f-4109-8486-d5c6a331660a",
+  "displayName": "Tour Guides",
+  "members": [
+    {"value": "2819c223-7f76-453a-919d-413861904646", "$ref": "https://example.com/v2/Users/2819c223-7f76-453a-919d-413861904646"},
+    {"value": "902c246b-6245-4190-8e05-00816be7344a", "$ref": "https://example.com/v2/Users/902c246b-6245-4190-8e05-00816be7344a"}
+  ]
+}`
+
+func TestApplyUser(t *testing.T) {
+	testcases := []struct {
+		Name       string
+		Operations string
+		Error      resource.ErrorType
+		Check      func(*testing.T, *resource.User)
+	}{
+		{
+			Name:       `add without path`,
+			Operations: `[{"op":"add","value":{"nickName":"Babs","emails":[{"value":"babs@example.org","type":"other"}]}}]`,
+			Check: func(t *testing.T, u *resource.User) {
+				require.Equal(t, `Babs`, u.NickName())
+				require.Len(t, u.Emails(), 3)
+				require.Equal(t, `babs@example.org`, u.Emails()[2].Value())
+			},
+		},
+		{
+			Name:       `add existing value to multi-valued attribute`,
+			Operations: `[{"op":"add","path":"emails","value":{"value":"babs@jensen.org","type":"home"}}]`,
+			Check: func(t *testing.T, u *resource.User) {
+				require.Len(t, u.Emails(), 2)
+			},
+		},
+		{
+			Name:       `add primary value demotes others`,
+			Operations: `[{"op":"add","path":"emails","value":[{"value":"babs@example.org","type":"other","primary":true}]}]`,
+			Check: func(t *testing.T, u *resource.User) {
+				require.Len(t, u.Emails(), 3)
+				require.False(t, u.Emails()[0].Primary())
+				require.True(t, u.Emails()[2].Primary())
+			},
+		},
+		{
+			Name:       `add sub-attribute`,
+			Operations: `[{"op":"add","path":"name.middleName","value":"Jane"}]`,
+			Check: func(t *testing.T, u *resource.User) {
+				require.Equal(t, `Jane`, u.Name().MiddleName())
+				require.Equal(t, `Jensen`, u.Name().FamilyName())
+			},
+		},
+		{
+			Name:       `replace complex attribute merges sub-attributes`,
+			Operations: `[{"op":"replace","path":"name","value":{"givenName":"Babs"}}]`,
+			Check: func(t *testing.T, u *resource.User) {
+				require.Equal(t, `Babs`, u.Name().GivenName())
+				require.Equal(t, `Jensen`, u.Name().FamilyName())
+			},
+		},
+		{
+			Name:       `replace multi-valued attribute`,
+			Operations: `[{"op":"replace","path":"emails","value":[{"value":"babs@example.org","type":"work"}]}]`,
+			Check: func(t *testing.T, u *resource.User) {
+				require.Len(t, u.Emails(), 1)
+				require.Equal(t, `babs@example.org`, u.Emails()[0].Value())
+			},
+		},
+		{
+			Name:       `replace with value filter and sub-attribute`,
+			Operations: `[{"op":"replace","path":"emails[type eq \"work\"].value","value":"barbara@example.com"}]`,
+			Check: func(t *testing.T, u *resource.User) {
+				require.Len(t, u.Emails(), 2)
+				require.Equal(t, `barbara@example.com`, u.Emails()[0].Value())
+				require.Equal(t, `babs@jensen.org`, u.Emails()[1].Value())
+			},
+		},
+		{
+			Name:       `replace with value filter`,
+			Operations: `[{"op":"replace","path":"emails[type eq \"home\"]","value":{"value":"babs@example.org","type":"home","primary":true}}]`,
+			Check: func(t *testing.T, u *resource.User) {
+				require.Len(t, u.Emails(), 2)
+				require.False(t, u.Emails()[0].Primary())
+				require.Equal(t, `babs@example.org`, u.Emails()[1].Value())
+				require.True(t, u.Emails()[1].Primary())
+			},
+		},
+		{
+			Name:       `replace without path`,
+			Operations: `[{"op":"Replace","value":{"displayName":"Babs Jensen","active":false}}]`,
+			Check: func(t *testing.T, u *resource.User) {
+				require.Equal(t, `Babs Jensen`, u.DisplayName())
+				require.False(t, u.Active())
+			},
+		},
+		{
+			Name:       `remove with value filter`,
+			Operations: `[{"op":"remove","path":"emails[type eq \"home\"]"}]`,
+			Check: func(t *testing.T, u *resource.User) {
+				require.Len(t, u.Emails(), 1)
+				require.Equal(t, `work`, u.Emails()[0].Type())
+			},
+		},
+		{
+			Name:       `remove attribute`,
+			Operations: `[{"op":"remove","path":"emails"}]`,
+			Check: func(t *testing.T, u *resource.User) {
+				require.False(t, u.HasEmails())
+			},
+		},
+		{
+			Name:       `remove sub-attribute`,
+			Operations: `[{"op":"remove","path":"name.givenName"}]`,
+			Check: func(t *testing.T, u *resource.User) {
+				require.False(t, u.Name().HasGivenName())
+				require.Equal(t, `Jensen`, u.Name().FamilyName())
+			},
+		},
+		{
+			Name:       `remove attribute that does not exist`,
+			Operations: `[{"op":"remove","path":"title"}]`,
+			Check: func(t *testing.T, u *resource.User) {
+				require.False(t, u.HasTitle())
+			},
+		},
+		{
+			Name:       `add extension attribute`,
+			Operations: `[{"op":"add","path":"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber","value":"701984"}]`,
+			Check: func(t *testing.T, u *resource.User) {
+				require.Contains(t, u.Schemas(), resource.EnterpriseUserSchemaURI)
+				var ext resource.EnterpriseUser
+				require.NoError(t, u.Get(resource.EnterpriseUserSchemaURI, &ext))
+				require.Equal(t, `701984`, ext.EmployeeNumber())
+			},
+		},
+		{
+			Name:       `add extension attribute without path`,
+			Operations: `[{"op":"add","value":{"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User":{"department":"Tour Operations"}}}]`,
+			Check: func(t *testing.T, u *resource.User) {
+				var ext resource.EnterpriseUser
+				require.NoError(t, u.Get(resource.EnterpriseUserSchemaURI, &ext))
+				require.Equal(t, `Tour Operations`, ext.Department())
+			},
+		},
+		{
+			Name:       `operations are applied in order`,
+			Operations: `[{"op":"add","path":"title","value":"Tour Guide"},{"op":"replace","path":"title","value":"Tour Lead"}]`,
+			Check: func(t *testing.T, u *resource.User) {
+				require.Equal(t, `Tour Lead`, u.Title())
+			},
+		},
+		{
+			Name:       `filter without matches`,
+			Operations: `[{"op":"replace","path":"emails[type eq \"other\"].value","value":"babs@example.org"}]`,
+			Error:      resource.ErrNoTarget,
+		},
+		{
+			Name:       `remove without path`,
+			Operations: `[{"op":"remove"}]`,
+			Error:      resource.ErrNoTarget,
+		},
+		{
+			Name:       `unknown attribute`,
+			Operations: `[{"op":"add","path":"nickname.foo","value":"Babs"}]`,
+			Error:      resource.ErrInvalidPath,
+		},
+		{
+			Name:       `malformed path`,
+			Operations: `[{"op":"add","path":"emails[type eq","value":"Babs"}]`,
+			Error:      resource.ErrInvalidPath,
+		},
+		{
+			Name:       `read-only attribute`,
+			Operations: `[{"op":"replace","path":"id","value":"foo"}]`,
+			Error:      resource.ErrMutability,
+		},
+		{
+			Name:       `required attribute`,
+			Operations: `[{"op":"remove","path":"userName"}]`,
+			Error:      resource.ErrInvalidValue,
+		},
+		{
+			Name:       `invalid operation`,
+			Operations: `[{"op":"move","path":"userName"}]`,
+			Error:      resource.ErrInvalidSyntax,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			var user resource.User
+			require.NoError(t, json.Unmarshal([]byte(testUser), &user), `json.Unmarshal should succeed`)
+
+			var req resource.PatchRequest
+			require.NoError(t, json.Unmarshal([]byte(`{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"operations":`+tc.Operations+`}`), &req), `json.Unmarshal should succeed`)
+
+			patched, err := patch.ApplyUser(&user, &req)
+			if tc.Error != "" {
+				require.Error(t, err, `patch.ApplyUser should fail`)
+				var rerr *resource.Error
+				require.True(t, errors.As(err, &rerr), `error should be a *resource.Error`)
+				require.Equal(t, tc.Error, rerr.SCIMType(), `scimType should match`)
+				return
+			}
+			require.NoError(t, err, `patch.ApplyUser should succeed`)
+			require.Equal(t, `bjensen`, user.UserName(), `original user should not be modified`)
+			require.Len(t, user.Emails(), 2, `original user should not be modified`)
+			tc.Check(t, patched)
+		})
+	}
+}
+
+func TestApplyGroup(t *testing.T) {
+	var group resource.Group
+	require.NoError(t, json.Unmarshal([]byte(testGroup), &group), `json.Unmarshal should succeed`)
+
+	var req resource.PatchRequest
+	require.NoError(t, json.Unmarshal([]byte(`{
+  "schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+  "operations": [
+    {"op": "remove", "path": "members[value eq \"2819c223-7f76-453a-919d-413861904646\"]"},
+    {"op": "add", "path": "members", "value": [{"value": "08e1d05d-121c-4561-8b96-473d93df9210"}]}
+  ]
+}`), &req), `json.Unmarshal should succeed`)
+
+	patched, err := patch.ApplyGroup(&group, &req)
+	require.NoError(t, err, `patch.ApplyGroup should succeed`)
+	require.Len(t, patched.Members(), 2)
+	require.Equal(t, `902c246b-6245-4190-8e05-00816be7344a`, patched.Members()[0].Value())
+	require.Equal(t, `08e1d05d-121c-4561-8b96-473d93df9210`, patched.Members()[1].Value())
+
+	require.NoError(t, json.Unmarshal([]byte(`{
+  "schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+  "operations": [
+    {"op": "replace", "path": "members[value eq \"2819c223-7f76-453a-919d-413861904646\"].value", "value": "08e1d05d-121c-4561-8b96-473d93df9210"}
+  ]
+}`), &req), `json.Unmarshal should succeed`)
+	_, err = patch.ApplyGroup(&group, &req)
+	var rerr *resource.Error
+	require.True(t, errors.As(err, &rerr), `error should be a *resource.Error`)
+	require.Equal(t, resource.ErrMutability, rerr.SCIMType(), `immutable sub-attributes cannot be replaced`)
+}
+
+func TestApplyMap(t *testing.T) {
+	var req resource.PatchRequest
+	require.NoError(t, json.Unmarshal([]byte(`{
+  "schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+  "operations": [{"op": "add", "path": "name.formatted", "value": "Ms. Barbara J Jensen III"}]
+}`), &req), `json.Unmarshal should succeed`)
+
+	patched, err := patch.Apply(map[string]interface{}{
+		`schemas`:  []interface{}{resource.UserSchemaURI},
+		`userName`: `bjensen`,
+	}, &req)
+	require.NoError(t, err, `patch.Apply should succeed`)
+	require.Equal(t, map[string]interface{}{
+		`schemas`:  []interface{}{resource.UserSchemaURI},
+		`userName`: `bjensen`,
+		`name`:     map[string]interface{}{`formatted`: `Ms. Barbara J Jensen III`},
+	}, patched)
+}
+
+func TestApplyResourceObject(t *testing.T) {
+	var user resource.User
+	require.NoError(t, json.Unmarshal([]byte(testUser), &user), `json.Unmarshal should succeed`)
+
+	var req resource.PatchRequest
+	require.NoError(t, json.Unmarshal([]byte(`{
+  "schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+  "operations": [
+    {"op": "replace", "path": "emails[type eq \"work\"].value", "value": "barbara@example.com"},
+    {"op": "remove", "path": "emails[type eq \"home\"]"},
+    {"op": "remove", "path": "name"},
+    {"op": "add", "path": "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber", "value": "701984"}
+  ]
+}`), &req), `json.Unmarshal should succeed`)
+
+	patched, err := patch.Apply(&user, &req)
+	require.NoError(t, err, `patch.Apply should succeed`)
+	patchedUser, ok := patched.(*resource.User)
+	require.True(t, ok, `result should be a *resource.User`)
+	require.Equal(t, `barbara@example.com`, patchedUser.Emails()[0].Value())
+	require.Len(t, patchedUser.Emails(), 1, `home email should be removed`)
+	require.False(t, patchedUser.HasName(), `name should be removed`)
+	require.Equal(t, user.UserName(), patchedUser.UserName(), `userName should be left unchanged`)
+
+	var employeeNumber string
+	require.NoError(t, patchedUser.GetExtension(`employeeNumber`, resource.EnterpriseUserSchemaURI, &employeeNumber), `GetExtension should succeed`)
+	require.Equal(t, `701984`, employeeNumber)
+
+	require.Equal(t, `bjensen@example.com`, user.Emails()[0].Value(), `target should not be modified`)
+	require.True(t, user.HasName(), `target should not be modified`)
+
+	require.NoError(t, json.Unmarshal([]byte(`{
+  "schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+  "operations": [{"op": "replace", "path": "emails[type eq \"other\"].value", "value": "barbara@example.com"}]
+}`), &req), `json.Unmarshal should succeed`)
+	_, err = patch.Apply(&user, &req)
+	var rerr *resource.Error
+	require.True(t, errors.As(err, &rerr), `error should be a *resource.Error`)
+	require.Equal(t, resource.ErrNoTarget, rerr.SCIMType(), `filters without matches should be reported`)
+}
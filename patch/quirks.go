@@ -0,0 +1,317 @@
+package patch
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/cybozu-go/scim/filter"
+	"github.com/cybozu-go/scim/resource"
+	"github.com/cybozu-go/scim/schema"
+)
+
+// Quirks is a set of deviations from RFC7644 that are commonly found in
+// PATCH requests sent by identity providers. Use `patch.Normalize()`
+// to rewrite such requests into their canonical form.
+type Quirks uint
+
+const (
+	// QuirkOperationCase accepts operations whose names are not in
+	// lower case (e.g. `"op": "Replace"`)
+	QuirkOperationCase Quirks = 1 << iota
+	// QuirkStringBooleans accepts the strings "true" and "false",
+	// in any case, as values of boolean attributes (e.g. `"False"`)
+	QuirkStringBooleans
+	// QuirkPathKeys accepts `add` and `replace` operations without
+	// a path, whose value objects use attribute paths as keys
+	// (e.g. `{"name.givenName": "Babs"}`)
+	QuirkPathKeys
+	// QuirkRemoveValue accepts `remove` operations that specify the
+	// values to be removed from a multi-valued attribute in `value`
+	// (e.g. `"path": "members", "value": [{"value": "2819c223"}]`),
+	// rather than in a value filter
+	QuirkRemoveValue
+
+	// QuirksNone does not accept any deviations
+	QuirksNone Quirks = 0
+	// QuirksAll accepts all known deviations
+	QuirksAll = QuirkOperationCase | QuirkStringBooleans | QuirkPathKeys | QuirkRemoveValue
+)
+
+// Normalize rewrites the PATCH request so that the deviations listed
+// in quirks are replaced with their canonical form, and returns the
+// resulting request. Operations that do not exhibit any of the
+// deviations are kept as they are.
+//
+// The schema of the resource being patched is used to find boolean
+// attributes. If it is nil, QuirkStringBooleans has no effect.
+func Normalize(req *resource.PatchRequest, s *resource.Schema, quirks Quirks) (*resource.PatchRequest, error) {
+	n := normalizer{schema: s, quirks: quirks}
+
+	var operations []*resource.PatchOperation
+	for i, op := range req.Operations() {
+		normalized, err := n.normalize(op)
+		if err != nil {
+			return nil, fmt.Errorf(`patch: failed to normalize operation %d: %w`, i, err)
+		}
+		operations = append(operations, normalized...)
+	}
+
+	return resource.NewPatchRequestBuilder().
+		Schemas(req.Schemas()...).
+		Operations(operations...).
+		Build()
+}
+
+type normalizer struct {
+	schema *resource.Schema
+	quirks Quirks
+}
+
+func (n *normalizer) enabled(q Quirks) bool {
+	return n.quirks&q == q
+}
+
+func (n *normalizer) normalize(op *resource.PatchOperation) ([]*resource.PatchOperation, error) {
+	typ := op.Op()
+	if n.enabled(QuirkOperationCase) {
+		typ = resource.PatchOperationType(strings.ToLower(string(typ)))
+	}
+	path := op.Path()
+	value := op.Value()
+	modified := typ != op.Op()
+
+	if n.enabled(QuirkRemoveValue) && typ == resource.PatchRemove && path != "" && value != nil {
+		if p, ok := removeValuePath(path, value); ok {
+			path = p
+			value = nil
+			modified = true
+		}
+	}
+
+	var split []*resource.PatchOperation
+	if n.enabled(QuirkPathKeys) && path == "" && (typ == resource.PatchAdd || typ == resource.PatchReplace) {
+		if obj, ok := value.(map[string]interface{}); ok {
+			remaining := make(map[string]interface{}, len(obj))
+			for _, key := range sortedKeys(obj) {
+				if !isPathKey(key) {
+					remaining[key] = obj[key]
+					continue
+				}
+				v := obj[key]
+				if n.enabled(QuirkStringBooleans) {
+					v = n.convertPath(key, v)
+				}
+				o, err := buildOperation(typ, key, v)
+				if err != nil {
+					return nil, err
+				}
+				split = append(split, o)
+			}
+			if len(split) > 0 {
+				modified = true
+				value = remaining
+				if len(remaining) == 0 {
+					return split, nil
+				}
+			}
+		}
+	}
+
+	if n.enabled(QuirkStringBooleans) && value != nil {
+		var converted interface{}
+		if path == "" {
+			converted = n.convertObject(value)
+		} else {
+			converted = n.convertPath(path, value)
+		}
+		if !reflect.DeepEqual(converted, value) {
+			value = converted
+			modified = true
+		}
+	}
+
+	if !modified {
+		return []*resource.PatchOperation{op}, nil
+	}
+
+	o, err := buildOperation(typ, path, value)
+	if err != nil {
+		return nil, err
+	}
+	return append([]*resource.PatchOperation{o}, split...), nil
+}
+
+func buildOperation(typ resource.PatchOperationType, path string, value interface{}) (*resource.PatchOperation, error) {
+	b := resource.NewPatchOperationBuilder().Op(typ)
+	if path != "" {
+		b.Path(path)
+	}
+	if value != nil {
+		b.Value(value)
+	}
+	return b.Build()
+}
+
+// isPathKey reports whether the key of a value object is an attribute
+// path, rather than an attribute name or the URI of a schema extension
+func isPathKey(key string) bool {
+	if _, ok := schema.Get(key); ok {
+		return false
+	}
+	path, err := filter.ParsePath(key)
+	if err != nil {
+		return false
+	}
+	return path.Filter == nil && (path.SchemaURI != "" || path.SubAttribute != "")
+}
+
+// removeValuePath converts the values given to a `remove` operation
+// into a value filter on the path (e.g. `members[value eq "2819c223"]`)
+func removeValuePath(path string, value interface{}) (string, bool) {
+	p, err := filter.ParsePath(path)
+	if err != nil || p.Filter != nil || p.SubAttribute != "" {
+		return "", false
+	}
+
+	var values []interface{}
+	switch v := value.(type) {
+	case []interface{}:
+		values = v
+	case map[string]interface{}:
+		values = []interface{}{v}
+	default:
+		return "", false
+	}
+
+	var expr filter.Expr
+	for _, v := range values {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		_, s, ok := lookup(obj, `value`)
+		if !ok {
+			return "", false
+		}
+		str, ok := s.(string)
+		if !ok {
+			return "", false
+		}
+		cmp := filter.NewCompareExpr(filter.NewIdentifierExpr(`value`), filter.EqualOp, filter.NewAttrValueExpr(str))
+		if expr == nil {
+			expr = cmp
+		} else {
+			expr = filter.NewLogExpr(expr, filter.OrOp, cmp)
+		}
+	}
+	if expr == nil {
+		return "", false
+	}
+	p.Filter = expr
+	return p.String(), true
+}
+
+// convertObject converts the boolean values in a value object of an
+// operation without a path
+func (n *normalizer) convertObject(value interface{}) interface{} {
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return value
+	}
+
+	converted := make(map[string]interface{}, len(obj))
+	for key, v := range obj {
+		if ext, ok := schema.Get(key); ok && (n.schema == nil || ext.ID() != n.schema.ID()) {
+			converted[key] = convertAttributes(ext.Attributes(), v)
+			continue
+		}
+		if n.schema == nil {
+			converted[key] = v
+			continue
+		}
+		converted[key] = convertValue(schema.FindAttribute(n.schema.Attributes(), key), v)
+	}
+	return converted
+}
+
+// convertPath converts the boolean values in the value of an operation
+// on the attribute path
+func (n *normalizer) convertPath(path string, value interface{}) interface{} {
+	p, err := filter.ParsePath(path)
+	if err != nil {
+		return value
+	}
+
+	s := n.schema
+	if p.SchemaURI != "" && (s == nil || !strings.EqualFold(p.SchemaURI, s.ID())) {
+		ext, ok := schema.Get(p.SchemaURI)
+		if !ok {
+			return value
+		}
+		s = ext
+	}
+	if s == nil {
+		return value
+	}
+
+	attr := schema.FindAttribute(s.Attributes(), p.Attribute)
+	if attr == nil {
+		return value
+	}
+	if p.SubAttribute != "" {
+		return convertValue(schema.FindAttribute(attr.SubAttributes(), p.SubAttribute), value)
+	}
+	if p.Filter != nil {
+		// the value replaces the matching values, not the attribute
+		return convertAttributes(attr.SubAttributes(), value)
+	}
+	return convertValue(attr, value)
+}
+
+// convertValue converts the value of the attribute
+func convertValue(attr *resource.SchemaAttribute, value interface{}) interface{} {
+	if attr == nil {
+		return value
+	}
+
+	switch v := value.(type) {
+	case string:
+		if attr.Type() != resource.Boolean {
+			return value
+		}
+		switch strings.ToLower(v) {
+		case `true`:
+			return true
+		case `false`:
+			return false
+		}
+	case []interface{}:
+		converted := make([]interface{}, len(v))
+		for i, elem := range v {
+			if attr.Type() == resource.Complex {
+				converted[i] = convertAttributes(attr.SubAttributes(), elem)
+			} else {
+				converted[i] = convertValue(attr, elem)
+			}
+		}
+		return converted
+	case map[string]interface{}:
+		return convertAttributes(attr.SubAttributes(), v)
+	}
+	return value
+}
+
+// convertAttributes converts the values in an object whose keys are
+// the names of the given attributes
+func convertAttributes(attrs []*resource.SchemaAttribute, value interface{}) interface{} {
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return value
+	}
+	converted := make(map[string]interface{}, len(obj))
+	for key, v := range obj {
+		converted[key] = convertValue(schema.FindAttribute(attrs, key), v)
+	}
+	return converted
+}
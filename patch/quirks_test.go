@@ -0,0 +1,122 @@
+package patch_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cybozu-go/scim/patch"
+	"github.com/cybozu-go/scim/resource"
+	"github.com/cybozu-go/scim/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	userSchema, _ := schema.Get(resource.UserSchemaURI)
+	groupSchema, _ := schema.Get(resource.GroupSchemaURI)
+
+	testcases := []struct {
+		Name       string
+		Schema     *resource.Schema
+		Quirks     patch.Quirks
+		Operations string
+		Expected   string
+	}{
+		{
+			Name:       `no quirks`,
+			Schema:     userSchema,
+			Quirks:     patch.QuirksNone,
+			Operations: `[{"op":"Replace","path":"active","value":"False"}]`,
+			Expected:   `[{"op":"Replace","path":"active","value":"False"}]`,
+		},
+		{
+			Name:       `operation case`,
+			Schema:     userSchema,
+			Quirks:     patch.QuirkOperationCase,
+			Operations: `[{"op":"Replace","path":"active","value":"False"},{"op":"ADD","path":"title","value":"Tour Guide"}]`,
+			Expected:   `[{"op":"replace","path":"active","value":"False"},{"op":"add","path":"title","value":"Tour Guide"}]`,
+		},
+		{
+			Name:       `string booleans with path`,
+			Schema:     userSchema,
+			Quirks:     patch.QuirkStringBooleans,
+			Operations: `[{"op":"replace","path":"active","value":"False"},{"op":"replace","path":"title","value":"True"}]`,
+			Expected:   `[{"op":"replace","path":"active","value":false},{"op":"replace","path":"title","value":"True"}]`,
+		},
+		{
+			Name:       `string booleans in sub-attributes`,
+			Schema:     userSchema,
+			Quirks:     patch.QuirkStringBooleans,
+			Operations: `[{"op":"add","path":"emails","value":[{"value":"babs@example.org","primary":"TRUE"}]},{"op":"replace","path":"emails[type eq \"work\"].primary","value":"false"}]`,
+			Expected:   `[{"op":"add","path":"emails","value":[{"value":"babs@example.org","primary":true}]},{"op":"replace","path":"emails[type eq \"work\"].primary","value":false}]`,
+		},
+		{
+			Name:       `string booleans without path`,
+			Schema:     userSchema,
+			Quirks:     patch.QuirkStringBooleans,
+			Operations: `[{"op":"replace","value":{"active":"True","displayName":"False"}}]`,
+			Expected:   `[{"op":"replace","value":{"active":true,"displayName":"False"}}]`,
+		},
+		{
+			Name:       `path keys`,
+			Schema:     userSchema,
+			Quirks:     patch.QuirkPathKeys,
+			Operations: `[{"op":"replace","value":{"displayName":"Babs Jensen","name.givenName":"Babs","urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department":"Tour Operations"}}]`,
+			Expected: `[
+  {"op":"replace","value":{"displayName":"Babs Jensen"}},
+  {"op":"replace","path":"name.givenName","value":"Babs"},
+  {"op":"replace","path":"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department","value":"Tour Operations"}
+]`,
+		},
+		{
+			Name:       `path keys only`,
+			Schema:     userSchema,
+			Quirks:     patch.QuirkPathKeys | patch.QuirkStringBooleans,
+			Operations: `[{"op":"add","value":{"emails.primary":"False"}}]`,
+			Expected:   `[{"op":"add","path":"emails.primary","value":false}]`,
+		},
+		{
+			Name:       `path keys leave extension objects alone`,
+			Schema:     userSchema,
+			Quirks:     patch.QuirkPathKeys,
+			Operations: `[{"op":"add","value":{"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User":{"department":"Tour Operations"}}}]`,
+			Expected:   `[{"op":"add","value":{"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User":{"department":"Tour Operations"}}}]`,
+		},
+		{
+			Name:       `remove value`,
+			Schema:     groupSchema,
+			Quirks:     patch.QuirkRemoveValue,
+			Operations: `[{"op":"remove","path":"members","value":[{"value":"2819c223"},{"value":"902c246b"}]}]`,
+			Expected:   `[{"op":"remove","path":"members[value eq \"2819c223\" or value eq \"902c246b\"]"}]`,
+		},
+		{
+			Name:       `remove value without value sub-attribute`,
+			Schema:     groupSchema,
+			Quirks:     patch.QuirkRemoveValue,
+			Operations: `[{"op":"remove","path":"members","value":[{"display":"Babs Jensen"}]}]`,
+			Expected:   `[{"op":"remove","path":"members","value":[{"display":"Babs Jensen"}]}]`,
+		},
+		{
+			Name:       `all quirks`,
+			Schema:     groupSchema,
+			Quirks:     patch.QuirksAll,
+			Operations: `[{"op":"Remove","path":"members","value":[{"value":"2819c223"}]},{"op":"Add","path":"members","value":[{"value":"08e1d05d"}]}]`,
+			Expected:   `[{"op":"remove","path":"members[value eq \"2819c223\"]"},{"op":"add","path":"members","value":[{"value":"08e1d05d"}]}]`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			var req resource.PatchRequest
+			require.NoError(t, json.Unmarshal([]byte(`{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"operations":`+tc.Operations+`}`), &req), `json.Unmarshal should succeed`)
+
+			normalized, err := patch.Normalize(&req, tc.Schema, tc.Quirks)
+			require.NoError(t, err, `patch.Normalize should succeed`)
+			require.Equal(t, []string{resource.PatchRequestSchemaURI}, normalized.Schemas(), `schemas should be preserved`)
+
+			serialized, err := json.Marshal(normalized.Operations())
+			require.NoError(t, err, `json.Marshal should succeed`)
+			require.JSONEq(t, tc.Expected, string(serialized), `operations should match`)
+		})
+	}
+}
@@ -0,0 +1,270 @@
+// Package projection selects the attributes of SCIM resources that are
+// returned to clients, according to the `attributes` and
+// `excludedAttributes` parameters (RFC7644 Section 3.9) and the
+// `returned` characteristic of each attribute (RFC7643 Section 2.4).
+package projection
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/cybozu-go/scim/filter"
+	"github.com/cybozu-go/scim/resource"
+	"github.com/cybozu-go/scim/schema"
+)
+
+// Apply returns the JSON representation of v, as a map, that only
+// contains the attributes that should be returned to the client.
+//
+// v is usually a *resource.User or a *resource.Group, but any resource
+// object that can be serialized to JSON, as well as map[string]interface{}
+// values can be projected. The result is not converted back to the type
+// of v, as projected resources may lack attributes that are required by
+// the resource object (e.g. `userName`). It can be serialized to JSON
+// in the same way as the resource object.
+//
+// The attributes are selected as follows:
+//
+//   - Attributes that are `returned: never` (e.g. `password`) are never
+//     returned, and attributes that are `returned: always` (e.g. `id`),
+//     as well as `schemas`, are always returned.
+//   - If attributes is not empty, only the specified attributes are
+//     returned. Specifying a sub-attribute (e.g. `name.givenName`)
+//     returns the complex attribute with only that sub-attribute.
+//   - Otherwise, attributes that are `returned: default` are returned,
+//     except for those in excludedAttributes.
+//   - Attributes that are `returned: request` are only returned if they
+//     are specified in attributes.
+//
+// Attribute names are case insensitive, and may be qualified with the
+// URI of the schema (e.g. `urn:ietf:params:scim:schemas:core:2.0:User:userName`).
+// Attributes of schema extensions must be qualified with the URI of the
+// extension, and the URI by itself selects the whole extension.
+// Attributes that are not defined in the schema are treated as
+// `returned: default`.
+//
+// Attribute paths that cannot be parsed are reported as a *resource.Error
+// with the `invalidPath` type.
+func Apply(v interface{}, attributes, excludedAttributes []string) (map[string]interface{}, error) {
+	s, _ := schema.ForResource(v)
+	include, err := parseSelection(s, attributes)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := parseSelection(s, excludedAttributes)
+	if err != nil {
+		return nil, err
+	}
+
+	serialized, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf(`projection: failed to serialize resource: %w`, err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(serialized, &doc); err != nil {
+		return nil, fmt.Errorf(`projection: failed to deserialize resource: %w`, err)
+	}
+
+	var attrs []*resource.SchemaAttribute
+	if s != nil {
+		attrs = s.Attributes()
+	}
+	return projectAttributes(attrs, doc, include, exclude, true), nil
+}
+
+// ApplyListResponse returns a copy of the list response, whose resources
+// are replaced with their projections. See `projection.Apply()` for
+// details.
+func ApplyListResponse(v *resource.ListResponse, attributes, excludedAttributes []string) (*resource.ListResponse, error) {
+	var projected resource.ListResponse
+	if err := v.Clone(&projected); err != nil {
+		return nil, fmt.Errorf(`projection: failed to clone list response: %w`, err)
+	}
+	if !v.HasResources() {
+		return &projected, nil
+	}
+
+	resources := make([]interface{}, 0, len(v.Resources()))
+	for _, r := range v.Resources() {
+		p, err := Apply(r, attributes, excludedAttributes)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, p)
+	}
+	if err := projected.Set(resource.ListResponseResourcesKey, resources); err != nil {
+		return nil, fmt.Errorf(`projection: failed to set resources: %w`, err)
+	}
+	return &projected, nil
+}
+
+// selection is a set of attributes, keyed by their names in lower case.
+// An attribute whose value is nil is selected as a whole, otherwise only
+// the sub-attributes in the value are selected. The attributes of schema
+// extensions are keyed by the URI of the extension, in the same way as
+// they appear in resources.
+type selection map[string]selection
+
+func (s selection) add(names ...string) {
+	for i, name := range names {
+		sub, ok := s[name]
+		if ok && sub == nil {
+			// the attribute is already selected as a whole
+			return
+		}
+		if i == len(names)-1 {
+			s[name] = nil
+			return
+		}
+		if sub == nil {
+			sub = make(selection)
+			s[name] = sub
+		}
+		s = sub
+	}
+}
+
+// lookupSchema returns the schema registered in the `schema` package
+// by its case-insensitive schema URI
+func lookupSchema(uri string) (*resource.Schema, bool) {
+	for _, s := range schema.All() {
+		if strings.EqualFold(uri, s.ID()) {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// parseSelection parses the attribute paths specified by the client.
+// It returns nil if no paths are specified
+func parseSelection(s *resource.Schema, paths []string) (selection, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	sel := make(selection)
+	for _, path := range paths {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+
+		if ext, ok := lookupSchema(path); ok && (s == nil || ext.ID() != s.ID()) {
+			sel.add(strings.ToLower(path))
+			continue
+		}
+
+		p, err := filter.ParsePath(path)
+		if err != nil {
+			return nil, newError(`invalid attribute path %q: %s`, path, err)
+		}
+		if p.Filter != nil {
+			return nil, newError(`invalid attribute path %q: filters are not allowed`, path)
+		}
+
+		var names []string
+		if p.SchemaURI != "" && (s == nil || !strings.EqualFold(p.SchemaURI, s.ID())) {
+			names = append(names, strings.ToLower(p.SchemaURI))
+		}
+		names = append(names, strings.ToLower(p.Attribute))
+		if p.SubAttribute != "" {
+			names = append(names, strings.ToLower(p.SubAttribute))
+		}
+		sel.add(names...)
+	}
+	if len(sel) == 0 {
+		return nil, nil
+	}
+	return sel, nil
+}
+
+// projectAttributes selects the attributes of a resource, an extension,
+// or a complex value. include is nil when the client did not specify
+// the attributes to be returned
+func projectAttributes(attrs []*resource.SchemaAttribute, obj map[string]interface{}, include, exclude selection, toplevel bool) map[string]interface{} {
+	result := make(map[string]interface{}, len(obj))
+	for key, value := range obj {
+		name := strings.ToLower(key)
+
+		var subAttrs []*resource.SchemaAttribute
+		returned := resource.ReturnedDefault
+		switch ext, isExt := lookupSchema(key); {
+		case toplevel && name == `schemas`:
+			returned = resource.ReturnedAlways
+		case toplevel && isExt:
+			subAttrs = ext.Attributes()
+		default:
+			if attr := schema.FindAttribute(attrs, key); attr != nil {
+				subAttrs = attr.SubAttributes()
+				if r := attr.Returned(); r != "" {
+					returned = r
+				}
+			}
+		}
+
+		var subInclude, subExclude selection
+		switch returned {
+		case resource.ReturnedNever:
+			continue
+		case resource.ReturnedAlways:
+		default:
+			if include != nil {
+				sub, ok := include[name]
+				if !ok {
+					continue
+				}
+				subInclude = sub
+			} else if returned == resource.ReturnedRequest {
+				continue
+			}
+			if sub, ok := exclude[name]; ok {
+				if sub == nil {
+					continue
+				}
+				subExclude = sub
+			}
+		}
+
+		if projected, ok := projectValue(subAttrs, value, subInclude, subExclude); ok {
+			result[key] = projected
+		}
+	}
+	return result
+}
+
+// projectValue selects the sub-attributes of a complex value, or of
+// each of the values of a multi-valued attribute. It returns false if
+// none of the sub-attributes that were specified are present
+func projectValue(attrs []*resource.SchemaAttribute, value interface{}, include, exclude selection) (interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		projected := projectAttributes(attrs, v, include, exclude, false)
+		if len(projected) == 0 && len(v) > 0 && (include != nil || exclude != nil) {
+			return nil, false
+		}
+		return projected, true
+	case []interface{}:
+		projected := make([]interface{}, 0, len(v))
+		for _, elem := range v {
+			if p, ok := projectValue(attrs, elem, include, exclude); ok {
+				projected = append(projected, p)
+			}
+		}
+		if len(projected) == 0 && len(v) > 0 {
+			return nil, false
+		}
+		return projected, true
+	default:
+		return value, true
+	}
+}
+
+func newError(format string, args ...interface{}) error {
+	return resource.NewErrorBuilder().
+		Status(http.StatusBadRequest).
+		SCIMType(resource.ErrInvalidPath).
+		Detail(fmt.Sprintf(format, args...)).
+		MustBuild()
+}
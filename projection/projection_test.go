@@ -0,0 +1,203 @@
+package projection_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cybozu-go/scim/projection"
+	"github.com/cybozu-go/scim/resource"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApply(t *testing.T) {
+	const user = `{
+  "schemas": [
+    "urn:ietf:params:scim:schemas:core:2.0:User",
+    "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+  ],
+  "id": "2819c223-7f76-453a-919d-413861904646",
+  "userName": "bjensen",
+  "password": "t1meMa$heen",
+  "title": "Tour Guide",
+  "name": {"familyName": "Jensen", "givenName": "Barbara", "middleName": "Jane"},
+  "emails": [
+    {"value": "bjensen@example.com", "type": "work", "primary": true},
+    {"value": "babs@jensen.org", "type": "home"}
+  ],
+  "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {
+    "employeeNumber": "701984",
+    "department": "Tour Operations"
+  },
+  "meta": {"resourceType": "User", "version": "W/\"1\""}
+}`
+
+	testcases := []struct {
+		Name               string
+		Attributes         []string
+		ExcludedAttributes []string
+		Expected           string
+		Error              bool
+	}{
+		{
+			Name: `default`,
+			Expected: `{
+  "schemas": [
+    "urn:ietf:params:scim:schemas:core:2.0:User",
+    "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+  ],
+  "id": "2819c223-7f76-453a-919d-413861904646",
+  "userName": "bjensen",
+  "title": "Tour Guide",
+  "name": {"familyName": "Jensen", "givenName": "Barbara", "middleName": "Jane"},
+  "emails": [
+    {"value": "bjensen@example.com", "type": "work", "primary": true},
+    {"value": "babs@jensen.org", "type": "home"}
+  ],
+  "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {
+    "employeeNumber": "701984",
+    "department": "Tour Operations"
+  },
+  "meta": {"resourceType": "User", "version": "W/\"1\""}
+}`,
+		},
+		{
+			Name:       `attributes`,
+			Attributes: []string{`UserName`, `name.givenName`, `emails.type`, `password`},
+			Expected: `{
+  "schemas": [
+    "urn:ietf:params:scim:schemas:core:2.0:User",
+    "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+  ],
+  "id": "2819c223-7f76-453a-919d-413861904646",
+  "userName": "bjensen",
+  "name": {"givenName": "Barbara"},
+  "emails": [{"type": "work"}, {"type": "home"}]
+}`,
+		},
+		{
+			Name: `URN-qualified attributes`,
+			Attributes: []string{
+				`urn:ietf:params:scim:schemas:core:2.0:User:title`,
+				`urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber`,
+			},
+			Expected: `{
+  "schemas": [
+    "urn:ietf:params:scim:schemas:core:2.0:User",
+    "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+  ],
+  "id": "2819c223-7f76-453a-919d-413861904646",
+  "title": "Tour Guide",
+  "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {"employeeNumber": "701984"}
+}`,
+		},
+		{
+			Name:       `whole extension`,
+			Attributes: []string{`urn:ietf:params:scim:schemas:extension:enterprise:2.0:User`},
+			Expected: `{
+  "schemas": [
+    "urn:ietf:params:scim:schemas:core:2.0:User",
+    "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+  ],
+  "id": "2819c223-7f76-453a-919d-413861904646",
+  "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {
+    "employeeNumber": "701984",
+    "department": "Tour Operations"
+  }
+}`,
+		},
+		{
+			Name:       `whole extension in a different case`,
+			Attributes: []string{`URN:IETF:PARAMS:SCIM:SCHEMAS:EXTENSION:ENTERPRISE:2.0:USER`},
+			Expected: `{
+  "schemas": [
+    "urn:ietf:params:scim:schemas:core:2.0:User",
+    "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+  ],
+  "id": "2819c223-7f76-453a-919d-413861904646",
+  "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {
+    "employeeNumber": "701984",
+    "department": "Tour Operations"
+  }
+}`,
+		},
+		{
+			Name: `excluded attributes`,
+			ExcludedAttributes: []string{
+				`id`,
+				`emails`,
+				`name.middleName`,
+				`meta`,
+				`urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department`,
+			},
+			Expected: `{
+  "schemas": [
+    "urn:ietf:params:scim:schemas:core:2.0:User",
+    "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+  ],
+  "id": "2819c223-7f76-453a-919d-413861904646",
+  "userName": "bjensen",
+  "title": "Tour Guide",
+  "name": {"familyName": "Jensen", "givenName": "Barbara"},
+  "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {"employeeNumber": "701984"}
+}`,
+		},
+		{
+			Name:       `filters are not allowed`,
+			Attributes: []string{`emails[type eq "work"]`},
+			Error:      true,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			var u resource.User
+			require.NoError(t, json.Unmarshal([]byte(user), &u), `json.Unmarshal should succeed`)
+
+			projected, err := projection.Apply(&u, tc.Attributes, tc.ExcludedAttributes)
+			if tc.Error {
+				require.Error(t, err, `projection.Apply should fail`)
+				var rerr *resource.Error
+				require.ErrorAs(t, err, &rerr, `error should be a *resource.Error`)
+				require.Equal(t, resource.ErrInvalidPath, rerr.SCIMType(), `error type should match`)
+				return
+			}
+			require.NoError(t, err, `projection.Apply should succeed`)
+
+			serialized, err := json.Marshal(projected)
+			require.NoError(t, err, `json.Marshal should succeed`)
+			require.JSONEq(t, tc.Expected, string(serialized), `projected user should match`)
+		})
+	}
+}
+
+func TestApplyListResponse(t *testing.T) {
+	lr := resource.NewListResponseBuilder().
+		TotalResults(2).
+		Resources(
+			resource.NewUserBuilder().
+				ID(`2819c223`).
+				UserName(`bjensen`).
+				DisplayName(`Babs Jensen`).
+				MustBuild(),
+			resource.NewGroupBuilder().
+				ID(`e9e30dba`).
+				DisplayName(`Tour Guides`).
+				MustBuild(),
+		).
+		MustBuild()
+
+	projected, err := projection.ApplyListResponse(lr, []string{`displayName`}, nil)
+	require.NoError(t, err, `projection.ApplyListResponse should succeed`)
+
+	serialized, err := json.Marshal(projected)
+	require.NoError(t, err, `json.Marshal should succeed`)
+	require.JSONEq(t, `{
+  "schemas": ["urn:ietf:params:scim:api:messages:2.0:ListResponse"],
+  "totalResults": 2,
+  "resources": [
+    {"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "id": "2819c223", "displayName": "Babs Jensen"},
+    {"schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group"], "id": "e9e30dba", "displayName": "Tour Guides"}
+  ]
+}`, string(serialized), `projected list response should match`)
+}
@@ -0,0 +1,36 @@
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// BulkData holds the resource data of a bulk operation, or the response
+// of a bulk operation, in its serialized form
+type BulkData json.RawMessage
+
+func (v *BulkData) GetValue() interface{} {
+	var dst interface{}
+	if err := json.Unmarshal(*v, &dst); err != nil {
+		return nil
+	}
+	return dst
+}
+
+// MarshalJSON returns the value as is, as it is already serialized
+func (v BulkData) MarshalJSON() ([]byte, error) {
+	if len(v) == 0 {
+		return []byte(`null`), nil
+	}
+	return v, nil
+}
+
+func (v *BulkData) AcceptValue(in interface{}) error {
+	serialized, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf(`failed to marshal value: %w`, err)
+	}
+
+	*v = BulkData(serialized)
+	return nil
+}
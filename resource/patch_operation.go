@@ -15,6 +15,14 @@ func (v *PatchOperationValue) GetValue() interface{} {
 	return dst
 }
 
+// MarshalJSON returns the value as is, as it is already serialized
+func (v PatchOperationValue) MarshalJSON() ([]byte, error) {
+	if len(v) == 0 {
+		return []byte(`null`), nil
+	}
+	return v, nil
+}
+
 func (v *PatchOperationValue) AcceptValue(in interface{}) error {
 	serialized, err := json.Marshal(in)
 	if err != nil {
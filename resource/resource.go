@@ -120,6 +120,7 @@ const (
 	DateTime        DataType = "dateTime"
 	Reference       DataType = "reference"
 	Complex         DataType = "complex"
+	Binary          DataType = "binary"
 )
 
 type Mutability string
@@ -164,6 +165,14 @@ const (
 	ErrSensitive     ErrorType = `sensitive`
 )
 
+// Sort orders accepted in the `sortOrder` parameter of search requests
+// (RFC7644 Section 3.4.2.3). The parameter is case-insensitive, so it
+// should be lower-cased before it is compared against these values.
+const (
+	SortAscending  = `ascending`
+	SortDescending = `descending`
+)
+
 type PatchOperationType string
 
 const (
@@ -6,6 +6,8 @@
 package schema
 
 import (
+	"strings"
+
 	"github.com/cybozu-go/scim/resource"
 )
 
@@ -40,3 +42,52 @@ func All() []*resource.Schema {
 	}
 	return list
 }
+
+// ForResource returns the schema that describes the given resource.
+// v can be a resource object such as *resource.User, or a
+// map[string]interface{} that lists the schema URIs in its `schemas`
+// attribute. If more than one schema URI is listed, the first one that
+// is registered is used.
+func ForResource(v interface{}) (*resource.Schema, bool) {
+	var uris []string
+	switch v := v.(type) {
+	case *resource.User:
+		uris = []string{resource.UserSchemaURI}
+	case *resource.Group:
+		uris = []string{resource.GroupSchemaURI}
+	case *resource.EnterpriseUser:
+		uris = []string{resource.EnterpriseUserSchemaURI}
+	case interface{ Schemas() []string }:
+		uris = v.Schemas()
+	case map[string]interface{}:
+		switch list := v[`schemas`].(type) {
+		case []string:
+			uris = list
+		case []interface{}:
+			for _, uri := range list {
+				if s, ok := uri.(string); ok {
+					uris = append(uris, s)
+				}
+			}
+		}
+	}
+
+	for _, uri := range uris {
+		if s, ok := Get(uri); ok {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// FindAttribute looks for the attribute specified by name from the list
+// of attributes, such as the attributes of a schema or the sub-attributes
+// of a complex attribute. Attribute names are case insensitive.
+func FindAttribute(attrs []*resource.SchemaAttribute, name string) *resource.SchemaAttribute {
+	for _, attr := range attrs {
+		if strings.EqualFold(attr.Name(), name) {
+			return attr
+		}
+	}
+	return nil
+}
@@ -440,7 +440,7 @@ func init() {
 							MustBuild(),
 						resource.NewSchemaAttributeBuilder().
 							Name("created").
-							Type("string").
+							Type("dateTime").
 							MultiValued(false).
 							Description("The \"DateTime\" that the resource was added to the service provider").
 							Required(false).
@@ -450,6 +450,42 @@ func init() {
 							Uniqueness(resource.UniqNone).
 							GoAccessorName("Created").
 							MustBuild(),
+						resource.NewSchemaAttributeBuilder().
+							Name("lastModified").
+							Type("dateTime").
+							MultiValued(false).
+							Description("The most recent DateTime that the details of this resource were updated at the service provider").
+							Required(false).
+							CaseExact(false).
+							Mutability(resource.MutReadOnly).
+							Returned(resource.ReturnedDefault).
+							Uniqueness(resource.UniqNone).
+							GoAccessorName("LastModified").
+							MustBuild(),
+						resource.NewSchemaAttributeBuilder().
+							Name("location").
+							Type("reference").
+							MultiValued(false).
+							Description("The URI of the resource being returned").
+							Required(false).
+							CaseExact(true).
+							Mutability(resource.MutReadOnly).
+							Returned(resource.ReturnedDefault).
+							Uniqueness(resource.UniqNone).
+							GoAccessorName("Location").
+							MustBuild(),
+						resource.NewSchemaAttributeBuilder().
+							Name("version").
+							Type("string").
+							MultiValued(false).
+							Description("The version of the resource being returned").
+							Required(false).
+							CaseExact(true).
+							Mutability(resource.MutReadOnly).
+							Returned(resource.ReturnedDefault).
+							Uniqueness(resource.UniqNone).
+							GoAccessorName("Version").
+							MustBuild(),
 					).
 					GoAccessorName("Meta").
 					MustBuild(),
@@ -834,11 +870,11 @@ func init() {
 					Attributes(
 						resource.NewSchemaAttributeBuilder().
 							Name("value").
-							Type("string").
+							Type("binary").
 							MultiValued(false).
 							Description("The value of an X.509 certificate.").
 							Required(false).
-							CaseExact(false).
+							CaseExact(true).
 							Mutability(resource.MutReadWrite).
 							Returned(resource.ReturnedDefault).
 							Uniqueness(resource.UniqNone).
@@ -1005,7 +1041,7 @@ func init() {
 							MustBuild(),
 						resource.NewSchemaAttributeBuilder().
 							Name("created").
-							Type("string").
+							Type("dateTime").
 							MultiValued(false).
 							Description("The \"DateTime\" that the resource was added to the service provider").
 							Required(false).
@@ -1015,6 +1051,42 @@ func init() {
 							Uniqueness(resource.UniqNone).
 							GoAccessorName("Created").
 							MustBuild(),
+						resource.NewSchemaAttributeBuilder().
+							Name("lastModified").
+							Type("dateTime").
+							MultiValued(false).
+							Description("The most recent DateTime that the details of this resource were updated at the service provider").
+							Required(false).
+							CaseExact(false).
+							Mutability(resource.MutReadOnly).
+							Returned(resource.ReturnedDefault).
+							Uniqueness(resource.UniqNone).
+							GoAccessorName("LastModified").
+							MustBuild(),
+						resource.NewSchemaAttributeBuilder().
+							Name("location").
+							Type("reference").
+							MultiValued(false).
+							Description("The URI of the resource being returned").
+							Required(false).
+							CaseExact(true).
+							Mutability(resource.MutReadOnly).
+							Returned(resource.ReturnedDefault).
+							Uniqueness(resource.UniqNone).
+							GoAccessorName("Location").
+							MustBuild(),
+						resource.NewSchemaAttributeBuilder().
+							Name("version").
+							Type("string").
+							MultiValued(false).
+							Description("The version of the resource being returned").
+							Required(false).
+							CaseExact(true).
+							Mutability(resource.MutReadOnly).
+							Returned(resource.ReturnedDefault).
+							Uniqueness(resource.UniqNone).
+							GoAccessorName("Version").
+							MustBuild(),
 					).
 					GoAccessorName("Meta").
 					MustBuild(),
@@ -1176,7 +1248,7 @@ func init() {
 							MustBuild(),
 						resource.NewSchemaAttributeBuilder().
 							Name("created").
-							Type("string").
+							Type("dateTime").
 							MultiValued(false).
 							Description("The \"DateTime\" that the resource was added to the service provider").
 							Required(false).
@@ -1186,6 +1258,42 @@ func init() {
 							Uniqueness(resource.UniqNone).
 							GoAccessorName("Created").
 							MustBuild(),
+						resource.NewSchemaAttributeBuilder().
+							Name("lastModified").
+							Type("dateTime").
+							MultiValued(false).
+							Description("The most recent DateTime that the details of this resource were updated at the service provider").
+							Required(false).
+							CaseExact(false).
+							Mutability(resource.MutReadOnly).
+							Returned(resource.ReturnedDefault).
+							Uniqueness(resource.UniqNone).
+							GoAccessorName("LastModified").
+							MustBuild(),
+						resource.NewSchemaAttributeBuilder().
+							Name("location").
+							Type("reference").
+							MultiValued(false).
+							Description("The URI of the resource being returned").
+							Required(false).
+							CaseExact(true).
+							Mutability(resource.MutReadOnly).
+							Returned(resource.ReturnedDefault).
+							Uniqueness(resource.UniqNone).
+							GoAccessorName("Location").
+							MustBuild(),
+						resource.NewSchemaAttributeBuilder().
+							Name("version").
+							Type("string").
+							MultiValued(false).
+							Description("The version of the resource being returned").
+							Required(false).
+							CaseExact(true).
+							Mutability(resource.MutReadOnly).
+							Returned(resource.ReturnedDefault).
+							Uniqueness(resource.UniqNone).
+							GoAccessorName("Version").
+							MustBuild(),
 					).
 					GoAccessorName("Meta").
 					MustBuild(),
@@ -3,6 +3,7 @@ package schema_test
 import (
 	"testing"
 
+	"github.com/cybozu-go/scim/resource"
 	"github.com/cybozu-go/scim/schema"
 	"github.com/stretchr/testify/require"
 )
@@ -21,4 +22,43 @@ func TestSchema(t *testing.T) {
 			t.Logf("schema name %q", n)
 		}
 	})
+	t.Run(`ForResource`, func(t *testing.T) {
+		testcases := []struct {
+			Name     string
+			Resource interface{}
+			Expected string
+		}{
+			{Name: `user`, Resource: resource.NewUserBuilder().UserName(`bjensen`).MustBuild(), Expected: resource.UserSchemaURI},
+			{Name: `group`, Resource: resource.NewGroupBuilder().DisplayName(`Tour Guides`).MustBuild(), Expected: resource.GroupSchemaURI},
+			{Name: `map`, Resource: map[string]interface{}{`schemas`: []interface{}{`urn:example:unknown`, resource.GroupSchemaURI}}, Expected: resource.GroupSchemaURI},
+			{Name: `map without schemas`, Resource: map[string]interface{}{`userName`: `bjensen`}},
+		}
+
+		for _, tc := range testcases {
+			tc := tc
+			t.Run(tc.Name, func(t *testing.T) {
+				s, ok := schema.ForResource(tc.Resource)
+				if tc.Expected == "" {
+					require.False(t, ok, `schema.ForResource should fail`)
+					return
+				}
+				require.True(t, ok, `schema.ForResource should succeed`)
+				require.Equal(t, tc.Expected, s.ID(), `schema should match`)
+			})
+		}
+	})
+	t.Run(`FindAttribute`, func(t *testing.T) {
+		s, ok := schema.Get(resource.UserSchemaURI)
+		require.True(t, ok, `schema.Get should succeed`)
+
+		attr := schema.FindAttribute(s.Attributes(), `NAME`)
+		require.NotNil(t, attr, `schema.FindAttribute should find name`)
+		require.Equal(t, `name`, attr.Name(), `attribute name should match`)
+
+		sub := schema.FindAttribute(attr.SubAttributes(), `familyname`)
+		require.NotNil(t, sub, `schema.FindAttribute should find name.familyName`)
+		require.Equal(t, `familyName`, sub.Name(), `attribute name should match`)
+
+		require.Nil(t, schema.FindAttribute(s.Attributes(), `unknown`), `schema.FindAttribute should not find unknown attributes`)
+	})
 }
@@ -5,11 +5,16 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"strings"
 
+	"github.com/cybozu-go/scim/filter"
+	"github.com/cybozu-go/scim/patch"
+	"github.com/cybozu-go/scim/projection"
 	"github.com/cybozu-go/scim/resource"
+	"github.com/cybozu-go/scim/schema"
 	"github.com/lestrrat-go/mux"
 )
 
@@ -99,21 +104,63 @@ func WriteSCIMError(w http.ResponseWriter, st int, msg string) {
 	_ = json.NewEncoder(w).Encode(serr)
 }
 
+// WriteError writes the error to the response writer. If the error
+// is a *resource.Error, it is written as is. Filter parse errors are
+// reported as `invalidFilter` errors with a 400 status, except for
+// filters with too many expressions, which are reported as `tooMany`.
+// Otherwise, the error is reported as an internal server error.
 func WriteError(w http.ResponseWriter, err error) {
+	serr := scimError(err)
+	w.WriteHeader(serr.Status())
+	// Look, I've explicitly stated to ignore errors, you linters
+	// should just let me be, OK?
+	//nolint:errchkjson
+	_ = json.NewEncoder(w).Encode(serr)
+}
+
+// scimError converts the error into the *resource.Error that is
+// reported to the client, as described in WriteError
+func scimError(err error) *resource.Error {
+	var perr *filter.ParseError
+	if errors.As(err, &perr) {
+		scimType := resource.ErrInvalidFilter
+		var lerr *filter.LimitError
+		if errors.As(perr, &lerr) && lerr.Limit == filter.LimitNodes {
+			scimType = resource.ErrTooMany
+		}
+		return resource.NewErrorBuilder().
+			Status(http.StatusBadRequest).
+			Detail(perr.Error()).
+			SCIMType(scimType).
+			MustBuild()
+	}
+
 	var serr *resource.Error
 	if errors.As(err, &serr) {
-		w.WriteHeader(serr.Status())
-		// Look, I've explicitly stated to ignore errors, you linters
-		// should just let me be, OK?
-		//nolint:errchkjson
-		_ = json.NewEncoder(w).Encode(serr)
-		return
+		return serr
 	}
 
-	WriteSCIMError(w, http.StatusInternalServerError, err.Error())
+	return resource.NewErrorBuilder().
+		Status(http.StatusInternalServerError).
+		Detail(err.Error()).
+		SCIMType(resource.ErrUnknown).
+		MustBuild()
+}
+
+// scimErrorf creates a *resource.Error with the given status. The
+// SCIM error type is omitted if typ is empty
+func scimErrorf(status int, typ resource.ErrorType, f string, args ...interface{}) *resource.Error {
+	b := resource.NewErrorBuilder().
+		Status(status).
+		Detail(fmt.Sprintf(f, args...))
+	if typ != `` {
+		b.SCIMType(typ)
+	}
+	return b.MustBuild()
 }
 
 func DeleteGroupEndpoint(b DeleteGroupBackend) http.Handler {
+	version := groupVersion(b)
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
 		id := vars.Get(`id`)
@@ -122,6 +169,12 @@ func DeleteGroupEndpoint(b DeleteGroupBackend) http.Handler {
 			return
 		}
 
+		r, p := withPreconditions(r)
+		if err := checkIfMatch(r.Context(), p, version, id); err != nil {
+			WriteError(w, err)
+			return
+		}
+
 		if err := b.DeleteGroup(r.Context(), id); err != nil {
 			WriteError(w, err)
 			return
@@ -131,6 +184,7 @@ func DeleteGroupEndpoint(b DeleteGroupBackend) http.Handler {
 }
 
 func ReplaceGroupEndpoint(b ReplaceGroupBackend) http.Handler {
+	version := groupVersion(b)
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
 		id := vars.Get(`id`)
@@ -139,6 +193,12 @@ func ReplaceGroupEndpoint(b ReplaceGroupBackend) http.Handler {
 			return
 		}
 
+		r, p := withPreconditions(r)
+		if err := checkIfMatch(r.Context(), p, version, id); err != nil {
+			WriteError(w, err)
+			return
+		}
+
 		var group resource.Group
 		if err := json.NewDecoder(r.Body).Decode(&group); err != nil {
 			WriteSCIMError(w, http.StatusBadRequest, `failed to parse payload`)
@@ -150,8 +210,21 @@ func ReplaceGroupEndpoint(b ReplaceGroupBackend) http.Handler {
 			WriteError(w, err)
 			return
 		}
+
+		projected, err := projection.Apply(replaced, nil, nil)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+
+		if meta := replaced.Meta(); meta != nil {
+			if v := meta.Version(); v != "" {
+				w.Header().Set(`ETag`, v)
+			}
+		}
+
 		w.WriteHeader(http.StatusOK)
-		_ = json.NewEncoder(w).Encode(replaced)
+		_ = json.NewEncoder(w).Encode(projected)
 	})
 }
 
@@ -164,6 +237,8 @@ func RetrieveGroupEndpoint(b RetrieveGroupBackend) http.Handler {
 			return
 		}
 
+		r, p := withPreconditions(r)
+
 		var attrs []string
 		if v := r.URL.Query().Get(`attributes`); v != "" {
 			attrs = strings.Split(v, ",")
@@ -179,14 +254,26 @@ func RetrieveGroupEndpoint(b RetrieveGroupBackend) http.Handler {
 			return
 		}
 
+		projected, err := projection.Apply(group, attrs, excluded)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+
+		var version string
 		if meta := group.Meta(); meta != nil {
-			if v := meta.Version(); v != "" {
-				w.Header().Set(`ETag`, v)
-			}
+			version = meta.Version()
+		}
+		if version != "" {
+			w.Header().Set(`ETag`, version)
+		}
+		if !p.NoneMatch(version) {
+			w.WriteHeader(http.StatusNotModified)
+			return
 		}
 
 		w.WriteHeader(http.StatusOK)
-		_ = json.NewEncoder(w).Encode(group)
+		_ = json.NewEncoder(w).Encode(projected)
 	})
 }
 
@@ -204,6 +291,12 @@ func CreateGroupEndpoint(b CreateGroupBackend) http.Handler {
 			return
 		}
 
+		projected, err := projection.Apply(created, nil, nil)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+
 		if meta := created.Meta(); meta != nil {
 			if v := meta.Version(); v != "" {
 				w.Header().Set(`ETag`, v)
@@ -212,16 +305,26 @@ func CreateGroupEndpoint(b CreateGroupBackend) http.Handler {
 
 		w.Header().Set(ctKey, mimeSCIM)
 		w.WriteHeader(http.StatusCreated)
-		_ = json.NewEncoder(w).Encode(created)
+		_ = json.NewEncoder(w).Encode(projected)
 	})
 }
 
 func DeleteUserEndpoint(b DeleteUserBackend) http.Handler {
+	return deleteUserEndpoint(b, pathUserID)
+}
+
+func deleteUserEndpoint(b DeleteUserBackend, userID userIDFunc) http.Handler {
+	version := userVersion(b)
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		vars := mux.Vars(r)
-		id := vars.Get(`id`)
-		if id == "" {
-			WriteSCIMError(w, http.StatusBadRequest, `missing ID`)
+		id, err := userID(r)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+
+		r, p := withPreconditions(r)
+		if err := checkIfMatch(r.Context(), p, version, id); err != nil {
+			WriteError(w, err)
 			return
 		}
 
@@ -234,11 +337,21 @@ func DeleteUserEndpoint(b DeleteUserBackend) http.Handler {
 }
 
 func ReplaceUserEndpoint(b ReplaceUserBackend) http.Handler {
+	return replaceUserEndpoint(b, pathUserID, false)
+}
+
+func replaceUserEndpoint(b ReplaceUserBackend, userID userIDFunc, me bool) http.Handler {
+	version := userVersion(b)
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		vars := mux.Vars(r)
-		id := vars.Get(`id`)
-		if id == "" {
-			WriteSCIMError(w, http.StatusBadRequest, `missing ID`)
+		id, err := userID(r)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+
+		r, p := withPreconditions(r)
+		if err := checkIfMatch(r.Context(), p, version, id); err != nil {
+			WriteError(w, err)
 			return
 		}
 
@@ -253,20 +366,41 @@ func ReplaceUserEndpoint(b ReplaceUserBackend) http.Handler {
 			WriteError(w, err)
 			return
 		}
+
+		projected, err := projection.Apply(newUser, nil, nil)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+
+		if meta := newUser.Meta(); meta != nil {
+			if v := meta.Version(); v != "" {
+				w.Header().Set(`ETag`, v)
+			}
+		}
+		if me {
+			w.Header().Set(`Content-Location`, userLocation(id, newUser))
+		}
+
 		w.WriteHeader(http.StatusOK)
-		_ = json.NewEncoder(w).Encode(newUser)
+		_ = json.NewEncoder(w).Encode(projected)
 	})
 }
 
 func RetrieveUserEndpoint(b RetrieveUserBackend) http.Handler {
+	return retrieveUserEndpoint(b, pathUserID, false)
+}
+
+func retrieveUserEndpoint(b RetrieveUserBackend, userID userIDFunc, me bool) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		vars := mux.Vars(r)
-		id := vars.Get(`id`)
-		if id == "" {
-			WriteSCIMError(w, http.StatusBadRequest, `missing ID`)
+		id, err := userID(r)
+		if err != nil {
+			WriteError(w, err)
 			return
 		}
 
+		r, p := withPreconditions(r)
+
 		var attrs []string
 		if v := r.URL.Query().Get(`attributes`); v != "" {
 			attrs = strings.Split(v, ",")
@@ -282,23 +416,73 @@ func RetrieveUserEndpoint(b RetrieveUserBackend) http.Handler {
 			return
 		}
 
+		projected, err := projection.Apply(user, attrs, excluded)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+
+		var version string
 		if meta := user.Meta(); meta != nil {
-			if v := meta.Version(); v != "" {
-				w.Header().Set(`ETag`, v)
-			}
+			version = meta.Version()
+		}
+		if version != "" {
+			w.Header().Set(`ETag`, version)
+		}
+		if me {
+			w.Header().Set(`Content-Location`, userLocation(id, user))
+		}
+		if !p.NoneMatch(version) {
+			w.WriteHeader(http.StatusNotModified)
+			return
 		}
 
 		w.WriteHeader(http.StatusOK)
-		_ = json.NewEncoder(w).Encode(user)
+		_ = json.NewEncoder(w).Encode(projected)
 	})
 }
 
-func PatchUserEndpoint(b PatchUserBackend) http.Handler {
+// patchNormalizer returns the function that rewrites the PATCH requests
+// according to the quirks specified in the options
+func patchNormalizer(uri string, options []PatchEndpointOption) func(*resource.PatchRequest) (*resource.PatchRequest, error) {
+	quirks := patch.QuirksNone
+	//nolint:forcetypeassert
+	for _, option := range options {
+		switch option.Ident() {
+		case identPatchQuirks{}:
+			quirks = option.Value().(patch.Quirks)
+		}
+	}
+
+	if quirks == patch.QuirksNone {
+		return func(req *resource.PatchRequest) (*resource.PatchRequest, error) {
+			return req, nil
+		}
+	}
+
+	s, _ := schema.Get(uri)
+	return func(req *resource.PatchRequest) (*resource.PatchRequest, error) {
+		return patch.Normalize(req, s, quirks)
+	}
+}
+
+func PatchUserEndpoint(b PatchUserBackend, options ...PatchEndpointOption) http.Handler {
+	return patchUserEndpoint(b, pathUserID, false, options)
+}
+
+func patchUserEndpoint(b PatchUserBackend, userID userIDFunc, me bool, options []PatchEndpointOption) http.Handler {
+	normalize := patchNormalizer(resource.UserSchemaURI, options)
+	version := userVersion(b)
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		vars := mux.Vars(r)
-		id := vars.Get(`id`)
-		if id == "" {
-			WriteSCIMError(w, http.StatusBadRequest, `missing ID`)
+		id, err := userID(r)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+
+		r, p := withPreconditions(r)
+		if err := checkIfMatch(r.Context(), p, version, id); err != nil {
+			WriteError(w, err)
 			return
 		}
 
@@ -309,17 +493,33 @@ func PatchUserEndpoint(b PatchUserBackend) http.Handler {
 			return
 		}
 
-		user, err := b.PatchUser(r.Context(), id, &preq)
+		normalized, err := normalize(&preq)
+		if err != nil {
+			WriteSCIMError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		user, err := b.PatchUser(r.Context(), id, normalized)
 		if err != nil {
 			WriteError(w, err)
 			return
 		}
 
+		if me {
+			w.Header().Set(`Content-Location`, userLocation(id, user))
+		}
+
 		if user == nil {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
 
+		projected, err := projection.Apply(user, nil, nil)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+
 		if meta := user.Meta(); meta != nil {
 			if v := meta.Version(); v != "" {
 				w.Header().Set(`ETag`, v)
@@ -327,11 +527,13 @@ func PatchUserEndpoint(b PatchUserBackend) http.Handler {
 		}
 
 		w.WriteHeader(http.StatusOK)
-		_ = json.NewEncoder(w).Encode(user)
+		_ = json.NewEncoder(w).Encode(projected)
 	})
 }
 
-func PatchGroupEndpoint(b PatchGroupBackend) http.Handler {
+func PatchGroupEndpoint(b PatchGroupBackend, options ...PatchEndpointOption) http.Handler {
+	normalize := patchNormalizer(resource.GroupSchemaURI, options)
+	version := groupVersion(b)
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
 		id := vars.Get(`id`)
@@ -340,6 +542,12 @@ func PatchGroupEndpoint(b PatchGroupBackend) http.Handler {
 			return
 		}
 
+		r, p := withPreconditions(r)
+		if err := checkIfMatch(r.Context(), p, version, id); err != nil {
+			WriteError(w, err)
+			return
+		}
+
 		defer r.Body.Close()
 		var preq resource.PatchRequest
 		if err := json.NewDecoder(r.Body).Decode(&preq); err != nil {
@@ -347,7 +555,13 @@ func PatchGroupEndpoint(b PatchGroupBackend) http.Handler {
 			return
 		}
 
-		group, err := b.PatchGroup(r.Context(), id, &preq)
+		normalized, err := normalize(&preq)
+		if err != nil {
+			WriteSCIMError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		group, err := b.PatchGroup(r.Context(), id, normalized)
 		if err != nil {
 			WriteError(w, err)
 			return
@@ -358,6 +572,12 @@ func PatchGroupEndpoint(b PatchGroupBackend) http.Handler {
 			return
 		}
 
+		projected, err := projection.Apply(group, nil, nil)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+
 		if meta := group.Meta(); meta != nil {
 			if v := meta.Version(); v != "" {
 				w.Header().Set(`ETag`, v)
@@ -365,7 +585,7 @@ func PatchGroupEndpoint(b PatchGroupBackend) http.Handler {
 		}
 
 		w.WriteHeader(http.StatusOK)
-		_ = json.NewEncoder(w).Encode(group)
+		_ = json.NewEncoder(w).Encode(projected)
 	})
 }
 
@@ -383,69 +603,40 @@ func CreateUserEndpoint(b CreateUserBackend) http.Handler {
 			return
 		}
 
-		w.Header().Set(ctKey, mimeSCIM)
-		w.WriteHeader(http.StatusCreated)
-		_ = json.NewEncoder(w).Encode(created)
-	})
-}
-
-// Creates an instance of reference implementation http.Handler that
-// uses the specified Backend
-func SearchEndpoint(b SearchBackend) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		var q resource.SearchRequest
-		if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
-			WriteSCIMError(w, http.StatusBadRequest, `failed to parse payload`)
-			return
-		}
-
-		lr, err := b.Search(r.Context(), &q)
+		projected, err := projection.Apply(created, nil, nil)
 		if err != nil {
 			WriteError(w, err)
 			return
 		}
 
-		var buf bytes.Buffer
-		if err := json.NewEncoder(&buf).Encode(lr); err != nil {
-			WriteSCIMError(w, http.StatusBadRequest, `failed to encode response`)
-			return
+		if meta := created.Meta(); meta != nil {
+			if v := meta.Version(); v != "" {
+				w.Header().Set(`ETag`, v)
+			}
 		}
 
-		hdr := w.Header()
-		hdr.Set(ctKey, mimeSCIM)
-		w.WriteHeader(http.StatusOK)
-		_, _ = io.Copy(w, &buf) // not much you can do by this point
+		w.Header().Set(ctKey, mimeSCIM)
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(projected)
 	})
 }
 
-func SearchUserEndpoint(b SearchUserBackend) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		var q resource.SearchRequest
-		if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
-			WriteSCIMError(w, http.StatusBadRequest, `failed to parse payload`)
-			return
-		}
-
-		lr, err := b.SearchUser(r.Context(), &q)
-		if err != nil {
-			WriteError(w, err)
-			return
-		}
+// Creates an instance of reference implementation http.Handler that
+// uses the specified Backend
+func SearchEndpoint(b SearchBackend, options ...SearchEndpointOption) http.Handler {
+	return searchEndpoint(b.Search, options)
+}
 
-		var buf bytes.Buffer
-		if err := json.NewEncoder(&buf).Encode(lr); err != nil {
-			WriteSCIMError(w, http.StatusBadRequest, `failed to encode response`)
-			return
-		}
+func SearchUserEndpoint(b SearchUserBackend, options ...SearchEndpointOption) http.Handler {
+	return searchEndpoint(b.SearchUser, options)
+}
 
-		hdr := w.Header()
-		hdr.Set(ctKey, mimeSCIM)
-		w.WriteHeader(http.StatusOK)
-		_, _ = io.Copy(w, &buf) // not much you can do by this point
-	})
+func SearchGroupEndpoint(b SearchGroupBackend, options ...SearchEndpointOption) http.Handler {
+	return searchEndpoint(b.SearchGroup, options)
 }
 
-func SearchGroupEndpoint(b SearchGroupBackend) http.Handler {
+func searchEndpoint(search func(context.Context, *resource.SearchRequest) (*resource.ListResponse, error), options []SearchEndpointOption) http.Handler {
+	parseOptions := filterParseOptions(options)
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var q resource.SearchRequest
 		if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
@@ -453,22 +644,15 @@ func SearchGroupEndpoint(b SearchGroupBackend) http.Handler {
 			return
 		}
 
-		lr, err := b.SearchGroup(r.Context(), &q)
-		if err != nil {
-			WriteError(w, err)
-			return
-		}
-
-		var buf bytes.Buffer
-		if err := json.NewEncoder(&buf).Encode(lr); err != nil {
-			WriteSCIMError(w, http.StatusBadRequest, `failed to encode response`)
-			return
+		// filters are checked in the same way as those in list requests
+		if v := q.Filter(); v != "" {
+			if _, err := filter.Parse(v, parseOptions...); err != nil {
+				WriteError(w, err)
+				return
+			}
 		}
 
-		hdr := w.Header()
-		hdr.Set(ctKey, mimeSCIM)
-		w.WriteHeader(http.StatusOK)
-		_, _ = io.Copy(w, &buf) // not much you can do by this point
+		writeSearchResponse(w, r, search, &q)
 	})
 }
 
@@ -0,0 +1,597 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cybozu-go/scim/resource"
+)
+
+// BulkBackend describes a backend that processes bulk requests by itself.
+// Backends that do not implement this interface can still process bulk
+// requests using `server.NewBulkDispatcher()`.
+type BulkBackend interface {
+	Bulk(context.Context, *resource.BulkRequest) (*resource.BulkResponse, error)
+}
+
+const bulkIDPrefix = `bulkId:`
+
+// Limits enforced on bulk requests when the configuration does not
+// specify them (i.e. they are 0)
+const (
+	defaultBulkMaxOperations  = 1000
+	defaultBulkMaxPayloadSize = 1048576
+)
+
+// BulkEndpoint creates an http.Handler that processes bulk requests
+// using the specified backend.
+//
+// The `maxOperations` and `maxPayloadSize` limits are enforced before
+// the request is passed to the backend, and requests that exceed them are
+// rejected with a 413 status. The limits are taken from the option
+// `server.WithBulkSupport()`, or from the service provider configuration
+// if the backend implements RetrieveServiceProviderConfigBackend. Limits
+// that are not configured default to 1000 operations and 1MiB. If the
+// configuration states that bulk operations are not supported, all
+// requests are rejected with a 501 status.
+func BulkEndpoint(b BulkBackend, options ...BulkEndpointOption) http.Handler {
+	var support *resource.BulkSupport
+	//nolint:forcetypeassert
+	for _, option := range options {
+		switch option.Ident() {
+		case identBulkSupport{}:
+			support = option.Value().(*resource.BulkSupport)
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bulk := support
+		if bulk == nil {
+			retrieved, err := retrieveBulkSupport(r.Context(), b)
+			if err != nil {
+				WriteError(w, err)
+				return
+			}
+			bulk = retrieved
+		}
+
+		if d, ok := b.(*bulkDispatcher); ok && d.requireSupport && (bulk == nil || !bulk.Supported()) {
+			WriteSCIMError(w, http.StatusNotImplemented, `bulk operations are not supported`)
+			return
+		}
+
+		maxOperations, maxPayloadSize := defaultBulkMaxOperations, defaultBulkMaxPayloadSize
+		if bulk != nil {
+			if bulk.HasSupported() && !bulk.Supported() {
+				WriteSCIMError(w, http.StatusNotImplemented, `bulk operations are not supported`)
+				return
+			}
+			if v := bulk.MaxOperations(); v > 0 {
+				maxOperations = v
+			}
+			if v := bulk.MaxPayloadSize(); v > 0 {
+				maxPayloadSize = v
+			}
+		}
+
+		defer r.Body.Close()
+		payload, err := io.ReadAll(io.LimitReader(r.Body, int64(maxPayloadSize)+1))
+		if err != nil {
+			WriteSCIMError(w, http.StatusBadRequest, `failed to read payload`)
+			return
+		}
+		if len(payload) > maxPayloadSize {
+			WriteError(w, scimErrorf(http.StatusRequestEntityTooLarge, ``, `the size of the bulk operation exceeds the maxPayloadSize (%d)`, maxPayloadSize))
+			return
+		}
+
+		var req resource.BulkRequest
+		if err := json.Unmarshal(payload, &req); err != nil {
+			WriteSCIMError(w, http.StatusBadRequest, `failed to parse payload`)
+			return
+		}
+		if len(req.Operations()) > maxOperations {
+			WriteError(w, scimErrorf(http.StatusRequestEntityTooLarge, resource.ErrTooMany, `the number of operations exceeds the maxOperations (%d)`, maxOperations))
+			return
+		}
+
+		res, err := b.Bulk(r.Context(), &req)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+
+		w.Header().Set(ctKey, mimeSCIM)
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(res)
+	})
+}
+
+// retrieveBulkSupport fetches the bulk configuration from the backend.
+// If the backend does not provide one, nil is returned
+func retrieveBulkSupport(ctx context.Context, b BulkBackend) (*resource.BulkSupport, error) {
+	var backend interface{} = b
+	if d, ok := b.(*bulkDispatcher); ok {
+		backend = d.backend
+	}
+
+	cb, ok := backend.(RetrieveServiceProviderConfigBackend)
+	if !ok {
+		return nil, nil
+	}
+	config, err := cb.RetrieveServiceProviderConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return config.Bulk(), nil
+}
+
+type bulkDispatcher struct {
+	backend interface{}
+	// requireSupport is set when the dispatcher is created by
+	// server.NewServer(), in which case bulk requests are only accepted
+	// when the configuration states that bulk operations are supported
+	requireSupport bool
+}
+
+// NewBulkDispatcher creates a BulkBackend that processes each operation
+// in a bulk request using the Create, Replace, Patch and Delete methods
+// of the backend, such as CreateUser() and PatchGroup(). Operations
+// for which the backend does not have a method fail with a 501 status.
+//
+// References to resources created in the same request
+// (e.g. `"value": "bulkId:qwerty"`) are replaced with the ID of the
+// resource. Operations are processed in the order they appear, except
+// that operations referring to resources that have not been created yet
+// are processed after the operations creating them. References that
+// cannot be resolved, including circular references, fail with a 409
+// status.
+//
+// The `version` of an operation is handled in the same way as the
+// `If-Match` header of the corresponding request (see server.Preconditions).
+//
+// Once the number of failed operations reaches `failOnErrors`, the
+// remaining operations are not processed, and are not included in
+// the response.
+func NewBulkDispatcher(backend interface{}) BulkBackend {
+	return &bulkDispatcher{backend: backend}
+}
+
+// isBulkDispatchable reports whether the backend implements any of the
+// interfaces used by the bulk dispatcher
+func isBulkDispatchable(backend interface{}) bool {
+	switch backend.(type) {
+	case CreateUserBackend, ReplaceUserBackend, PatchUserBackend, DeleteUserBackend,
+		CreateGroupBackend, ReplaceGroupBackend, PatchGroupBackend, DeleteGroupBackend:
+		return true
+	default:
+		return false
+	}
+}
+
+func (d *bulkDispatcher) Bulk(ctx context.Context, req *resource.BulkRequest) (*resource.BulkResponse, error) {
+	operations := req.Operations()
+	failOnErrors := req.FailOnErrors()
+
+	// bulkIds of the resources that are going to be created
+	declared := make(map[string]struct{})
+	for _, op := range operations {
+		if strings.EqualFold(op.Method(), http.MethodPost) && op.BulkID() != "" {
+			declared[op.BulkID()] = struct{}{}
+		}
+	}
+
+	results := make([]*resource.BulkOperationResponse, len(operations))
+	ids := make(map[string]string)
+	failed := make(map[string]struct{})
+	var failures int
+
+	pending := make([]int, len(operations))
+	for i := range operations {
+		pending[i] = i
+	}
+
+LOOP:
+	for len(pending) > 0 {
+		var deferred []int
+		for _, i := range pending {
+			op := operations[i]
+			if d.waiting(op, declared, ids, failed) {
+				deferred = append(deferred, i)
+				continue
+			}
+
+			res, id, err := d.process(ctx, op, ids)
+			if err != nil {
+				serr := scimError(err)
+				res, err = d.failure(op, serr)
+				if err != nil {
+					return nil, err
+				}
+				if op.BulkID() != "" {
+					failed[op.BulkID()] = struct{}{}
+				}
+				failures++
+			} else if op.BulkID() != "" && id != "" {
+				ids[op.BulkID()] = id
+			}
+			results[i] = res
+
+			if failOnErrors > 0 && failures >= failOnErrors {
+				break LOOP
+			}
+		}
+
+		if len(deferred) == len(pending) {
+			// none of the operations could be processed, which means
+			// that they refer to each other
+			for _, i := range deferred {
+				res, err := d.failure(operations[i], scimErrorf(http.StatusConflict, resource.ErrInvalidValue, `circular bulkId references`))
+				if err != nil {
+					return nil, err
+				}
+				results[i] = res
+				failures++
+				if failOnErrors > 0 && failures >= failOnErrors {
+					break
+				}
+			}
+			break
+		}
+		pending = deferred
+	}
+
+	var responses []*resource.BulkOperationResponse
+	for _, res := range results {
+		if res != nil {
+			responses = append(responses, res)
+		}
+	}
+
+	return resource.NewBulkResponseBuilder().
+		Operations(responses...).
+		Build()
+}
+
+// waiting reports whether the operation refers to a resource that
+// is going to be created by another operation in the same request
+func (d *bulkDispatcher) waiting(op *resource.BulkOperation, declared map[string]struct{}, ids map[string]string, failed map[string]struct{}) bool {
+	var refs []string
+	collectBulkIDs(op.Path(), &refs)
+	collectBulkIDs(op.Data(), &refs)
+	for _, ref := range refs {
+		if _, ok := declared[ref]; !ok {
+			continue
+		}
+		if _, ok := ids[ref]; ok {
+			continue
+		}
+		if _, ok := failed[ref]; ok {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func (d *bulkDispatcher) failure(op *resource.BulkOperation, serr *resource.Error) (*resource.BulkOperationResponse, error) {
+	b := resource.NewBulkOperationResponseBuilder().
+		Method(op.Method()).
+		Status(strconv.Itoa(serr.Status())).
+		Response(serr)
+	if v := op.BulkID(); v != "" {
+		b.BulkID(v)
+	}
+	if v := op.Path(); v != "" {
+		b.Location(v)
+	}
+	return b.Build()
+}
+
+// process executes a single operation, and returns the response along
+// with the ID of the resource that was created
+func (d *bulkDispatcher) process(ctx context.Context, op *resource.BulkOperation, ids map[string]string) (*resource.BulkOperationResponse, string, error) {
+	method := strings.ToUpper(op.Method())
+	resolved, err := resolveBulkIDs(op.Path(), ids)
+	if err != nil {
+		return nil, "", err
+	}
+	path, _ := resolved.(string)
+
+	var data interface{}
+	if op.HasData() {
+		data, err = resolveBulkIDs(op.Data(), ids)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	segments := strings.Split(strings.Trim(path, `/`), `/`)
+	var id string
+	switch len(segments) {
+	case 1:
+	case 2:
+		id = segments[1]
+	default:
+		return nil, "", scimErrorf(http.StatusBadRequest, resource.ErrInvalidPath, `invalid path %q`, path)
+	}
+
+	switch method {
+	case http.MethodPost:
+		if id != "" {
+			return nil, "", scimErrorf(http.StatusBadRequest, resource.ErrInvalidPath, `invalid path %q for method %q`, path, op.Method())
+		}
+		if op.BulkID() == "" {
+			return nil, "", scimErrorf(http.StatusBadRequest, resource.ErrInvalidSyntax, `bulkId is required for method %q`, op.Method())
+		}
+	case http.MethodPut, http.MethodPatch, http.MethodDelete:
+		if id == "" {
+			return nil, "", scimErrorf(http.StatusBadRequest, resource.ErrInvalidPath, `invalid path %q for method %q`, path, op.Method())
+		}
+	default:
+		return nil, "", scimErrorf(http.StatusBadRequest, resource.ErrInvalidSyntax, `invalid method %q`, op.Method())
+	}
+	if method != http.MethodDelete && data == nil {
+		return nil, "", scimErrorf(http.StatusBadRequest, resource.ErrInvalidSyntax, `data is required for method %q`, op.Method())
+	}
+
+	// the version of an operation is handled in the same way as If-Match
+	if v := op.Version(); v != "" && method != http.MethodPost {
+		ctx = contextWithPreconditions(ctx, &Preconditions{IfMatch: []string{v}})
+	}
+
+	var meta *resource.Meta
+	var status int
+	switch segments[0] {
+	case `Users`:
+		id, meta, status, err = d.users(ctx, method, id, data)
+	case `Groups`:
+		id, meta, status, err = d.groups(ctx, method, id, data)
+	default:
+		return nil, "", scimErrorf(http.StatusBadRequest, resource.ErrInvalidPath, `invalid path %q`, path)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	b := resource.NewBulkOperationResponseBuilder().
+		Method(op.Method()).
+		Status(strconv.Itoa(status))
+	if v := op.BulkID(); v != "" {
+		b.BulkID(v)
+	}
+
+	location := `/` + segments[0] + `/` + id
+	if meta != nil {
+		if v := meta.Location(); v != "" {
+			location = v
+		}
+		if v := meta.Version(); v != "" {
+			b.Version(v)
+		}
+	}
+	b.Location(location)
+
+	res, err := b.Build()
+	if err != nil {
+		return nil, "", err
+	}
+	if method != http.MethodPost {
+		id = ""
+	}
+	return res, id, nil
+}
+
+func (d *bulkDispatcher) users(ctx context.Context, method, id string, data interface{}) (string, *resource.Meta, int, error) {
+	if method != http.MethodPost {
+		if err := checkIfMatch(ctx, PreconditionsFromContext(ctx), userVersion(d.backend), id); err != nil {
+			return "", nil, 0, err
+		}
+	}
+
+	switch method {
+	case http.MethodPost:
+		b, ok := d.backend.(CreateUserBackend)
+		if !ok {
+			break
+		}
+		var user resource.User
+		if err := decodeBulkData(data, &user); err != nil {
+			return "", nil, 0, err
+		}
+		created, err := b.CreateUser(ctx, &user)
+		if err != nil {
+			return "", nil, 0, err
+		}
+		return created.ID(), created.Meta(), http.StatusCreated, nil
+	case http.MethodPut:
+		b, ok := d.backend.(ReplaceUserBackend)
+		if !ok {
+			break
+		}
+		var user resource.User
+		if err := decodeBulkData(data, &user); err != nil {
+			return "", nil, 0, err
+		}
+		replaced, err := b.ReplaceUser(ctx, id, &user)
+		if err != nil {
+			return "", nil, 0, err
+		}
+		return id, replaced.Meta(), http.StatusOK, nil
+	case http.MethodPatch:
+		b, ok := d.backend.(PatchUserBackend)
+		if !ok {
+			break
+		}
+		var preq resource.PatchRequest
+		if err := decodeBulkData(data, &preq); err != nil {
+			return "", nil, 0, err
+		}
+		patched, err := b.PatchUser(ctx, id, &preq)
+		if err != nil {
+			return "", nil, 0, err
+		}
+		if patched == nil {
+			return id, nil, http.StatusNoContent, nil
+		}
+		return id, patched.Meta(), http.StatusOK, nil
+	case http.MethodDelete:
+		b, ok := d.backend.(DeleteUserBackend)
+		if !ok {
+			break
+		}
+		if err := b.DeleteUser(ctx, id); err != nil {
+			return "", nil, 0, err
+		}
+		return id, nil, http.StatusNoContent, nil
+	}
+	return "", nil, 0, scimErrorf(http.StatusNotImplemented, ``, `method %q is not supported for users`, method)
+}
+
+func (d *bulkDispatcher) groups(ctx context.Context, method, id string, data interface{}) (string, *resource.Meta, int, error) {
+	if method != http.MethodPost {
+		if err := checkIfMatch(ctx, PreconditionsFromContext(ctx), groupVersion(d.backend), id); err != nil {
+			return "", nil, 0, err
+		}
+	}
+
+	switch method {
+	case http.MethodPost:
+		b, ok := d.backend.(CreateGroupBackend)
+		if !ok {
+			break
+		}
+		var group resource.Group
+		if err := decodeBulkData(data, &group); err != nil {
+			return "", nil, 0, err
+		}
+		created, err := b.CreateGroup(ctx, &group)
+		if err != nil {
+			return "", nil, 0, err
+		}
+		return created.ID(), created.Meta(), http.StatusCreated, nil
+	case http.MethodPut:
+		b, ok := d.backend.(ReplaceGroupBackend)
+		if !ok {
+			break
+		}
+		var group resource.Group
+		if err := decodeBulkData(data, &group); err != nil {
+			return "", nil, 0, err
+		}
+		replaced, err := b.ReplaceGroup(ctx, id, &group)
+		if err != nil {
+			return "", nil, 0, err
+		}
+		return id, replaced.Meta(), http.StatusOK, nil
+	case http.MethodPatch:
+		b, ok := d.backend.(PatchGroupBackend)
+		if !ok {
+			break
+		}
+		var preq resource.PatchRequest
+		if err := decodeBulkData(data, &preq); err != nil {
+			return "", nil, 0, err
+		}
+		patched, err := b.PatchGroup(ctx, id, &preq)
+		if err != nil {
+			return "", nil, 0, err
+		}
+		if patched == nil {
+			return id, nil, http.StatusNoContent, nil
+		}
+		return id, patched.Meta(), http.StatusOK, nil
+	case http.MethodDelete:
+		b, ok := d.backend.(DeleteGroupBackend)
+		if !ok {
+			break
+		}
+		if err := b.DeleteGroup(ctx, id); err != nil {
+			return "", nil, 0, err
+		}
+		return id, nil, http.StatusNoContent, nil
+	}
+	return "", nil, 0, scimErrorf(http.StatusNotImplemented, ``, `method %q is not supported for groups`, method)
+}
+
+func decodeBulkData(data interface{}, dst interface{}) error {
+	serialized, err := json.Marshal(data)
+	if err != nil {
+		return scimErrorf(http.StatusBadRequest, resource.ErrInvalidSyntax, `failed to serialize data: %s`, err)
+	}
+	if err := json.Unmarshal(serialized, dst); err != nil {
+		return scimErrorf(http.StatusBadRequest, resource.ErrInvalidSyntax, `failed to parse data: %s`, err)
+	}
+	return nil
+}
+
+// collectBulkIDs collects the bulkIds referred to in the value
+func collectBulkIDs(v interface{}, refs *[]string) {
+	switch v := v.(type) {
+	case string:
+		for _, segment := range strings.Split(v, `/`) {
+			if strings.HasPrefix(segment, bulkIDPrefix) {
+				*refs = append(*refs, strings.TrimPrefix(segment, bulkIDPrefix))
+			}
+		}
+	case []interface{}:
+		for _, elem := range v {
+			collectBulkIDs(elem, refs)
+		}
+	case map[string]interface{}:
+		for _, elem := range v {
+			collectBulkIDs(elem, refs)
+		}
+	}
+}
+
+// resolveBulkIDs replaces the references to bulkIds in the value,
+// including path segments such as `/Groups/bulkId:qwerty`, with the IDs
+// of the resources that were created
+func resolveBulkIDs(v interface{}, ids map[string]string) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		if !strings.Contains(v, bulkIDPrefix) {
+			return v, nil
+		}
+		segments := strings.Split(v, `/`)
+		for i, segment := range segments {
+			if !strings.HasPrefix(segment, bulkIDPrefix) {
+				continue
+			}
+			ref := strings.TrimPrefix(segment, bulkIDPrefix)
+			id, ok := ids[ref]
+			if !ok {
+				return nil, scimErrorf(http.StatusConflict, resource.ErrInvalidValue, `unable to resolve bulkId %q`, ref)
+			}
+			segments[i] = id
+		}
+		return strings.Join(segments, `/`), nil
+	case []interface{}:
+		resolved := make([]interface{}, len(v))
+		for i, elem := range v {
+			r, err := resolveBulkIDs(elem, ids)
+			if err != nil {
+				return nil, err
+			}
+			resolved[i] = r
+		}
+		return resolved, nil
+	case map[string]interface{}:
+		resolved := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			r, err := resolveBulkIDs(elem, ids)
+			if err != nil {
+				return nil, err
+			}
+			resolved[key] = r
+		}
+		return resolved, nil
+	default:
+		return v, nil
+	}
+}
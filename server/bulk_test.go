@@ -0,0 +1,393 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cybozu-go/scim/resource"
+	"github.com/cybozu-go/scim/server"
+	"github.com/stretchr/testify/require"
+)
+
+type bulkBackend struct {
+	users  map[string]*resource.User
+	groups map[string]*resource.Group
+}
+
+func newBulkBackend() *bulkBackend {
+	return &bulkBackend{
+		users:  make(map[string]*resource.User),
+		groups: make(map[string]*resource.Group),
+	}
+}
+
+func (b *bulkBackend) CreateUser(_ context.Context, in *resource.User) (*resource.User, error) {
+	id := fmt.Sprintf(`user-%d`, len(b.users)+1)
+	user, err := resource.NewUserBuilder().
+		From(in).
+		ID(id).
+		Meta(resource.NewMetaBuilder().
+			ResourceType(`User`).
+			Location(`https://example.com/v2/Users/` + id).
+			Version(`W/"1"`).
+			MustBuild()).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+	b.users[id] = user
+	return user, nil
+}
+
+func (b *bulkBackend) DeleteUser(_ context.Context, id string) error {
+	if _, ok := b.users[id]; !ok {
+		return resource.NewErrorBuilder().
+			Status(http.StatusNotFound).
+			Detail(fmt.Sprintf(`user %q not found`, id)).
+			MustBuild()
+	}
+	delete(b.users, id)
+	return nil
+}
+
+func (b *bulkBackend) CreateGroup(_ context.Context, in *resource.Group) (*resource.Group, error) {
+	id := fmt.Sprintf(`group-%d`, len(b.groups)+1)
+	group, err := resource.NewGroupBuilder().
+		From(in).
+		ID(id).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+	b.groups[id] = group
+	return group, nil
+}
+
+// configuredBulkBackend advertises whether bulk operations are
+// supported in its service provider configuration
+type configuredBulkBackend struct {
+	*bulkBackend
+	supported bool
+}
+
+func (b *configuredBulkBackend) RetrieveServiceProviderConfig(_ context.Context) (*resource.ServiceProviderConfig, error) {
+	const format = `{
+  "authenticationSchemes": [],
+  "bulk": {"supported": %t, "maxOperations": 1000, "maxPayloadSize": 1048576},
+  "changePassword": {"supported": false},
+  "etag": {"supported": false},
+  "filter": {"supported": true, "maxResults": 200},
+  "patch": {"supported": true},
+  "sort": {"supported": false}
+}`
+	var config resource.ServiceProviderConfig
+	if err := json.Unmarshal([]byte(fmt.Sprintf(format, b.supported)), &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func TestBulk(t *testing.T) {
+	supported := []server.NewServerOption{
+		server.WithBulkSupport(resource.NewBulkSupportBuilder().
+			MaxOperations(0).
+			MaxPayloadSize(0).
+			Supported(true).
+			MustBuild()),
+	}
+
+	var operations []string
+	for i := 0; i < 1001; i++ {
+		operations = append(operations, fmt.Sprintf(`{"method": "POST", "path": "/Users", "bulkId": "user-%d", "data": {"userName": "user%d"}}`, i, i))
+	}
+
+	testcases := []struct {
+		Name     string
+		Options  []server.NewServerOption
+		Payload  string
+		Status   int
+		Expected string
+		Check    func(*testing.T, *bulkBackend)
+	}{
+		{
+			Name:    `create with bulkId references`,
+			Options: supported,
+			Payload: `{
+  "schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkRequest"],
+  "Operations": [
+    {
+      "method": "POST",
+      "path": "/Groups",
+      "bulkId": "ytrewq",
+      "data": {"displayName": "Tour Guides", "members": [{"type": "User", "value": "bulkId:qwerty"}]}
+    },
+    {
+      "method": "POST",
+      "path": "/Users",
+      "bulkId": "qwerty",
+      "data": {"userName": "Alice"}
+    }
+  ]
+}`,
+			Status: http.StatusOK,
+			Expected: `{
+  "schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkResponse"],
+  "Operations": [
+    {"method": "POST", "bulkId": "ytrewq", "location": "/Groups/group-1", "status": "201"},
+    {"method": "POST", "bulkId": "qwerty", "location": "https://example.com/v2/Users/user-1", "version": "W/\"1\"", "status": "201"}
+  ]
+}`,
+			Check: func(t *testing.T, b *bulkBackend) {
+				group, ok := b.groups[`group-1`]
+				require.True(t, ok, `group should be created`)
+				require.Len(t, group.Members(), 1, `group should have a member`)
+				require.Equal(t, `user-1`, group.Members()[0].Value(), `bulkId should be resolved`)
+			},
+		},
+		{
+			Name:    `unresolvable bulkId`,
+			Options: supported,
+			Payload: `{
+  "schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkRequest"],
+  "Operations": [
+    {"method": "DELETE", "path": "/Users/bulkId:qwerty"}
+  ]
+}`,
+			Status: http.StatusOK,
+			Expected: `{
+  "schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkResponse"],
+  "Operations": [
+    {
+      "method": "DELETE",
+      "location": "/Users/bulkId:qwerty",
+      "status": "409",
+      "response": {"scimType": "invalidValue", "detail": "unable to resolve bulkId \"qwerty\"", "status": 409}
+    }
+  ]
+}`,
+		},
+		{
+			Name:    `failOnErrors`,
+			Options: supported,
+			Payload: `{
+  "schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkRequest"],
+  "failOnErrors": 1,
+  "Operations": [
+    {"method": "DELETE", "path": "/Users/unknown"},
+    {"method": "POST", "path": "/Users", "bulkId": "qwerty", "data": {"userName": "Alice"}}
+  ]
+}`,
+			Status: http.StatusOK,
+			Expected: `{
+  "schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkResponse"],
+  "Operations": [
+    {
+      "method": "DELETE",
+      "location": "/Users/unknown",
+      "status": "404",
+      "response": {"detail": "user \"unknown\" not found", "status": 404}
+    }
+  ]
+}`,
+			Check: func(t *testing.T, b *bulkBackend) {
+				require.Empty(t, b.users, `operations after the failure should not be processed`)
+			},
+		},
+		{
+			Name:    `unsupported method`,
+			Options: supported,
+			Payload: `{
+  "schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkRequest"],
+  "Operations": [
+    {"method": "DELETE", "path": "/Groups/group-1"}
+  ]
+}`,
+			Status: http.StatusOK,
+			Expected: `{
+  "schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkResponse"],
+  "Operations": [
+    {
+      "method": "DELETE",
+      "location": "/Groups/group-1",
+      "status": "501",
+      "response": {"detail": "method \"DELETE\" is not supported for groups", "status": 501}
+    }
+  ]
+}`,
+		},
+		{
+			Name: `maxOperations`,
+			Options: []server.NewServerOption{
+				server.WithBulkSupport(resource.NewBulkSupportBuilder().
+					MaxOperations(1).
+					MaxPayloadSize(1048576).
+					Supported(true).
+					MustBuild()),
+			},
+			Payload: `{
+  "schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkRequest"],
+  "Operations": [
+    {"method": "POST", "path": "/Users", "bulkId": "qwerty", "data": {"userName": "Alice"}},
+    {"method": "POST", "path": "/Users", "bulkId": "ytrewq", "data": {"userName": "Bob"}}
+  ]
+}`,
+			Status:   http.StatusRequestEntityTooLarge,
+			Expected: `{"scimType": "tooMany", "detail": "the number of operations exceeds the maxOperations (1)", "status": 413}`,
+		},
+		{
+			Name: `maxPayloadSize`,
+			Options: []server.NewServerOption{
+				server.WithBulkSupport(resource.NewBulkSupportBuilder().
+					MaxOperations(1000).
+					MaxPayloadSize(64).
+					Supported(true).
+					MustBuild()),
+			},
+			Payload: `{
+  "schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkRequest"],
+  "Operations": [
+    {"method": "POST", "path": "/Users", "bulkId": "qwerty", "data": {"userName": "Alice"}}
+  ]
+}`,
+			Status:   http.StatusRequestEntityTooLarge,
+			Expected: `{"detail": "the size of the bulk operation exceeds the maxPayloadSize (64)", "status": 413}`,
+		},
+		{
+			Name:     `default maxOperations`,
+			Options:  supported,
+			Payload:  `{"schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkRequest"], "Operations": [` + strings.Join(operations, `,`) + `]}`,
+			Status:   http.StatusRequestEntityTooLarge,
+			Expected: `{"scimType": "tooMany", "detail": "the number of operations exceeds the maxOperations (1000)", "status": 413}`,
+			Check: func(t *testing.T, b *bulkBackend) {
+				require.Empty(t, b.users, `no users should be created`)
+			},
+		},
+		{
+			Name:     `default maxPayloadSize`,
+			Options:  supported,
+			Payload:  `{"schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkRequest"], "Operations": [], "padding": "` + strings.Repeat(`a`, 1048576) + `"}`,
+			Status:   http.StatusRequestEntityTooLarge,
+			Expected: `{"detail": "the size of the bulk operation exceeds the maxPayloadSize (1048576)", "status": 413}`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			backend := newBulkBackend()
+			hh, err := server.NewServer(backend, tc.Options...)
+			require.NoError(t, err, `server.NewServer should succeed`)
+
+			req := httptest.NewRequest(http.MethodPost, `/Bulk`, strings.NewReader(tc.Payload))
+			w := httptest.NewRecorder()
+			hh.ServeHTTP(w, req)
+			require.Equal(t, tc.Status, w.Code, `status should match`)
+			require.JSONEq(t, tc.Expected, w.Body.String(), `response should match`)
+
+			if tc.Check != nil {
+				tc.Check(t, backend)
+			}
+		})
+	}
+}
+
+func TestBulkRegistration(t *testing.T) {
+	const payload = `{
+  "schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkRequest"],
+  "Operations": []
+}`
+
+	unsupported := server.WithBulkSupport(resource.NewBulkSupportBuilder().
+		MaxOperations(0).
+		MaxPayloadSize(0).
+		Supported(false).
+		MustBuild())
+
+	testcases := []struct {
+		Name     string
+		Backend  interface{}
+		Options  []server.NewServerOption
+		Status   int
+		Expected string
+	}{
+		{
+			Name:     `dispatcher without bulk support`,
+			Backend:  newBulkBackend(),
+			Status:   http.StatusNotImplemented,
+			Expected: `{"scimType": "unknown", "detail": "bulk operations are not supported", "status": 501}`,
+		},
+		{
+			Name:     `dispatcher with bulk operations not supported`,
+			Backend:  newBulkBackend(),
+			Options:  []server.NewServerOption{unsupported},
+			Status:   http.StatusNotImplemented,
+			Expected: `{"scimType": "unknown", "detail": "bulk operations are not supported", "status": 501}`,
+		},
+		{
+			Name:     `dispatcher with bulk operations not supported in service provider configuration`,
+			Backend:  &configuredBulkBackend{bulkBackend: newBulkBackend(), supported: false},
+			Status:   http.StatusNotImplemented,
+			Expected: `{"scimType": "unknown", "detail": "bulk operations are not supported", "status": 501}`,
+		},
+		{
+			Name:     `dispatcher with bulk operations supported in service provider configuration`,
+			Backend:  &configuredBulkBackend{bulkBackend: newBulkBackend(), supported: true},
+			Status:   http.StatusOK,
+			Expected: `{"schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkResponse"]}`,
+		},
+		{
+			Name:     `bulk backend with bulk operations not supported`,
+			Backend:  server.NewBulkDispatcher(newBulkBackend()),
+			Options:  []server.NewServerOption{unsupported},
+			Status:   http.StatusNotImplemented,
+			Expected: `{"scimType": "unknown", "detail": "bulk operations are not supported", "status": 501}`,
+		},
+		{
+			Name:     `bulk backend`,
+			Backend:  server.NewBulkDispatcher(newBulkBackend()),
+			Status:   http.StatusOK,
+			Expected: `{"schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkResponse"]}`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			hh, err := server.NewServer(tc.Backend, tc.Options...)
+			require.NoError(t, err, `server.NewServer should succeed`)
+
+			req := httptest.NewRequest(http.MethodPost, `/Bulk`, strings.NewReader(payload))
+			w := httptest.NewRecorder()
+			hh.ServeHTTP(w, req)
+			require.Equal(t, tc.Status, w.Code, `status should match`)
+			if tc.Expected != "" {
+				require.JSONEq(t, tc.Expected, w.Body.String(), `response should match`)
+			}
+		})
+	}
+}
+
+func TestBulkRequest(t *testing.T) {
+	const payload = `{
+  "schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkRequest"],
+  "failOnErrors": 1,
+  "Operations": [
+    {"method": "PATCH", "path": "/Groups/e9e30dba", "version": "W/\"1\"", "data": {"operations": [{"op": "remove", "path": "members"}]}}
+  ]
+}`
+
+	var req resource.BulkRequest
+	require.NoError(t, json.Unmarshal([]byte(payload), &req), `json.Unmarshal should succeed`)
+	require.Equal(t, 1, req.FailOnErrors(), `failOnErrors should match`)
+	require.Len(t, req.Operations(), 1, `operations should match`)
+	require.Equal(t, `W/"1"`, req.Operations()[0].Version(), `version should match`)
+
+	serialized, err := json.Marshal(&req)
+	require.NoError(t, err, `json.Marshal should succeed`)
+	require.JSONEq(t, payload, string(serialized), `round trip should match`)
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/cybozu-go/scim/resource"
+	"github.com/lestrrat-go/mux"
+)
+
+// SubjectResolver derives the ID of the user that made the request,
+// which is used to serve the `/Me` endpoint (RFC 7644 Section 3.11).
+// How the subject is determined depends on the authentication scheme,
+// e.g. it may be taken from a context value set by an authentication
+// middleware, or from the claims of a bearer token.
+//
+// If the subject cannot be determined, the resolver should return an
+// empty ID, in which case the request fails with a 401 status. Errors
+// are reported to the client in the same way as errors from backends.
+type SubjectResolver interface {
+	ResolveSubject(*http.Request) (string, error)
+}
+
+type SubjectResolverFunc func(*http.Request) (string, error)
+
+func (f SubjectResolverFunc) ResolveSubject(r *http.Request) (string, error) {
+	return f(r)
+}
+
+// userIDFunc extracts the ID of the user that the request applies to
+type userIDFunc func(*http.Request) (string, error)
+
+func pathUserID(r *http.Request) (string, error) {
+	id := mux.Vars(r).Get(`id`)
+	if id == "" {
+		return "", scimErrorf(http.StatusBadRequest, resource.ErrUnknown, `missing ID`)
+	}
+	return id, nil
+}
+
+func subjectUserID(resolver SubjectResolver) userIDFunc {
+	return func(r *http.Request) (string, error) {
+		id, err := resolver.ResolveSubject(r)
+		if err != nil {
+			return "", err
+		}
+		if id == "" {
+			return "", scimErrorf(http.StatusUnauthorized, ``, `the authenticated subject could not be determined`)
+		}
+		return id, nil
+	}
+}
+
+// userLocation returns the URI of the user, which is reported in the
+// `Content-Location` header of responses from the `/Me` endpoint
+func userLocation(id string, user *resource.User) string {
+	if user != nil {
+		if meta := user.Meta(); meta != nil {
+			if v := meta.Location(); v != "" {
+				return v
+			}
+		}
+		if v := user.ID(); v != "" {
+			id = v
+		}
+	}
+	return `/Users/` + id
+}
+
+// RetrieveMeEndpoint creates the handler for `GET /Me`, which retrieves
+// the user identified by the resolver using the user backend.
+func RetrieveMeEndpoint(b RetrieveUserBackend, resolver SubjectResolver) http.Handler {
+	return retrieveUserEndpoint(b, subjectUserID(resolver), true)
+}
+
+// ReplaceMeEndpoint creates the handler for `PUT /Me`, which replaces
+// the user identified by the resolver using the user backend.
+func ReplaceMeEndpoint(b ReplaceUserBackend, resolver SubjectResolver) http.Handler {
+	return replaceUserEndpoint(b, subjectUserID(resolver), true)
+}
+
+// PatchMeEndpoint creates the handler for `PATCH /Me`, which patches
+// the user identified by the resolver using the user backend.
+func PatchMeEndpoint(b PatchUserBackend, resolver SubjectResolver, options ...PatchEndpointOption) http.Handler {
+	return patchUserEndpoint(b, subjectUserID(resolver), true, options)
+}
+
+// DeleteMeEndpoint creates the handler for `DELETE /Me`, which deletes
+// the user identified by the resolver using the user backend.
+func DeleteMeEndpoint(b DeleteUserBackend, resolver SubjectResolver) http.Handler {
+	return deleteUserEndpoint(b, subjectUserID(resolver))
+}
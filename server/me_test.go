@@ -0,0 +1,186 @@
+package server_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cybozu-go/scim/resource"
+	"github.com/cybozu-go/scim/server"
+	"github.com/stretchr/testify/require"
+)
+
+type meBackend struct {
+	users map[string]*resource.User
+}
+
+func newMeBackend() *meBackend {
+	return &meBackend{
+		users: map[string]*resource.User{
+			`2819c223`: resource.NewUserBuilder().
+				ID(`2819c223`).
+				UserName(`bjensen`).
+				Meta(resource.NewMetaBuilder().
+					ResourceType(`User`).
+					Location(`https://example.com/v2/Users/2819c223`).
+					Version(`W/"1"`).
+					MustBuild()).
+				MustBuild(),
+		},
+	}
+}
+
+func (b *meBackend) lookup(id string) (*resource.User, error) {
+	user, ok := b.users[id]
+	if !ok {
+		return nil, resource.NewErrorBuilder().
+			Status(http.StatusNotFound).
+			Detail(fmt.Sprintf(`user %q not found`, id)).
+			MustBuild()
+	}
+	return user, nil
+}
+
+func (b *meBackend) RetrieveUser(_ context.Context, id string, _, _ []string) (*resource.User, error) {
+	return b.lookup(id)
+}
+
+func (b *meBackend) ReplaceUser(_ context.Context, id string, in *resource.User) (*resource.User, error) {
+	user, err := b.lookup(id)
+	if err != nil {
+		return nil, err
+	}
+	replaced, err := resource.NewUserBuilder().
+		From(in).
+		ID(id).
+		Meta(user.Meta()).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+	b.users[id] = replaced
+	return replaced, nil
+}
+
+func (b *meBackend) PatchUser(_ context.Context, id string, _ *resource.PatchRequest) (*resource.User, error) {
+	_, err := b.lookup(id)
+	return nil, err
+}
+
+func (b *meBackend) DeleteUser(_ context.Context, id string) error {
+	if _, err := b.lookup(id); err != nil {
+		return err
+	}
+	delete(b.users, id)
+	return nil
+}
+
+func TestMe(t *testing.T) {
+	resolver := server.SubjectResolverFunc(func(r *http.Request) (string, error) {
+		return r.Header.Get(`X-Subject`), nil
+	})
+
+	testcases := []struct {
+		Name            string
+		Options         []server.NewServerOption
+		Method          string
+		Subject         string
+		Payload         string
+		Status          int
+		ContentLocation string
+		Expected        string
+		Check           func(*testing.T, *meBackend)
+	}{
+		{
+			Name:            `retrieve`,
+			Method:          http.MethodGet,
+			Subject:         `2819c223`,
+			Status:          http.StatusOK,
+			ContentLocation: `https://example.com/v2/Users/2819c223`,
+			Expected:        `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "id": "2819c223", "userName": "bjensen", "meta": {"resourceType": "User", "location": "https://example.com/v2/Users/2819c223", "version": "W/\"1\""}}`,
+		},
+		{
+			Name:            `replace`,
+			Method:          http.MethodPut,
+			Subject:         `2819c223`,
+			Payload:         `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "userName": "bjensen", "displayName": "Babs Jensen"}`,
+			Status:          http.StatusOK,
+			ContentLocation: `https://example.com/v2/Users/2819c223`,
+			Expected:        `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "id": "2819c223", "userName": "bjensen", "displayName": "Babs Jensen", "meta": {"resourceType": "User", "location": "https://example.com/v2/Users/2819c223", "version": "W/\"1\""}}`,
+			Check: func(t *testing.T, b *meBackend) {
+				require.Equal(t, `Babs Jensen`, b.users[`2819c223`].DisplayName(), `user should be replaced`)
+			},
+		},
+		{
+			Name:            `patch`,
+			Method:          http.MethodPatch,
+			Subject:         `2819c223`,
+			Payload:         `{"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"], "operations": [{"op": "replace", "path": "displayName", "value": "Babs"}]}`,
+			Status:          http.StatusNoContent,
+			ContentLocation: `/Users/2819c223`,
+		},
+		{
+			Name:    `delete`,
+			Method:  http.MethodDelete,
+			Subject: `2819c223`,
+			Status:  http.StatusNoContent,
+			Check: func(t *testing.T, b *meBackend) {
+				require.Empty(t, b.users, `user should be deleted`)
+			},
+		},
+		{
+			Name:     `unknown subject`,
+			Method:   http.MethodGet,
+			Subject:  `unknown`,
+			Status:   http.StatusNotFound,
+			Expected: `{"detail": "user \"unknown\" not found", "status": 404}`,
+		},
+		{
+			Name:     `unauthenticated`,
+			Method:   http.MethodDelete,
+			Status:   http.StatusUnauthorized,
+			Expected: `{"detail": "the authenticated subject could not be determined", "status": 401}`,
+			Check: func(t *testing.T, b *meBackend) {
+				require.Len(t, b.users, 1, `user should not be deleted`)
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			backend := newMeBackend()
+			hh, err := server.NewServer(backend, server.WithSubjectResolver(resolver))
+			require.NoError(t, err, `server.NewServer should succeed`)
+
+			req := httptest.NewRequest(tc.Method, `/Me`, strings.NewReader(tc.Payload))
+			if tc.Subject != "" {
+				req.Header.Set(`X-Subject`, tc.Subject)
+			}
+			w := httptest.NewRecorder()
+			hh.ServeHTTP(w, req)
+			require.Equal(t, tc.Status, w.Code, `status should match`)
+			require.Equal(t, tc.ContentLocation, w.Header().Get(`Content-Location`), `Content-Location should match`)
+			if tc.Expected != "" {
+				require.JSONEq(t, tc.Expected, w.Body.String(), `response should match`)
+			}
+
+			if tc.Check != nil {
+				tc.Check(t, backend)
+			}
+		})
+	}
+
+	t.Run(`without a subject resolver`, func(t *testing.T) {
+		hh, err := server.NewServer(newMeBackend())
+		require.NoError(t, err, `server.NewServer should succeed`)
+
+		req := httptest.NewRequest(http.MethodGet, `/Me`, nil)
+		w := httptest.NewRecorder()
+		hh.ServeHTTP(w, req)
+		require.Equal(t, http.StatusNotFound, w.Code, `/Me should not be registered`)
+	})
+}
@@ -3,11 +3,44 @@
 package server
 
 import (
+	"github.com/cybozu-go/scim/filter"
+	"github.com/cybozu-go/scim/patch"
+	"github.com/cybozu-go/scim/resource"
 	"github.com/lestrrat-go/option"
 )
 
 type Option = option.Interface
 
+// BulkEndpointOption describes an option that can be passed to
+// `server.BulkEndpoint()`.
+type BulkEndpointOption interface {
+	Option
+	bulkEndpointOption()
+}
+
+type bulkEndpointOption struct {
+	Option
+}
+
+func (*bulkEndpointOption) bulkEndpointOption() {}
+
+// BulkOption describes an option that can be passed to either
+// `server.NewServer()`, or the bulk endpoint.
+type BulkOption interface {
+	NewServerOption
+	BulkEndpointOption
+	newServerOption()
+	bulkEndpointOption()
+}
+
+type bulkOption struct {
+	Option
+}
+
+func (*bulkOption) newServerOption() {}
+
+func (*bulkOption) bulkEndpointOption() {}
+
 // HandlerOption describes an option that can be passed to `(server.Builder).Handler()`.
 type HandlerOption interface {
 	Option
@@ -20,13 +53,148 @@ type handlerOption struct {
 
 func (*handlerOption) handlerOption() {}
 
+// NewServerOption describes an option that can be passed to `server.NewServer()`.
+type NewServerOption interface {
+	Option
+	newServerOption()
+}
+
+type newServerOption struct {
+	Option
+}
+
+func (*newServerOption) newServerOption() {}
+
+// PatchEndpointOption describes an option that can be passed to
+// `server.PatchUserEndpoint()` and `server.PatchGroupEndpoint()`.
+type PatchEndpointOption interface {
+	Option
+	patchEndpointOption()
+}
+
+type patchEndpointOption struct {
+	Option
+}
+
+func (*patchEndpointOption) patchEndpointOption() {}
+
+// PatchOption describes an option that can be passed to either
+// `server.NewServer()`, or the PATCH endpoints.
+type PatchOption interface {
+	NewServerOption
+	PatchEndpointOption
+	newServerOption()
+	patchEndpointOption()
+}
+
+type patchOption struct {
+	Option
+}
+
+func (*patchOption) newServerOption() {}
+
+func (*patchOption) patchEndpointOption() {}
+
+// SearchEndpointOption describes an option that can be passed to
+// the search endpoints, such as `server.SearchUserEndpoint()` and
+// `server.ListUsersEndpoint()`.
+type SearchEndpointOption interface {
+	Option
+	searchEndpointOption()
+}
+
+type searchEndpointOption struct {
+	Option
+}
+
+func (*searchEndpointOption) searchEndpointOption() {}
+
+// SearchOption describes an option that can be passed to either
+// `server.NewServer()`, or the search endpoints.
+type SearchOption interface {
+	NewServerOption
+	SearchEndpointOption
+	newServerOption()
+	searchEndpointOption()
+}
+
+type searchOption struct {
+	Option
+}
+
+func (*searchOption) newServerOption() {}
+
+func (*searchOption) searchEndpointOption() {}
+
+type identBulkSupport struct{}
+type identFilterLimits struct{}
+type identPatchQuirks struct{}
 type identPath struct{}
+type identSubjectResolver struct{}
+
+func (identBulkSupport) String() string {
+	return "WithBulkSupport"
+}
+
+func (identFilterLimits) String() string {
+	return "WithFilterLimits"
+}
+
+func (identPatchQuirks) String() string {
+	return "WithPatchQuirks"
+}
 
 func (identPath) String() string {
 	return "WithPath"
 }
 
+func (identSubjectResolver) String() string {
+	return "WithSubjectResolver"
+}
+
+// WithBulkSupport specifies the limits that are enforced on bulk
+// requests. When this option is not specified, the limits are taken
+// from the `bulk` section of the service provider configuration if
+// the backend provides one, and the default limits described in
+// `server.BulkEndpoint()` are enforced otherwise.
+//
+// For backends that do not implement BulkBackend, `server.NewServer()`
+// processes bulk requests using `server.NewBulkDispatcher()` only when
+// bulk operations are advertised as supported, either by this option
+// or by the service provider configuration. Other bulk requests are
+// rejected with a 501 status.
+func WithBulkSupport(v *resource.BulkSupport) BulkOption {
+	return &bulkOption{option.New(identBulkSupport{}, v)}
+}
+
+// WithFilterLimits specifies the options, such as `filter.WithMaxNodes()`,
+// that are passed to `filter.Parse()` when the filters in search requests
+// are parsed. Filters that exceed the limits are rejected with a 400
+// status before the backend is called. By default, filters are not
+// limited.
+func WithFilterLimits(v []filter.ParseOption) SearchOption {
+	return &searchOption{option.New(identFilterLimits{}, v)}
+}
+
+// WithPatchQuirks specifies the deviations from RFC7644 that are
+// accepted in PATCH requests. Requests are rewritten into their
+// canonical form using `patch.Normalize()` before they are passed
+// to the backend, so that backends only need to handle compliant
+// requests. By default, no deviations are accepted.
+func WithPatchQuirks(v patch.Quirks) PatchOption {
+	return &patchOption{option.New(identPatchQuirks{}, v)}
+}
+
 // WithPath specifies the path that the handler should be registered at
 func WithPath(v string) HandlerOption {
 	return &handlerOption{option.New(identPath{}, v)}
 }
+
+// WithSubjectResolver specifies how the authenticated user is
+// determined for requests to the `/Me` endpoint. The `/Me` endpoint
+// is only registered when this option is specified, and it supports
+// the same methods as `/Users/{id}` for which the backend has the
+// corresponding user backend.
+func WithSubjectResolver(v SubjectResolver) NewServerOption {
+	return &newServerOption{option.New(identSubjectResolver{}, v)}
+}
@@ -9,5 +9,9 @@ import (
 )
 
 func TestOptionIdent(t *testing.T) {
+	require.Equal(t, "WithBulkSupport", identBulkSupport{}.String())
+	require.Equal(t, "WithFilterLimits", identFilterLimits{}.String())
+	require.Equal(t, "WithPatchQuirks", identPatchQuirks{}.String())
 	require.Equal(t, "WithPath", identPath{}.String())
+	require.Equal(t, "WithSubjectResolver", identSubjectResolver{}.String())
 }
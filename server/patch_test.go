@@ -0,0 +1,65 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cybozu-go/scim/patch"
+	"github.com/cybozu-go/scim/resource"
+	"github.com/cybozu-go/scim/server"
+	"github.com/stretchr/testify/require"
+)
+
+type patchBackend struct {
+	received *resource.PatchRequest
+}
+
+func (b *patchBackend) PatchUser(_ context.Context, _ string, req *resource.PatchRequest) (*resource.User, error) {
+	b.received = req
+	return nil, nil
+}
+
+func TestPatchQuirks(t *testing.T) {
+	const payload = `{
+  "schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+  "operations": [{"op": "Replace", "path": "active", "value": "False"}]
+}`
+
+	testcases := []struct {
+		Name     string
+		Options  []server.NewServerOption
+		Expected string
+	}{
+		{
+			Name:     `without quirks`,
+			Expected: `[{"op":"Replace","path":"active","value":"False"}]`,
+		},
+		{
+			Name:     `with quirks`,
+			Options:  []server.NewServerOption{server.WithPatchQuirks(patch.QuirksAll)},
+			Expected: `[{"op":"replace","path":"active","value":false}]`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			var backend patchBackend
+			hh, err := server.NewServer(&backend, tc.Options...)
+			require.NoError(t, err, `server.NewServer should succeed`)
+
+			req := httptest.NewRequest(http.MethodPatch, `/Users/2819c223`, strings.NewReader(payload))
+			w := httptest.NewRecorder()
+			hh.ServeHTTP(w, req)
+			require.Equal(t, http.StatusNoContent, w.Code, `status should match`)
+
+			serialized, err := json.Marshal(backend.received.Operations())
+			require.NoError(t, err, `json.Marshal should succeed`)
+			require.JSONEq(t, tc.Expected, string(serialized), `operations should match`)
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// Preconditions holds the entity tags specified in the `If-Match` and
+// `If-None-Match` headers of a request (RFC 7644 Section 3.14).
+//
+// The endpoints store the preconditions in the request context before
+// calling the backend, so that backends that manage versions can check
+// them atomically using `server.PreconditionsFromContext()`. Backends
+// that do not check the preconditions by themselves can still rely on
+// the endpoints, which compare them against the version of the resource
+// whenever the backend also implements the corresponding Retrieve
+// interface (e.g. RetrieveUserBackend).
+type Preconditions struct {
+	IfMatch     []string
+	IfNoneMatch []string
+}
+
+type preconditionsKey struct{}
+
+// PreconditionsFromContext returns the preconditions of the request
+// being processed, or nil if the request does not have any.
+func PreconditionsFromContext(ctx context.Context) *Preconditions {
+	p, _ := ctx.Value(preconditionsKey{}).(*Preconditions)
+	return p
+}
+
+func contextWithPreconditions(ctx context.Context, p *Preconditions) context.Context {
+	return context.WithValue(ctx, preconditionsKey{}, p)
+}
+
+// Match returns true if a resource with the given version satisfies
+// the `If-Match` precondition. Entity tags are compared using the weak
+// comparison, as SCIM versions are usually weak entity tags.
+func (p *Preconditions) Match(version string) bool {
+	if p == nil || len(p.IfMatch) == 0 {
+		return true
+	}
+	return matchETag(p.IfMatch, version)
+}
+
+// NoneMatch returns true if a resource with the given version satisfies
+// the `If-None-Match` precondition. Entity tags are compared using the
+// weak comparison.
+func (p *Preconditions) NoneMatch(version string) bool {
+	if p == nil || len(p.IfNoneMatch) == 0 {
+		return true
+	}
+	return !matchETag(p.IfNoneMatch, version)
+}
+
+func matchETag(tags []string, version string) bool {
+	for _, tag := range tags {
+		if tag == `*` {
+			return true
+		}
+		if version != "" && strings.TrimPrefix(tag, `W/`) == strings.TrimPrefix(version, `W/`) {
+			return true
+		}
+	}
+	return false
+}
+
+// withPreconditions parses the conditional headers, and stores the
+// resulting preconditions in the context of the request
+func withPreconditions(r *http.Request) (*http.Request, *Preconditions) {
+	p := &Preconditions{
+		IfMatch:     parseETags(r.Header.Values(`If-Match`)),
+		IfNoneMatch: parseETags(r.Header.Values(`If-None-Match`)),
+	}
+	if len(p.IfMatch) == 0 && len(p.IfNoneMatch) == 0 {
+		return r, nil
+	}
+	return r.WithContext(contextWithPreconditions(r.Context(), p)), p
+}
+
+// parseETags parses a list of entity tags, such as `W/"1", "2"`
+func parseETags(values []string) []string {
+	var tags []string
+	appendTag := func(tag string) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	for _, value := range values {
+		var quoted bool
+		start := 0
+		for i := 0; i < len(value); i++ {
+			switch value[i] {
+			case '"':
+				quoted = !quoted
+			case ',':
+				if !quoted {
+					appendTag(value[start:i])
+					start = i + 1
+				}
+			}
+		}
+		appendTag(value[start:])
+	}
+	return tags
+}
+
+// versionFunc fetches the current version of a resource
+type versionFunc func(context.Context, string) (string, error)
+
+// userVersion returns the function that fetches the version of a user,
+// or nil if the backend cannot retrieve users
+func userVersion(b interface{}) versionFunc {
+	rb, ok := b.(RetrieveUserBackend)
+	if !ok {
+		return nil
+	}
+	return func(ctx context.Context, id string) (string, error) {
+		user, err := rb.RetrieveUser(ctx, id, nil, nil)
+		if err != nil {
+			return "", err
+		}
+		if meta := user.Meta(); meta != nil {
+			return meta.Version(), nil
+		}
+		return "", nil
+	}
+}
+
+// groupVersion returns the function that fetches the version of a group,
+// or nil if the backend cannot retrieve groups
+func groupVersion(b interface{}) versionFunc {
+	rb, ok := b.(RetrieveGroupBackend)
+	if !ok {
+		return nil
+	}
+	return func(ctx context.Context, id string) (string, error) {
+		group, err := rb.RetrieveGroup(ctx, id, nil, nil)
+		if err != nil {
+			return "", err
+		}
+		if meta := group.Meta(); meta != nil {
+			return meta.Version(), nil
+		}
+		return "", nil
+	}
+}
+
+// checkIfMatch compares the `If-Match` precondition against the current
+// version of the resource, and returns an error with a 412 status if it
+// is not satisfied. If the version cannot be fetched, the check is left
+// to the backend
+func checkIfMatch(ctx context.Context, p *Preconditions, version versionFunc, id string) error {
+	if p == nil || len(p.IfMatch) == 0 || version == nil {
+		return nil
+	}
+
+	v, err := version(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !p.Match(v) {
+		return scimErrorf(http.StatusPreconditionFailed, ``, `resource version does not match`)
+	}
+	return nil
+}
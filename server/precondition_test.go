@@ -0,0 +1,157 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cybozu-go/scim/resource"
+	"github.com/cybozu-go/scim/server"
+	"github.com/stretchr/testify/require"
+)
+
+type versionedBackend struct {
+	preconditions *server.Preconditions
+}
+
+func (b *versionedBackend) user(id string) *resource.User {
+	return resource.NewUserBuilder().
+		ID(id).
+		UserName(`bjensen`).
+		Meta(resource.NewMetaBuilder().
+			ResourceType(`User`).
+			Version(`W/"3694e05e9dff590"`).
+			MustBuild()).
+		MustBuild()
+}
+
+func (b *versionedBackend) CreateUser(_ context.Context, _ *resource.User) (*resource.User, error) {
+	return b.user(`2819c223`), nil
+}
+
+func (b *versionedBackend) RetrieveUser(_ context.Context, id string, _, _ []string) (*resource.User, error) {
+	return b.user(id), nil
+}
+
+func (b *versionedBackend) ReplaceUser(_ context.Context, id string, _ *resource.User) (*resource.User, error) {
+	return b.user(id), nil
+}
+
+func (b *versionedBackend) PatchUser(_ context.Context, id string, _ *resource.PatchRequest) (*resource.User, error) {
+	return b.user(id), nil
+}
+
+func (b *versionedBackend) DeleteUser(_ context.Context, _ string) error {
+	return nil
+}
+
+// groups cannot be retrieved, so the preconditions are left to the backend
+func (b *versionedBackend) DeleteGroup(ctx context.Context, _ string) error {
+	b.preconditions = server.PreconditionsFromContext(ctx)
+	return nil
+}
+
+func TestPreconditions(t *testing.T) {
+	testcases := []struct {
+		Name    string
+		Method  string
+		Path    string
+		Header  http.Header
+		Payload string
+		Status  int
+		ETag    string
+	}{
+		{
+			Name:   `GET with matching If-None-Match`,
+			Method: http.MethodGet,
+			Path:   `/Users/2819c223`,
+			Header: http.Header{`If-None-Match`: []string{`W/"e180ee84f0671b1", W/"3694e05e9dff590"`}},
+			Status: http.StatusNotModified,
+			ETag:   `W/"3694e05e9dff590"`,
+		},
+		{
+			Name:   `GET with If-None-Match that does not match`,
+			Method: http.MethodGet,
+			Path:   `/Users/2819c223`,
+			Header: http.Header{`If-None-Match`: []string{`W/"e180ee84f0671b1"`}},
+			Status: http.StatusOK,
+			ETag:   `W/"3694e05e9dff590"`,
+		},
+		{
+			Name:    `POST sets ETag`,
+			Method:  http.MethodPost,
+			Path:    `/Users`,
+			Payload: `{"userName": "bjensen"}`,
+			Status:  http.StatusCreated,
+			ETag:    `W/"3694e05e9dff590"`,
+		},
+		{
+			Name:    `PUT with matching If-Match`,
+			Method:  http.MethodPut,
+			Path:    `/Users/2819c223`,
+			Header:  http.Header{`If-Match`: []string{`W/"3694e05e9dff590"`}},
+			Payload: `{"userName": "bjensen"}`,
+			Status:  http.StatusOK,
+			ETag:    `W/"3694e05e9dff590"`,
+		},
+		{
+			Name:    `PUT with If-Match that does not match`,
+			Method:  http.MethodPut,
+			Path:    `/Users/2819c223`,
+			Header:  http.Header{`If-Match`: []string{`W/"e180ee84f0671b1"`}},
+			Payload: `{"userName": "bjensen"}`,
+			Status:  http.StatusPreconditionFailed,
+		},
+		{
+			Name:    `PATCH with If-Match that does not match`,
+			Method:  http.MethodPatch,
+			Path:    `/Users/2819c223`,
+			Header:  http.Header{`If-Match`: []string{`"e180ee84f0671b1"`}},
+			Payload: `{"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"], "operations": [{"op": "remove", "path": "title"}]}`,
+			Status:  http.StatusPreconditionFailed,
+		},
+		{
+			Name:   `DELETE with wildcard If-Match`,
+			Method: http.MethodDelete,
+			Path:   `/Users/2819c223`,
+			Header: http.Header{`If-Match`: []string{`*`}},
+			Status: http.StatusNoContent,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			hh, err := server.NewServer(&versionedBackend{})
+			require.NoError(t, err, `server.NewServer should succeed`)
+
+			req := httptest.NewRequest(tc.Method, tc.Path, strings.NewReader(tc.Payload))
+			for k, v := range tc.Header {
+				req.Header[k] = v
+			}
+			w := httptest.NewRecorder()
+			hh.ServeHTTP(w, req)
+			require.Equal(t, tc.Status, w.Code, `status should match`)
+			require.Equal(t, tc.ETag, w.Header().Get(`ETag`), `ETag should match`)
+		})
+	}
+}
+
+func TestPreconditionsFromContext(t *testing.T) {
+	var backend versionedBackend
+	hh, err := server.NewServer(&backend)
+	require.NoError(t, err, `server.NewServer should succeed`)
+
+	req := httptest.NewRequest(http.MethodDelete, `/Groups/e9e30dba`, nil)
+	req.Header.Set(`If-Match`, `W/"1", W/"2,3"`)
+	w := httptest.NewRecorder()
+	hh.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNoContent, w.Code, `status should match`)
+
+	require.NotNil(t, backend.preconditions, `preconditions should be passed to the backend`)
+	require.Equal(t, []string{`W/"1"`, `W/"2,3"`}, backend.preconditions.IfMatch, `If-Match should match`)
+	require.True(t, backend.preconditions.Match(`"2,3"`), `weak comparison should match`)
+	require.False(t, backend.preconditions.Match(`W/"4"`), `different version should not match`)
+}
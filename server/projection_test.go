@@ -0,0 +1,93 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cybozu-go/scim/resource"
+	"github.com/cybozu-go/scim/server"
+	"github.com/stretchr/testify/require"
+)
+
+type projectionBackend struct{}
+
+func (projectionBackend) user() *resource.User {
+	return resource.NewUserBuilder().
+		ID(`2819c223`).
+		UserName(`bjensen`).
+		Password(`t1meMa$heen`).
+		DisplayName(`Babs Jensen`).
+		Name(resource.NewNamesBuilder().
+			FamilyName(`Jensen`).
+			GivenName(`Barbara`).
+			MustBuild()).
+		MustBuild()
+}
+
+func (b projectionBackend) RetrieveUser(_ context.Context, _ string, _, _ []string) (*resource.User, error) {
+	return b.user(), nil
+}
+
+func (b projectionBackend) SearchUser(_ context.Context, _ *resource.SearchRequest) (*resource.ListResponse, error) {
+	return resource.NewListResponseBuilder().
+		TotalResults(1).
+		Resources(b.user()).
+		Build()
+}
+
+func TestProjection(t *testing.T) {
+	testcases := []struct {
+		Name     string
+		Method   string
+		Path     string
+		Payload  string
+		Expected string
+	}{
+		{
+			Name:     `retrieve without attributes`,
+			Method:   http.MethodGet,
+			Path:     `/Users/2819c223`,
+			Expected: `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "id": "2819c223", "userName": "bjensen", "displayName": "Babs Jensen", "name": {"familyName": "Jensen", "givenName": "Barbara"}}`,
+		},
+		{
+			Name:     `retrieve with attributes`,
+			Method:   http.MethodGet,
+			Path:     `/Users/2819c223?attributes=userName,name.givenName`,
+			Expected: `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "id": "2819c223", "userName": "bjensen", "name": {"givenName": "Barbara"}}`,
+		},
+		{
+			Name:     `retrieve with excludedAttributes`,
+			Method:   http.MethodGet,
+			Path:     `/Users/2819c223?excludedAttributes=name,displayName`,
+			Expected: `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "id": "2819c223", "userName": "bjensen"}`,
+		},
+		{
+			Name:    `search with attributes`,
+			Method:  http.MethodPost,
+			Path:    `/Users/.search`,
+			Payload: `{"schemas": ["urn:ietf:params:scim:api:messages:2.0:SearchRequest"], "attributes": ["displayName"]}`,
+			Expected: `{
+  "schemas": ["urn:ietf:params:scim:api:messages:2.0:ListResponse"],
+  "totalResults": 1,
+  "resources": [{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "id": "2819c223", "displayName": "Babs Jensen"}]
+}`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			hh, err := server.NewServer(projectionBackend{})
+			require.NoError(t, err, `server.NewServer should succeed`)
+
+			req := httptest.NewRequest(tc.Method, tc.Path, strings.NewReader(tc.Payload))
+			w := httptest.NewRecorder()
+			hh.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code, `status should match`)
+			require.JSONEq(t, tc.Expected, w.Body.String(), `response should match`)
+		})
+	}
+}
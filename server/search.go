@@ -0,0 +1,156 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/cybozu-go/scim/filter"
+	"github.com/cybozu-go/scim/projection"
+	"github.com/cybozu-go/scim/resource"
+)
+
+// ListUsersEndpoint creates the handler for `GET /Users`, which searches
+// for users using query parameters (RFC 7644 Section 3.4.2). The search
+// is performed by the same backend as `POST /Users/.search`.
+func ListUsersEndpoint(b SearchUserBackend, options ...SearchEndpointOption) http.Handler {
+	return listEndpoint(b.SearchUser, options)
+}
+
+// ListGroupsEndpoint creates the handler for `GET /Groups`, which searches
+// for groups using query parameters (RFC 7644 Section 3.4.2). The search
+// is performed by the same backend as `POST /Groups/.search`.
+func ListGroupsEndpoint(b SearchGroupBackend, options ...SearchEndpointOption) http.Handler {
+	return listEndpoint(b.SearchGroup, options)
+}
+
+func listEndpoint(search func(context.Context, *resource.SearchRequest) (*resource.ListResponse, error), options []SearchEndpointOption) http.Handler {
+	parseOptions := filterParseOptions(options)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q, err := searchRequestFromQuery(r.URL.Query(), parseOptions)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+
+		writeSearchResponse(w, r, search, q)
+	})
+}
+
+// writeSearchResponse performs the search, and writes the list response
+// with the attributes projected as requested. It is shared by the
+// handlers for `GET` list requests and `POST .search` requests
+func writeSearchResponse(w http.ResponseWriter, r *http.Request, search func(context.Context, *resource.SearchRequest) (*resource.ListResponse, error), q *resource.SearchRequest) {
+	lr, err := search(r.Context(), q)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	lr, err = projection.ApplyListResponse(lr, q.Attributes(), q.ExcludedAttributes())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(lr); err != nil {
+		WriteSCIMError(w, http.StatusBadRequest, `failed to encode response`)
+		return
+	}
+
+	hdr := w.Header()
+	hdr.Set(ctKey, mimeSCIM)
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.Copy(w, &buf) // not much you can do by this point
+}
+
+// filterParseOptions returns the options that are passed to
+// `filter.Parse()` when the filters in search requests are parsed
+func filterParseOptions(options []SearchEndpointOption) []filter.ParseOption {
+	var parseOptions []filter.ParseOption
+	//nolint:forcetypeassert
+	for _, option := range options {
+		switch option.Ident() {
+		case identFilterLimits{}:
+			parseOptions = append(parseOptions, option.Value().([]filter.ParseOption)...)
+		}
+	}
+	return parseOptions
+}
+
+// searchRequestFromQuery converts the query parameters of a GET request
+// into a search request. Malformed parameters are reported as errors with
+// a 400 status. As per RFC 7644 Section 3.4.2.4, a `startIndex` less than
+// 1 is interpreted as 1, and a negative `count` is interpreted as 0
+func searchRequestFromQuery(query url.Values, parseOptions []filter.ParseOption) (*resource.SearchRequest, error) {
+	b := resource.NewSearchRequestBuilder()
+
+	if v := query.Get(resource.SearchRequestFilterKey); v != "" {
+		if _, err := filter.Parse(v, parseOptions...); err != nil {
+			return nil, err
+		}
+		b.Filter(v)
+	}
+
+	if v := query.Get(resource.SearchRequestStartIndexKey); v != "" {
+		startIndex, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, scimErrorf(http.StatusBadRequest, resource.ErrInvalidValue, `invalid startIndex %q`, v)
+		}
+		if startIndex < 1 {
+			startIndex = 1
+		}
+		b.StartIndex(startIndex)
+	}
+
+	if v := query.Get(resource.SearchRequestCountKey); v != "" {
+		count, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, scimErrorf(http.StatusBadRequest, resource.ErrInvalidValue, `invalid count %q`, v)
+		}
+		if count < 0 {
+			count = 0
+		}
+		b.Count(count)
+	}
+
+	if v := query.Get(resource.SearchRequestSortByKey); v != "" {
+		b.SortBy(v)
+	}
+
+	if v := query.Get(resource.SearchRequestSortOrderKey); v != "" {
+		switch sortOrder := strings.ToLower(v); sortOrder {
+		case resource.SortAscending, resource.SortDescending:
+			b.SortOrder(sortOrder)
+		default:
+			return nil, scimErrorf(http.StatusBadRequest, resource.ErrInvalidValue, `invalid sortOrder %q`, v)
+		}
+	}
+
+	if attrs := splitAttributes(query.Get(resource.SearchRequestAttributesKey)); len(attrs) > 0 {
+		b.Attributes(attrs...)
+	}
+
+	if excluded := splitAttributes(query.Get(resource.SearchRequestExcludedAttributesKey)); len(excluded) > 0 {
+		b.ExcludedAttributes(excluded...)
+	}
+
+	return b.Build()
+}
+
+// splitAttributes splits a comma separated list of attribute paths
+func splitAttributes(v string) []string {
+	var list []string
+	for _, attr := range strings.Split(v, ",") {
+		if attr = strings.TrimSpace(attr); attr != "" {
+			list = append(list, attr)
+		}
+	}
+	return list
+}
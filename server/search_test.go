@@ -0,0 +1,221 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/cybozu-go/scim/filter"
+	"github.com/cybozu-go/scim/resource"
+	"github.com/cybozu-go/scim/server"
+	"github.com/stretchr/testify/require"
+)
+
+type listBackend struct {
+	request *resource.SearchRequest
+}
+
+func (b *listBackend) SearchUser(_ context.Context, q *resource.SearchRequest) (*resource.ListResponse, error) {
+	b.request = q
+	return resource.NewListResponseBuilder().
+		TotalResults(1).
+		Resources(resource.NewUserBuilder().
+			ID(`2819c223`).
+			UserName(`bjensen`).
+			DisplayName(`Babs Jensen`).
+			MustBuild()).
+		Build()
+}
+
+func (b *listBackend) SearchGroup(_ context.Context, q *resource.SearchRequest) (*resource.ListResponse, error) {
+	b.request = q
+	return resource.NewListResponseBuilder().
+		TotalResults(0).
+		Build()
+}
+
+func TestList(t *testing.T) {
+	testcases := []struct {
+		Name     string
+		Path     string
+		Status   int
+		Error    resource.ErrorType
+		Request  string
+		Expected string
+	}{
+		{
+			Name:    `all parameters`,
+			Path:    `/Users?filter=userName+eq+%22bjensen%22&startIndex=1&count=100&sortBy=userName&sortOrder=Descending&attributes=userName,+displayName&excludedAttributes=emails`,
+			Status:  http.StatusOK,
+			Request: `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:SearchRequest"], "filter": "userName eq \"bjensen\"", "startIndex": 1, "count": 100, "sortBy": "userName", "sortOrder": "descending", "attributes": ["userName", "displayName"], "excludedAttributes": ["emails"]}`,
+			Expected: `{
+  "schemas": ["urn:ietf:params:scim:api:messages:2.0:ListResponse"],
+  "totalResults": 1,
+  "resources": [{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "id": "2819c223", "userName": "bjensen", "displayName": "Babs Jensen"}]
+}`,
+		},
+		{
+			Name:     `out of range pagination`,
+			Path:     `/Groups?startIndex=0&count=-1`,
+			Status:   http.StatusOK,
+			Request:  `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:SearchRequest"], "startIndex": 1, "count": 0}`,
+			Expected: `{"schemas": ["urn:ietf:params:scim:api:messages:2.0:ListResponse"], "totalResults": 0}`,
+		},
+		{
+			Name:   `invalid filter`,
+			Path:   `/Users?filter=userName+eq`,
+			Status: http.StatusBadRequest,
+			Error:  resource.ErrInvalidFilter,
+		},
+		{
+			Name:   `invalid startIndex`,
+			Path:   `/Users?startIndex=first`,
+			Status: http.StatusBadRequest,
+			Error:  resource.ErrInvalidValue,
+		},
+		{
+			Name:   `invalid count`,
+			Path:   `/Groups?count=1.5`,
+			Status: http.StatusBadRequest,
+			Error:  resource.ErrInvalidValue,
+		},
+		{
+			Name:   `invalid sortOrder`,
+			Path:   `/Users?sortBy=userName&sortOrder=up`,
+			Status: http.StatusBadRequest,
+			Error:  resource.ErrInvalidValue,
+		},
+		{
+			Name:   `invalid attributes`,
+			Path:   `/Users?attributes=emails%5Btype+eq+%22work%22%5D`,
+			Status: http.StatusBadRequest,
+			Error:  resource.ErrInvalidPath,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			backend := &listBackend{}
+			hh, err := server.NewServer(backend)
+			require.NoError(t, err, `server.NewServer should succeed`)
+
+			req := httptest.NewRequest(http.MethodGet, tc.Path, nil)
+			w := httptest.NewRecorder()
+			hh.ServeHTTP(w, req)
+			require.Equal(t, tc.Status, w.Code, `status should match`)
+
+			if tc.Status != http.StatusOK {
+				var serr resource.Error
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &serr), `json.Unmarshal should succeed`)
+				require.Equal(t, tc.Error, serr.SCIMType(), `error type should match`)
+				return
+			}
+
+			require.JSONEq(t, tc.Expected, w.Body.String(), `response should match`)
+
+			serialized, err := json.Marshal(backend.request)
+			require.NoError(t, err, `json.Marshal should succeed`)
+			require.JSONEq(t, tc.Request, string(serialized), `search request should match`)
+		})
+	}
+}
+
+func TestFilterLimits(t *testing.T) {
+	const tooComplex = `userName eq "bjensen" or userName eq "jsmith" or userName eq "mjones"`
+
+	limits := server.WithFilterLimits([]filter.ParseOption{
+		filter.WithMaxNodes(3),
+		filter.WithMaxLength(100),
+	})
+
+	testcases := []struct {
+		Name    string
+		Method  string
+		Path    string
+		Payload string
+		NoLimit bool
+		Status  int
+		Error   resource.ErrorType
+	}{
+		{
+			Name:    `search without limits`,
+			Method:  http.MethodPost,
+			Path:    `/Users/.search`,
+			Payload: `{"schemas": ["urn:ietf:params:scim:api:messages:2.0:SearchRequest"], "filter": ` + strconv.Quote(tooComplex) + `}`,
+			NoLimit: true,
+			Status:  http.StatusOK,
+		},
+		{
+			Name:    `search with an invalid filter`,
+			Method:  http.MethodPost,
+			Path:    `/Users/.search`,
+			Payload: `{"schemas": ["urn:ietf:params:scim:api:messages:2.0:SearchRequest"], "filter": "userName eq"}`,
+			NoLimit: true,
+			Status:  http.StatusBadRequest,
+			Error:   resource.ErrInvalidFilter,
+		},
+		{
+			Name:   `list within the limits`,
+			Method: http.MethodGet,
+			Path:   `/Users?filter=` + url.QueryEscape(`userName eq "bjensen"`),
+			Status: http.StatusOK,
+		},
+		{
+			Name:   `list with too many nodes`,
+			Method: http.MethodGet,
+			Path:   `/Users?filter=` + url.QueryEscape(tooComplex),
+			Status: http.StatusBadRequest,
+			Error:  resource.ErrTooMany,
+		},
+		{
+			Name:    `search with too many nodes`,
+			Method:  http.MethodPost,
+			Path:    `/Groups/.search`,
+			Payload: `{"schemas": ["urn:ietf:params:scim:api:messages:2.0:SearchRequest"], "filter": ` + strconv.Quote(tooComplex) + `}`,
+			Status:  http.StatusBadRequest,
+			Error:   resource.ErrTooMany,
+		},
+		{
+			Name:    `search exceeding the maximum length`,
+			Method:  http.MethodPost,
+			Path:    `/Users/.search`,
+			Payload: `{"schemas": ["urn:ietf:params:scim:api:messages:2.0:SearchRequest"], "filter": ` + strconv.Quote(`displayName eq "`+strings.Repeat(`a`, 100)+`"`) + `}`,
+			Status:  http.StatusBadRequest,
+			Error:   resource.ErrInvalidFilter,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			var options []server.NewServerOption
+			if !tc.NoLimit {
+				options = append(options, limits)
+			}
+
+			backend := &listBackend{}
+			hh, err := server.NewServer(backend, options...)
+			require.NoError(t, err, `server.NewServer should succeed`)
+
+			req := httptest.NewRequest(tc.Method, tc.Path, strings.NewReader(tc.Payload))
+			w := httptest.NewRecorder()
+			hh.ServeHTTP(w, req)
+			require.Equal(t, tc.Status, w.Code, `status should match`)
+
+			if tc.Status != http.StatusOK {
+				var serr resource.Error
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &serr), `json.Unmarshal should succeed`)
+				require.Equal(t, tc.Error, serr.SCIMType(), `error type should match`)
+				require.Nil(t, backend.request, `backend should not be called`)
+				return
+			}
+			require.NotNil(t, backend.request, `backend should be called`)
+		})
+	}
+}
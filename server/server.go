@@ -14,17 +14,38 @@ import (
 var ctKey = `Content-Type`
 var mimeSCIM = `application/scim+json`
 
-func MustNewServer(backend interface{}) http.Handler {
-	h, err := NewServer(backend)
+func MustNewServer(backend interface{}, options ...NewServerOption) http.Handler {
+	h, err := NewServer(backend, options...)
 	if err != nil {
 		panic(err)
 	}
 	return h
 }
 
-func NewServer(backend interface{}) (http.Handler, error) {
+func NewServer(backend interface{}, options ...NewServerOption) (http.Handler, error) {
 	var b Builder
 
+	var patchOptions []PatchEndpointOption
+	var bulkOptions []BulkEndpointOption
+	var searchOptions []SearchEndpointOption
+	var subjectResolver SubjectResolver
+	for _, option := range options {
+		switch option.Ident() {
+		case identSubjectResolver{}:
+			//nolint:forcetypeassert
+			subjectResolver = option.Value().(SubjectResolver)
+		case identPatchQuirks{}:
+			//nolint:forcetypeassert
+			patchOptions = append(patchOptions, option.(PatchEndpointOption))
+		case identBulkSupport{}:
+			//nolint:forcetypeassert
+			bulkOptions = append(bulkOptions, option.(BulkEndpointOption))
+		case identFilterLimits{}:
+			//nolint:forcetypeassert
+			searchOptions = append(searchOptions, option.(SearchEndpointOption))
+		}
+	}
+
 	if v, ok := backend.(CreateGroupBackend); ok {
 		b.CreateGroup(CreateGroupEndpoint(v))
 	}
@@ -41,7 +62,7 @@ func NewServer(backend interface{}) (http.Handler, error) {
 	}
 
 	if v, ok := backend.(PatchGroupBackend); ok {
-		b.PatchGroup(PatchGroupEndpoint(v))
+		b.PatchGroup(PatchGroupEndpoint(v, patchOptions...))
 	}
 
 	if v, ok := backend.(CreateUserBackend); ok {
@@ -61,19 +82,36 @@ func NewServer(backend interface{}) (http.Handler, error) {
 	}
 
 	if v, ok := backend.(PatchUserBackend); ok {
-		b.PatchUser(PatchUserEndpoint(v))
+		b.PatchUser(PatchUserEndpoint(v, patchOptions...))
+	}
+
+	if subjectResolver != nil {
+		if v, ok := backend.(RetrieveUserBackend); ok {
+			b.RetrieveMe(RetrieveMeEndpoint(v, subjectResolver))
+		}
+		if v, ok := backend.(ReplaceUserBackend); ok {
+			b.ReplaceMe(ReplaceMeEndpoint(v, subjectResolver))
+		}
+		if v, ok := backend.(PatchUserBackend); ok {
+			b.PatchMe(PatchMeEndpoint(v, subjectResolver, patchOptions...))
+		}
+		if v, ok := backend.(DeleteUserBackend); ok {
+			b.DeleteMe(DeleteMeEndpoint(v, subjectResolver))
+		}
 	}
 
 	if v, ok := backend.(SearchGroupBackend); ok {
-		b.SearchGroup(SearchGroupEndpoint(v))
+		b.SearchGroup(SearchGroupEndpoint(v, searchOptions...))
+		b.ListGroups(ListGroupsEndpoint(v, searchOptions...))
 	}
 
 	if v, ok := backend.(SearchUserBackend); ok {
-		b.SearchUser(SearchUserEndpoint(v))
+		b.SearchUser(SearchUserEndpoint(v, searchOptions...))
+		b.ListUsers(ListUsersEndpoint(v, searchOptions...))
 	}
 
 	if v, ok := backend.(SearchBackend); ok {
-		b.Search(SearchEndpoint(v))
+		b.Search(SearchEndpoint(v, searchOptions...))
 	}
 
 	if v, ok := backend.(RetrieveServiceProviderConfigBackend); ok {
@@ -91,6 +129,15 @@ func NewServer(backend interface{}) (http.Handler, error) {
 	if v, ok := backend.(RetrieveSchemaBackend); ok {
 		b.RetrieveSchema(RetrieveSchemaEndpoint(v))
 	}
+
+	// backends that do not process bulk requests by themselves only
+	// accept them when bulk operations are advertised as supported, as a
+	// bulk request can cause many calls to the backend
+	if v, ok := backend.(BulkBackend); ok {
+		b.Bulk(BulkEndpoint(v, bulkOptions...))
+	} else if isBulkDispatchable(backend) {
+		b.Bulk(BulkEndpoint(&bulkDispatcher{backend: backend, requireSupport: true}, bulkOptions...))
+	}
 	return b.Build()
 }
 
@@ -238,6 +285,26 @@ func (b *Builder) PatchUser(hh http.Handler) *Builder {
 	return b
 }
 
+func (b *Builder) DeleteMe(hh http.Handler) *Builder {
+	b.Handler(http.MethodDelete, `/Me`, hh)
+	return b
+}
+
+func (b *Builder) ReplaceMe(hh http.Handler) *Builder {
+	b.Handler(http.MethodPut, `/Me`, hh)
+	return b
+}
+
+func (b *Builder) RetrieveMe(hh http.Handler) *Builder {
+	b.Handler(http.MethodGet, `/Me`, hh)
+	return b
+}
+
+func (b *Builder) PatchMe(hh http.Handler) *Builder {
+	b.Handler(http.MethodPatch, `/Me`, hh)
+	return b
+}
+
 func (b *Builder) SearchGroup(hh http.Handler) *Builder {
 	b.Handler(http.MethodPost, `/Groups/.search`, hh)
 	return b
@@ -248,6 +315,16 @@ func (b *Builder) SearchUser(hh http.Handler) *Builder {
 	return b
 }
 
+func (b *Builder) ListGroups(hh http.Handler) *Builder {
+	b.Handler(http.MethodGet, `/Groups`, hh)
+	return b
+}
+
+func (b *Builder) ListUsers(hh http.Handler) *Builder {
+	b.Handler(http.MethodGet, `/Users`, hh)
+	return b
+}
+
 func (b *Builder) Search(hh http.Handler) *Builder {
 	b.Handler(http.MethodPost, `/.search`, hh)
 	return b
@@ -272,3 +349,8 @@ func (b *Builder) RetrieveSchema(hh http.Handler) *Builder {
 	b.Handler(http.MethodGet, `/Schemas/{id}`, hh)
 	return b
 }
+
+func (b *Builder) Bulk(hh http.Handler) *Builder {
+	b.Handler(http.MethodPost, `/Bulk`, hh)
+	return b
+}
@@ -319,9 +319,37 @@ func UsersSearch(t *testing.T, cl *client.Client) func(t *testing.T) {
 						Do(context.TODO())
 					require.NoError(t, err, `search should succeed`)
 					require.Equal(t, tc.TotalResults, res.TotalResults(), `total results should be %d`, tc.TotalResults)
+
+					res, err = cl.User().List().
+						Filter(tc.Query).
+						Do(context.TODO())
+					require.NoError(t, err, `list should succeed`)
+					require.Equal(t, tc.TotalResults, res.TotalResults(), `total results should be %d`, tc.TotalResults)
 				})
 			}
 		})
+		t.Run("search via GET /Users", func(t *testing.T) {
+			res, err := cl.User().List().
+				Filter(`roles.value eq "actor"`).
+				SortBy(`userName`).
+				SortOrder(`descending`).
+				StartIndex(2).
+				Count(2).
+				Do(context.TODO())
+			require.NoError(t, err, `list should succeed`)
+			require.Equal(t, 4, res.TotalResults(), `total results should be 4`)
+			require.Len(t, res.Resources(), 2, `resources should be paginated`)
+
+			_, err = cl.User().List().
+				SortOrder(`sideways`).
+				Do(context.TODO())
+			require.Error(t, err, `list with an invalid sortOrder should fail`)
+
+			_, err = cl.User().List().
+				Filter(`userName eq`).
+				Do(context.TODO())
+			require.Error(t, err, `list with an invalid filter should fail`)
+		})
 	}
 }
 
@@ -891,6 +919,16 @@ func GroupsSearch(t *testing.T, cl *client.Client) func(t *testing.T) {
 				require.NoError(t, err, `cl.Search should succeed`)
 				require.Equal(t, 2, res.TotalResults(), `total results should be 2`)
 			})
+			t.Run("Use `sw` predicate via GET /Groups", func(t *testing.T) {
+				res, err := cl.Group().List().
+					Attributes(`displayName`).
+					Filter(`displayName sw "search-test"`).
+					StartIndex(1).
+					Count(10).
+					Do(context.TODO())
+				require.NoError(t, err, `cl.List should succeed`)
+				require.Equal(t, 2, res.TotalResults(), `total results should be 2`)
+			})
 			t.Run("Use `co` predicate", func(t *testing.T) {
 				res, err := cl.Group().Search().
 					Attributes(`displayName`).
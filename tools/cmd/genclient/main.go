@@ -437,10 +437,17 @@ func generateCall(o *codegen.Output, svc Service, call *codegen.Object) error {
 			o.L(`if len(m) > 0 {`)
 			o.L(`vals = make(url.Values)`)
 			o.L(`for key, value := range m {`)
+			// schemas identify the message in JSON payloads, and have
+			// no meaning as query parameters
+			o.L(`if key == "schemas" {`)
+			o.L(`continue`)
+			o.L(`}`)
 			// HACK: this needs to be fixed
 			o.L(`switch value := value.(type) {`)
 			o.L(`case []string:`)
 			o.L(`vals.Add(key, strings.Join(value, ","))`)
+			o.L(`case int:`)
+			o.L(`vals.Add(key, strconv.Itoa(value))`)
 			o.L(`default:`)
 			// TODO: this is over simplified
 			o.L("vals.Add(key, fmt.Sprintf(`%%s`, value))")
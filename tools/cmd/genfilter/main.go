@@ -87,33 +87,56 @@ func generateExpr(objects []*codegen.Object) error {
 	for _, object := range objects {
 		o.LL(`type %s interface {`, object.Name(true))
 		o.L(`Interface`)
+		// marker method, so that expressions with identical method sets
+		// (e.g. IdentifierExpr and AttrValueExpr) can be distinguished
+		o.L(`is%s()`, object.Name(true))
 		for _, field := range object.Fields() {
 			o.L(`%s() %s`, field.Name(true), field.Type())
 		}
+		// methods that are not backed by a field are implemented by hand
+		if methods, ok := object.Extra(`methods`); ok {
+			for _, method := range methods.([]interface{}) {
+				o.L(`%s`, method)
+			}
+		}
 		o.L(`}`)
 
 		o.LL(`type %s struct {`, object.Name(false))
 		for _, field := range object.Fields() {
 			o.L(`%s %s`, field.Name(false), field.Type())
 		}
+		// fields that are derived from the other fields, and are
+		// populated by a constructor that is implemented by hand
+		if fields, ok := object.Extra(`derived_fields`); ok {
+			for _, field := range fields.([]interface{}) {
+				o.L(`%s`, field)
+			}
+		}
 		o.L(`}`)
 
 		o.LL(`func (*%s) expression() {}`, object.Name(false))
+		o.LL(`func (*%s) is%s() {}`, object.Name(false), object.Name(true))
 
-		o.LL(`func New%s(`, object.Name(true))
-		for i, field := range object.Fields() {
-			if i > 0 {
-				o.R(`,`)
+		o.LL(`func (e *%s) String() string {`, object.Name(false))
+		o.L(`return Format(e)`)
+		o.L(`}`)
+
+		if _, ok := object.Extra(`derived_fields`); !ok {
+			o.LL(`func New%s(`, object.Name(true))
+			for i, field := range object.Fields() {
+				if i > 0 {
+					o.R(`,`)
+				}
+				o.R(`%s %s`, field.Name(false), field.Type())
 			}
-			o.R(`%s %s`, field.Name(false), field.Type())
-		}
-		o.R(`) %s {`, object.Name(true))
-		o.L(`return &%s{`, object.Name(false))
-		for _, field := range object.Fields() {
-			o.L(`%[1]s: %[1]s,`, field.Name(false))
+			o.R(`) %s {`, object.Name(true))
+			o.L(`return &%s{`, object.Name(false))
+			for _, field := range object.Fields() {
+				o.L(`%[1]s: %[1]s,`, field.Name(false))
+			}
+			o.L(`}`)
+			o.L(`}`)
 		}
-		o.L(`}`)
-		o.L(`}`)
 
 		for _, field := range object.Fields() {
 			o.LL(`func (e *%s) %s() %s {`, object.Name(false), field.Name(true), field.Type())
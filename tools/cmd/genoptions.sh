@@ -16,7 +16,7 @@ popd > /dev/null
 
 EXE="$DIR/.genoptions"
 
-for dir in client examples/sql filter resource server; do
+for dir in client filter filter/ldapfilter filter/mongoquery filter/sqlgen patch resource server; do
   echo "  ⌛ Processing $dir/options.yaml"
   "$EXE" -objects="$dir/options.yaml"
 done
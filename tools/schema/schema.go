@@ -77,6 +77,81 @@ func (AuthenticationScheme) Fields() []*schema.FieldSpec {
 	}
 }
 
+func bulkDataType() *schema.TypeSpec {
+	return schema.TypeName(`BulkData`).
+		GetValue(true).
+		AcceptValue(true).
+		ApparentType(`interface{}`)
+}
+
+type BulkOperation struct {
+	schema.Base
+	scimSchemaBase
+}
+
+func (BulkOperation) Fields() []*schema.FieldSpec {
+	return []*schema.FieldSpec{
+		schema.String(`BulkID`).
+			Unexported(`bulkID`).
+			JSON(`bulkId`),
+		schema.Field(`Data`, bulkDataType()),
+		schema.String(`Method`),
+		schema.String(`Path`),
+		schema.String(`Version`),
+	}
+}
+
+type BulkOperationResponse struct {
+	schema.Base
+	scimSchemaBase
+}
+
+func (BulkOperationResponse) Fields() []*schema.FieldSpec {
+	return []*schema.FieldSpec{
+		schema.String(`BulkID`).
+			Unexported(`bulkID`).
+			JSON(`bulkId`),
+		schema.String(`Location`),
+		schema.String(`Method`),
+		schema.Field(`Response`, bulkDataType()),
+		schema.String(`Status`),
+		schema.String(`Version`),
+	}
+}
+
+type BulkRequest struct {
+	schema.Base
+	scimSchemaBase
+}
+
+func (BulkRequest) GetSchemaURI() string {
+	return "urn:ietf:params:scim:api:messages:2.0:BulkRequest"
+}
+
+func (BulkRequest) Fields() []*schema.FieldSpec {
+	return []*schema.FieldSpec{
+		schema.Int(`FailOnErrors`),
+		schema.Field(`Operations`, schema.TypeName(`[]*BulkOperation`)).JSON(`Operations`),
+		schema.Field(`Schemas`, schemastyp),
+	}
+}
+
+type BulkResponse struct {
+	schema.Base
+	scimSchemaBase
+}
+
+func (BulkResponse) GetSchemaURI() string {
+	return "urn:ietf:params:scim:api:messages:2.0:BulkResponse"
+}
+
+func (BulkResponse) Fields() []*schema.FieldSpec {
+	return []*schema.FieldSpec{
+		schema.Field(`Operations`, schema.TypeName(`[]*BulkOperationResponse`)).JSON(`Operations`),
+		schema.Field(`Schemas`, schemastyp),
+	}
+}
+
 type BulkSupport struct {
 	schema.Base
 	scimSchemaBase